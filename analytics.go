@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+)
+
+type DocumentAnalyticsResult struct {
+	Result            bool            `json:"result"`
+	DocumentId        int             `json:"documentId"`
+	DocumentVersion   int             `json:"documentVersion"` // 集計したバージョン (0は全バージョン)
+	TotalDwellSeconds float64         `json:"totalDwellSeconds"`
+	Pages             []PageAnalytics `json:"pages"` // ページ順
+}
+
+// PageAnalytics はページ毎の集計
+type PageAnalytics struct {
+	DocumentPage int            `json:"documentPage"`
+	DwellSeconds float64        `json:"dwellSeconds"` // 発表者が表示していた時間の合計
+	ViewCount    int            `json:"viewCount"`    // 表示された回数
+	ReactionNum  int            `json:"reactionNum"`  // 全ての種類のリアクションの合計
+	Reactions    map[string]int `json:"reactions"`    // 種類毎のリアクションの数 (confusedは参加者を特定できないものを含む)
+	QuestionNum  int            `json:"questionNum"`
+	VoteNum      int            `json:"voteNum"` // 質問への投票数の合計
+	HandsUpNum   int            `json:"handsUpNum"`
+}
+
+// analyzeDocument はページの表示期間，リアクション，質問，挙手をページ毎に集計する
+// version が0の場合は全てのバージョンを合わせて集計する
+// 分からない(confused)はreactionsの数を使い，それ以外の種類はpageReactionsの参加者毎のリアクションを数える
+func analyzeDocument(document Document, version int, views []PageView, reactions []Reaction, pageReactions []PageReactionCount, questions []Question, now time.Time) *DocumentAnalyticsResult {
+	pages := make(map[int]*PageAnalytics)
+	page := func(documentPage int) *PageAnalytics {
+		p, ok := pages[documentPage]
+		if !ok {
+			p = &PageAnalytics{DocumentPage: documentPage, Reactions: make(map[string]int)}
+			pages[documentPage] = p
+		}
+		return p
+	}
+	for i := 1; i <= document.PageCount; i++ {
+		page(i)
+	}
+
+	result := &DocumentAnalyticsResult{
+		Result:          true,
+		DocumentId:      document.DocumentId,
+		DocumentVersion: version,
+	}
+	for _, view := range views {
+		if version != 0 && view.DocumentVersion != version {
+			continue
+		}
+		// 表示中のページは現在時刻までとする
+		end := now
+		if view.EndedAt != nil {
+			end = *view.EndedAt
+		}
+		dwell := math.Max(0, end.Sub(view.StartedAt).Seconds())
+		p := page(view.DocumentPage)
+		p.DwellSeconds += dwell
+		p.ViewCount++
+		result.TotalDwellSeconds += dwell
+	}
+	for _, reaction := range reactions {
+		if version != 0 && reaction.DocumentVersion != version {
+			continue
+		}
+		p := page(reaction.DocumentPage)
+		p.ReactionNum += reaction.ReactionNum
+		p.Reactions[reactionConfused] += reaction.ReactionNum
+	}
+	for _, count := range pageReactions {
+		// 分からないはreactionsに含まれる
+		if (version != 0 && count.DocumentVersion != version) || count.Kind == reactionConfused {
+			continue
+		}
+		p := page(count.DocumentPage)
+		p.ReactionNum += count.ReactionNum
+		p.Reactions[count.Kind] += count.ReactionNum
+	}
+	for _, question := range questions {
+		if version != 0 && question.DocumentVersion != version {
+			continue
+		}
+		if question.UserId == moderatorUserId || question.IsNominated || question.ModerationStatus != questionApproved {
+			continue
+		}
+		p := page(question.DocumentPage)
+		if question.IsVoice {
+			p.HandsUpNum++
+		} else {
+			p.QuestionNum++
+			p.VoteNum += question.VoteNum
+		}
+	}
+
+	result.Pages = make([]PageAnalytics, 0, len(pages))
+	for _, p := range pages {
+		result.Pages = append(result.Pages, *p)
+	}
+	sort.Slice(result.Pages, func(i, j int) bool { return result.Pages[i].DocumentPage < result.Pages[j].DocumentPage })
+	return result
+}
+
+// documentAnalytics は資料のページ毎の集計を返す (?format=csv でCSV)
+// 会議の参加者のみ取得できる (?userId=...)，?version= で特定のバージョンに絞り込む
+func documentAnalytics(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		documentId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &DocumentAnalyticsResult{Result: false})
+		}
+		version := 0
+		if v := c.QueryParam("version"); v != "" {
+			if version, err = strconv.Atoi(v); err != nil {
+				return c.JSON(http.StatusBadRequest, &DocumentAnalyticsResult{Result: false})
+			}
+		}
+		found, document := getDocument(db, documentId)
+		if !found {
+			return c.JSON(http.StatusNotFound, &DocumentAnalyticsResult{Result: false})
+		}
+		if !isMeetingMember(db, document.MeetingId, c.QueryParam("userId")) {
+			return c.JSON(http.StatusForbidden, &DocumentAnalyticsResult{Result: false})
+		}
+
+		result := analyzeDocument(document, version, getPageViews(db, documentId), getDocumentReactions(db, documentId), getDocumentPageReactionCounts(db, documentId), getDocumentQuestions(db, documentId), time.Now())
+
+		switch c.QueryParam("format") {
+		case "", "json":
+			return c.JSON(http.StatusOK, result)
+		case "csv":
+			c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=UTF-8")
+			c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"document-%d-analytics.csv\"", documentId))
+			c.Response().WriteHeader(http.StatusOK)
+			return writeAnalyticsCsv(c.Response(), result)
+		default:
+			return c.JSON(http.StatusBadRequest, &DocumentAnalyticsResult{Result: false})
+		}
+	}
+}
+
+func writeAnalyticsCsv(w http.ResponseWriter, result *DocumentAnalyticsResult) error {
+	writer := csv.NewWriter(w)
+	header := []string{"documentPage", "dwellSeconds", "viewCount", "reactionNum"}
+	// 種類毎のリアクションの数は reaction_confused のような列にする
+	for _, kind := range reactionKinds {
+		header = append(header, "reaction_"+kind)
+	}
+	writer.Write(append(header, "questionNum", "voteNum", "handsUpNum"))
+	for _, p := range result.Pages {
+		record := []string{
+			strconv.Itoa(p.DocumentPage),
+			strconv.FormatFloat(p.DwellSeconds, 'f', 1, 64),
+			strconv.Itoa(p.ViewCount),
+			strconv.Itoa(p.ReactionNum),
+		}
+		for _, kind := range reactionKinds {
+			record = append(record, strconv.Itoa(p.Reactions[kind]))
+		}
+		writer.Write(append(record,
+			strconv.Itoa(p.QuestionNum),
+			strconv.Itoa(p.VoteNum),
+			strconv.Itoa(p.HandsUpNum),
+		))
+	}
+	writer.Flush()
+	return writer.Error()
+}
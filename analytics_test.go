@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestAnalyzeDocumentReactions は分からないの数と参加者毎のリアクションをページ毎に合わせて数えることを確認する
+func TestAnalyzeDocumentReactions(t *testing.T) {
+	document := Document{DocumentId: 1, PageCount: 2, Version: 2}
+	reactions := []Reaction{
+		{DocumentPage: 1, DocumentVersion: 2, ReactionNum: 3},
+		{DocumentPage: 2, DocumentVersion: 1, ReactionNum: 5},
+	}
+	pageReactions := []PageReactionCount{
+		{DocumentVersion: 2, DocumentPage: 1, Kind: reactionConfused, ReactionNum: 2}, // reactionsに含まれる
+		{DocumentVersion: 2, DocumentPage: 1, Kind: reactionTooFast, ReactionNum: 4},
+		{DocumentVersion: 2, DocumentPage: 2, Kind: reactionApplause, ReactionNum: 1},
+		{DocumentVersion: 1, DocumentPage: 2, Kind: reactionTooSlow, ReactionNum: 6},
+	}
+
+	result := analyzeDocument(document, 2, nil, reactions, pageReactions, nil, time.Now())
+	if got := result.Pages[0]; got.ReactionNum != 7 || got.Reactions[reactionConfused] != 3 || got.Reactions[reactionTooFast] != 4 {
+		t.Errorf("page 1 of version 2: %+v", got)
+	}
+	if got := result.Pages[1]; got.ReactionNum != 1 || got.Reactions[reactionApplause] != 1 || got.Reactions[reactionTooSlow] != 0 {
+		t.Errorf("page 2 of version 2: %+v", got)
+	}
+
+	result = analyzeDocument(document, 0, nil, reactions, pageReactions, nil, time.Now())
+	if got := result.Pages[1]; got.ReactionNum != 12 || got.Reactions[reactionTooSlow] != 6 {
+		t.Errorf("page 2 of all versions: %+v", got)
+	}
+
+	recorder := httptest.NewRecorder()
+	if err := writeAnalyticsCsv(recorder, result); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(recorder.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"documentPage", "dwellSeconds", "viewCount", "reactionNum", "reaction_confused", "reaction_agree", "reaction_too_fast", "reaction_too_slow", "reaction_applause", "reaction_emoji", "questionNum", "voteNum", "handsUpNum"}
+	if len(records) != 3 || len(records[0]) != len(want) {
+		t.Fatalf("csv: %v", records)
+	}
+	for i, column := range want {
+		if records[0][i] != column {
+			t.Errorf("csv column %d: got %s, want %s", i, records[0][i], column)
+		}
+	}
+	if page2 := records[2]; page2[3] != "12" || page2[7] != "6" || page2[8] != "1" {
+		t.Errorf("csv page 2: %v", page2)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 会議の質問の匿名性
+const (
+	// 質問者を会議の全員に見せる
+	anonymityAttributed = "attributed"
+
+	// 質問者は会議には見せず，ホストにだけ見せる (従来の動作)
+	anonymityRoom = "anonymous"
+
+	// 質問者のIDを保存せず，会議毎の仮名だけを保存する
+	// 仮名は同じ会議の中では同じになるため，重複の検出や質問者本人の確認に使える
+	anonymityFull = "full"
+)
+
+func validAnonymityLevel(level string) bool {
+	return level == anonymityAttributed || level == anonymityRoom || level == anonymityFull
+}
+
+// anonymitySecret は仮名を作る鍵の元になるサーバーの秘密
+// DBには保存しないため，DBや報告書を見ても会議のソルトだけでは仮名を計算できない
+var anonymitySecret []byte
+
+// setAnonymitySecret は設定の秘密を使う
+// 設定されていなければ起動毎に作る (再起動すると以前の質問の質問者を確認できなくなる)
+func setAnonymitySecret(secret string) {
+	if secret == "" {
+		fmt.Printf("Warning: anonymitySecretが未設定のため一時的な値を使います．再起動すると完全匿名の会議の質問者を確認できなくなります in setAnonymitySecret\n")
+		secret = newAnonymitySalt()
+	}
+	anonymitySecret = []byte(secret)
+}
+
+// meetingAnonymity は会議の匿名性の設定を補い (省略時は会議内匿名)，完全匿名ならソルトを付ける
+// 設定が不正ならfalseを返す
+func meetingAnonymity(meeting *Meeting) bool {
+	if meeting.AnonymityLevel == "" {
+		meeting.AnonymityLevel = anonymityRoom
+	}
+	if !validAnonymityLevel(meeting.AnonymityLevel) {
+		return false
+	}
+	if meeting.AnonymityLevel == anonymityFull {
+		meeting.AnonymitySalt = newAnonymitySalt()
+	}
+	return true
+}
+
+func newAnonymitySalt() string {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err.Error())
+	}
+	return hex.EncodeToString(salt)
+}
+
+// anonymousId はサーバーの秘密，会議のソルトと利用者IDから仮名を作る
+// ソルトは会議毎に異なるため，別の会議の質問と結び付けることはできない
+func anonymousId(salt string, userId string) string {
+	keyMac := hmac.New(sha256.New, anonymitySecret)
+	keyMac.Write([]byte(salt))
+	mac := hmac.New(sha256.New, keyMac.Sum(nil))
+	mac.Write([]byte(userId))
+	return "anon-" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// meetingAskerId は会議への質問のQuestion.UserIdに保存する値を返す
+// 完全匿名の会議では仮名，それ以外は利用者ID
+func meetingAskerId(meeting Meeting, userId string) string {
+	if meeting.AnonymityLevel != anonymityFull {
+		return userId
+	}
+	if meeting.AnonymitySalt == "" {
+		fmt.Printf("Error: 完全匿名の会議にソルトがありません: %d in meetingAskerId\n", meeting.MeetingId)
+		return ""
+	}
+	return anonymousId(meeting.AnonymitySalt, userId)
+}
+
+// questionAskerId は資料への質問のQuestion.UserIdに保存する値を返す
+func questionAskerId(db *gorm.DB, documentId int, userId string) string {
+	found, meeting := getDocumentMeeting(db, documentId)
+	if !found {
+		return userId
+	}
+	return meetingAskerId(meeting, userId)
+}
+
+// isQuestionAsker はuserIdが質問の投稿者かを確認する (完全匿名の会議では仮名で比べる)
+func isQuestionAsker(db *gorm.DB, userId string, question Question) bool {
+	return userId != "" && question.UserId == questionAskerId(db, question.DocumentId, userId)
+}
+
+// meetingParticipantId は会議での回答・返信の投稿者として保存するID
+// 完全匿名の会議では質問と同じ仮名にし，利用者IDを保存しない
+func meetingParticipantId(db *gorm.DB, documentId int, userId string) string {
+	return questionAskerId(db, documentId, userId)
+}
+
+// visibleAskerId は匿名性の設定に従って質問者として見せるIDを返す
+// ホストには完全匿名以外で利用者ID，完全匿名では仮名を見せ，
+// 会議の参加者には質問者を明かす設定の場合のみ見せる
+func visibleAskerId(level string, forHost bool, question Question) string {
+	if question.IsVoice || question.UserId == moderatorUserId {
+		return ""
+	}
+	if forHost || level == anonymityAttributed {
+		return question.UserId
+	}
+	return ""
+}
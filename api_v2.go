@@ -0,0 +1,443 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+)
+
+// /api/v2 はリソース毎のURLとHTTPのメソッド・ステータスで操作を表すAPI
+// 失敗した場合は常に APIError の形で返す (v1 の {result: false} は返さない)
+// v1 の経路は既存のフロントエンドのためにそのまま残す
+
+// APIError は v2 API のエラーの本文
+type APIError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details"`
+}
+
+type UserResource struct {
+	UserId   string `json:"userId"`
+	UserName string `json:"userName"`
+}
+
+type MeetingResource struct {
+	MeetingId        int                 `json:"meetingId"`
+	MeetingName      string              `json:"meetingName"`
+	MeetingStartTime string              `json:"meetingStartTime"`
+	MeetingEndTime   string              `json:"meetingEndTime"` // 終了前は空
+	MeetingDone      bool                `json:"meetingDone"`
+	HostId           string              `json:"hostId"`
+	PreModeration    bool                `json:"preModeration"`
+	AnonymityLevel   string              `json:"anonymityLevel"`
+	Presenters       []PresenterResource `json:"presenters"` // 発表順
+}
+
+type PresenterResource struct {
+	UserId     string `json:"userId"`
+	UserName   string `json:"userName"`
+	DocumentId int    `json:"documentId"`
+}
+
+type DocumentResource struct {
+	DocumentId     int                   `json:"documentId"`
+	MeetingId      int                   `json:"meetingId"`
+	PresenterId    string                `json:"presenterId"`
+	DocumentUrl    string                `json:"documentUrl"`
+	Script         string                `json:"script"`
+	ScriptSegments []ScriptSegmentObject `json:"scriptSegments"` // ページ順
+	PageCount      int                   `json:"pageCount"`
+	Version        int                   `json:"version"`
+}
+
+type QuestionPageResource struct {
+	Questions  []QuestionResult `json:"questions"`
+	NextCursor string           `json:"nextCursor"` // 次のページが無ければ空
+}
+
+type CreateUserRequest struct {
+	UserId       string `json:"userId"`
+	UserName     string `json:"userName"`
+	UserPassword string `json:"userPassword"`
+}
+
+// UpdateDocumentRequest は指定した項目だけを変更する
+type UpdateDocumentRequest struct {
+	UserId         string                `json:"userId"` // 発表者またはホスト
+	DocumentUrl    *string               `json:"documentUrl"`
+	Script         *string               `json:"script"`
+	ScriptSegments []ScriptSegmentObject `json:"scriptSegments"`
+}
+
+// writeAPIError はエラーをHTTPのステータスと APIError の本文にする
+// StoreError 以外のエラーは内容を返さずに internal_error とする
+func writeAPIError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	body := APIError{Code: "internal_error", Message: "internal server error", Details: map[string]interface{}{}}
+
+	var storeErr *StoreError
+	if errors.As(err, &storeErr) && storeErr.Kind != nil {
+		body.Code, body.Message = storeErr.Code, storeErr.Message
+		if storeErr.Details != nil {
+			body.Details = storeErr.Details
+		}
+		switch {
+		case errors.Is(storeErr, errNotFound):
+			status = http.StatusNotFound
+		case errors.Is(storeErr, errConflict):
+			status = http.StatusConflict
+		case errors.Is(storeErr, errInvalid):
+			status = http.StatusBadRequest
+		case errors.Is(storeErr, errForbidden):
+			status = http.StatusForbidden
+		case errors.Is(storeErr, errUnauthorized):
+			status = http.StatusUnauthorized
+		}
+	}
+	if status == http.StatusInternalServerError {
+		fmt.Printf("Error: %v in writeAPIError\n", err)
+	}
+	return c.JSON(status, body)
+}
+
+func paramId(c echo.Context, name string) (int, error) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil || id <= 0 {
+		return 0, invalidError("invalid_id", "invalid "+name, map[string]interface{}{name: c.Param(name)})
+	}
+	return id, nil
+}
+
+func bindRequest(c echo.Context, request interface{}) error {
+	if err := c.Bind(request); err != nil {
+		return invalidError("invalid_body", "invalid request body", nil)
+	}
+	return nil
+}
+
+func meetingResource(db *gorm.DB, meeting Meeting) MeetingResource {
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	resource := MeetingResource{
+		MeetingId:        meeting.MeetingId,
+		MeetingName:      meeting.MeetingName,
+		MeetingStartTime: meeting.MeetingStartTime.In(location).Format("2006/01/02 15:04:05"),
+		MeetingDone:      meeting.MeetingDone,
+		HostId:           meeting.HostUserId,
+		PreModeration:    meeting.PreModeration,
+		AnonymityLevel:   meeting.AnonymityLevel,
+		Presenters:       make([]PresenterResource, 0, 10),
+	}
+	if meeting.MeetingEndTime != nil {
+		resource.MeetingEndTime = meeting.MeetingEndTime.In(location).Format("2006/01/02 15:04:05")
+	}
+	for _, presenter := range getPresenters(db, meeting.MeetingId) {
+		resource.Presenters = append(resource.Presenters, PresenterResource{
+			UserId:     presenter.UserId,
+			UserName:   getUserName(db, presenter.UserId),
+			DocumentId: getDocumentId(db, presenter.UserId, meeting.MeetingId),
+		})
+	}
+	return resource
+}
+
+func documentResource(db *gorm.DB, document Document) (DocumentResource, error) {
+	segments, err := findScriptSegments(db, document.DocumentId)
+	if err != nil {
+		return DocumentResource{}, err
+	}
+	resource := DocumentResource{
+		DocumentId:     document.DocumentId,
+		MeetingId:      document.MeetingId,
+		PresenterId:    document.UserId,
+		ScriptSegments: make([]ScriptSegmentObject, 0, len(segments)),
+		PageCount:      document.PageCount,
+		Version:        document.Version,
+	}
+	if document.DocumentUrl != nil {
+		resource.DocumentUrl = *document.DocumentUrl
+	}
+	if document.Script != nil {
+		resource.Script = *document.Script
+	}
+	for _, segment := range segments {
+		resource.ScriptSegments = append(resource.ScriptSegments, ScriptSegmentObject{DocumentPage: segment.DocumentPage, Script: segment.Script})
+	}
+	return resource, nil
+}
+
+// questionResource は質問をuserIdの利用者に見せる形にする
+// 公開中でない質問は質問者とホストにだけ見せる
+func questionResource(db *gorm.DB, question Question, userId string) (QuestionResult, error) {
+	document, err := findDocument(db, question.DocumentId)
+	if err != nil {
+		return QuestionResult{}, err
+	}
+	meeting, err := findMeeting(db, document.MeetingId)
+	if err != nil {
+		return QuestionResult{}, err
+	}
+	forHost := isHost(db, meeting.MeetingId, userId)
+	if question.ModerationStatus != questionApproved && !forHost && !isQuestionAsker(db, userId, question) {
+		return QuestionResult{}, notFoundError("question", question.QuestionId)
+	}
+	return questionResult(meeting.MeetingId, document.UserId, visibleAskerId(meeting.AnonymityLevel, forHost, question), question), nil
+}
+
+func initRoutingV2(g *echo.Group, hub *Hub, db *gorm.DB, loginLimiter *rateLimiter) {
+	g.POST("/users", func(c echo.Context) error {
+		request := new(CreateUserRequest)
+		if err := bindRequest(c, request); err != nil {
+			return writeAPIError(c, err)
+		}
+		user := User{UserId: request.UserId, UserName: request.UserName, UserPassword: request.UserPassword}
+		if err := insertUser(db, user); err != nil {
+			return writeAPIError(c, err)
+		}
+		return c.JSON(http.StatusCreated, &UserResource{UserId: user.UserId, UserName: user.UserName})
+	})
+
+	g.POST("/sessions", func(c echo.Context) error {
+		request := new(UserLoginRequest)
+		if err := bindRequest(c, request); err != nil {
+			return writeAPIError(c, err)
+		}
+		// 総当たり対策は v1 の /user/login と同じ制限を共有する
+		ipOk, ipRetry := loginLimiter.allow("ip/"+c.RealIP(), hub.config.RateLimit.Login)
+		userOk, userRetry := loginLimiter.allow("user/"+request.UserId, hub.config.RateLimit.Login)
+		if !ipOk || !userOk {
+			throttledEvents.WithLabelValues("login", throttleRateLimited).Inc()
+			retryAfter := ipRetry
+			if userRetry > retryAfter {
+				retryAfter = userRetry
+			}
+			return c.JSON(http.StatusTooManyRequests, &APIError{
+				Code:    "rate_limited",
+				Message: "too many login attempts",
+				Details: map[string]interface{}{"retryAfter": int(retryAfter.Seconds() + 0.5)},
+			})
+		}
+		user, err := authenticateUser(db, request.UserId, request.UserPassword)
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		return c.JSON(http.StatusOK, &UserResource{UserId: user.UserId, UserName: user.UserName})
+	})
+
+	g.POST("/meetings", func(c echo.Context) error {
+		request := new(CreateMeetingRequest)
+		if err := bindRequest(c, request); err != nil {
+			return writeAPIError(c, err)
+		}
+		location, _ := time.LoadLocation("Asia/Tokyo")
+		startTime, err := time.ParseInLocation("2006/01/02 15:04:05", request.MeetingStartTime, location)
+		if err != nil {
+			return writeAPIError(c, invalidError("invalid_meeting", "meetingStartTime must be formatted as 2006/01/02 15:04:05", map[string]interface{}{"meetingStartTime": request.MeetingStartTime}))
+		}
+		meeting, err := insertMeeting(db, Meeting{
+			MeetingName:      request.MeetingName,
+			MeetingStartTime: startTime,
+			HostUserId:       request.HostId,
+			PreModeration:    request.PreModeration,
+			AnonymityLevel:   request.AnonymityLevel,
+		}, request.PresenterIds)
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		return c.JSON(http.StatusCreated, meetingResource(db, meeting))
+	})
+
+	g.GET("/meetings/:id", func(c echo.Context) error {
+		meetingId, err := paramId(c, "id")
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		meeting, err := findMeeting(db, meetingId)
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		return c.JSON(http.StatusOK, meetingResource(db, meeting))
+	})
+
+	// 会議への参加 (v1 の /meeting/join)
+	g.PUT("/meetings/:id/participants/:userId", func(c echo.Context) error {
+		meetingId, err := paramId(c, "id")
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		meeting, err := findMeeting(db, meetingId)
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		if _, err := findUser(db, c.Param("userId")); err != nil {
+			return writeAPIError(c, err)
+		}
+		ok, _, startTime, _, _, _ := joinMeeting(db, c.Param("userId"), meetingId)
+		if !ok {
+			return writeAPIError(c, &StoreError{Kind: errConflict, Code: "join_failed", Message: "failed to join the meeting"})
+		}
+		go hub.sendStartMeetingMessage(meetingId, startTime)
+		return c.JSON(http.StatusOK, meetingResource(db, meeting))
+	})
+
+	// 会議からの退出 (v1 の /meeting/exit)，?documentId= の資料への挙手を取り下げる
+	g.DELETE("/meetings/:id/participants/:userId", func(c echo.Context) error {
+		meetingId, err := paramId(c, "id")
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		if _, err := findMeeting(db, meetingId); err != nil {
+			return writeAPIError(c, err)
+		}
+		documentId, _ := strconv.Atoi(c.QueryParam("documentId"))
+		if !exitMeeting(db, c.Param("userId"), meetingId, documentId) {
+			return writeAPIError(c, &StoreError{Code: "internal_error", Message: "failed to exit the meeting"})
+		}
+		handsQueueBroadcast(hub, db, documentId)
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	g.GET("/meetings/:id/questions", func(c echo.Context) error {
+		meetingId, err := paramId(c, "id")
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		meeting, err := findMeeting(db, meetingId)
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		result, err := listMeetingQuestions(c, db, meeting)
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		return c.JSON(http.StatusOK, &QuestionPageResource{Questions: result.Questions, NextCursor: result.NextCursor})
+	})
+
+	g.GET("/meetings/:id/page", func(c echo.Context) error {
+		meetingId, err := paramId(c, "id")
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		if _, err := findMeeting(db, meetingId); err != nil {
+			return writeAPIError(c, err)
+		}
+		found, state := getModeratorState(db, meetingId)
+		if !found {
+			return writeAPIError(c, &StoreError{Kind: errNotFound, Code: "page_not_found", Message: "the meeting has not started", Details: map[string]interface{}{"id": meetingId}})
+		}
+		location, _ := time.LoadLocation("Asia/Tokyo")
+		return c.JSON(http.StatusOK, &CurrentPageResult{
+			Result:        true,
+			MeetingId:     meetingId,
+			PresenterId:   state.PresenterId,
+			DocumentId:    state.DocumentId,
+			DocumentPage:  state.DocumentPage,
+			PageChangedAt: state.PageChangedAt.In(location).Format("2006/01/02 15:04:05"),
+		})
+	})
+
+	g.GET("/documents/:id", func(c echo.Context) error {
+		documentId, err := paramId(c, "id")
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		document, err := findDocument(db, documentId)
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		resource, err := documentResource(db, document)
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		return c.JSON(http.StatusOK, resource)
+	})
+
+	// 資料URLと原稿の登録 (v1 の /document/register)
+	g.PATCH("/documents/:id", func(c echo.Context) error {
+		documentId, err := paramId(c, "id")
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		request := new(UpdateDocumentRequest)
+		if err := bindRequest(c, request); err != nil {
+			return writeAPIError(c, err)
+		}
+		segments := make([]ScriptSegment, 0, len(request.ScriptSegments))
+		for _, segment := range request.ScriptSegments {
+			segments = append(segments, ScriptSegment{DocumentPage: segment.DocumentPage, Script: segment.Script})
+		}
+		document, err := updateDocument(db, request.UserId, documentId, request.DocumentUrl, request.Script, segments)
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		hub.sendDocumentUpdate(document.MeetingId, document.DocumentId, document.Version)
+		resource, err := documentResource(db, document)
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		return c.JSON(http.StatusOK, resource)
+	})
+
+	// 質問の取得 (?userId=閲覧者)
+	g.GET("/questions/:id", func(c echo.Context) error {
+		questionId, err := paramId(c, "id")
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		question, err := findQuestion(db, questionId)
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		resource, err := questionResource(db, question, c.QueryParam("userId"))
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		return c.JSON(http.StatusOK, resource)
+	})
+
+	// 質問の本文の変更 (v1 の /question/:id/edit)
+	g.PATCH("/questions/:id", func(c echo.Context) error {
+		questionId, err := paramId(c, "id")
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		request := new(QuestionEditRequest)
+		if err := bindRequest(c, request); err != nil {
+			return writeAPIError(c, err)
+		}
+		if status, code := editOwnQuestion(hub, db, request.UserId, questionId, request.QuestionBody); code != "" {
+			return writeAPIError(c, statusError(status, code))
+		}
+		question, err := findQuestion(db, questionId)
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		resource, err := questionResource(db, question, request.UserId)
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		return c.JSON(http.StatusOK, resource)
+	})
+
+	// 質問の取り下げ (v1 の /question/:id/withdraw)，?userId=質問者かホスト
+	g.DELETE("/questions/:id", func(c echo.Context) error {
+		questionId, err := paramId(c, "id")
+		if err != nil {
+			return writeAPIError(c, err)
+		}
+		if status, code := withdrawQuestion(hub, db, c.QueryParam("userId"), questionId); code != "" {
+			return writeAPIError(c, statusError(status, code))
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	// v2 の存在しない経路もエラーの形を揃える
+	g.Any("/*", func(c echo.Context) error {
+		return writeAPIError(c, &StoreError{Kind: errNotFound, Code: "route_not_found", Message: "route not found", Details: map[string]interface{}{"method": c.Request().Method, "path": c.Request().URL.Path}})
+	})
+}
@@ -0,0 +1,751 @@
+// Code generated by websocket --generate-sdk; DO NOT EDIT.
+
+export interface APIError {
+  code: string;
+  message: string;
+  details: Record<string, unknown>;
+}
+
+export interface AnswerObject {
+  answerId: number;
+  userId: string;
+  answerBody: string;
+  answeredAt: string;
+  isFollowUp: boolean;
+}
+
+export interface CreateMeetingRequest {
+  meetingName: string;
+  meetingStartTime: string;
+  presenterIds: string[];
+  hostId: string;
+  preModeration: boolean;
+  anonymityLevel: string;
+}
+
+export interface CreateMeetingResult {
+  result: boolean;
+  meetingId: number;
+  meetingName: string;
+}
+
+export interface CreateUserRequest {
+  userId: string;
+  userName: string;
+  userPassword: string;
+}
+
+export interface CurrentPageResult {
+  result: boolean;
+  meetingId: number;
+  presenterId: string;
+  documentId: number;
+  documentPage: number;
+  pageChangedAt: string;
+}
+
+export interface DocumentAnalyticsResult {
+  result: boolean;
+  documentId: number;
+  documentVersion: number;
+  totalDwellSeconds: number;
+  pages: PageAnalytics[];
+}
+
+export interface DocumentGetRequest {
+  documentId: number;
+}
+
+export interface DocumentGetResult {
+  result: boolean;
+  documentUrl: string;
+  script: string;
+  scriptSegments: ScriptSegmentObject[];
+}
+
+export interface DocumentPageResult {
+  documentPage: number;
+  text: string;
+  layoutUrl: string;
+  width: number;
+  height: number;
+}
+
+export interface DocumentPagesResult {
+  result: boolean;
+  documentId: number;
+  pageCount: number;
+  pages: DocumentPageResult[];
+}
+
+export interface DocumentReactionsResult {
+  result: boolean;
+  meetingId: number;
+  documentId: number;
+  documentVersion: number;
+  pages: PageReactionsResult[];
+  window: number;
+  recent: PageReactionsResult[];
+}
+
+export interface DocumentRegisterRequest {
+  documentId: number;
+  documentUrl: string;
+  script: string;
+  scriptSegments: ScriptSegmentObject[];
+}
+
+export interface DocumentRegisterResult {
+  result: boolean;
+  documentVersion: number;
+}
+
+export interface DocumentResource {
+  documentId: number;
+  meetingId: number;
+  presenterId: string;
+  documentUrl: string;
+  script: string;
+  scriptSegments: ScriptSegmentObject[];
+  pageCount: number;
+  version: number;
+}
+
+export interface DocumentRestoreRequest {
+  userId: string;
+}
+
+export interface DocumentRestoreResult {
+  result: boolean;
+  documentVersion: number;
+}
+
+export interface DocumentUpdateResult {
+  messageType: string;
+  meetingId: number;
+  documentId: number;
+  documentVersion: number;
+}
+
+export interface DocumentUploadResult {
+  result: boolean;
+  documentUrl: string;
+  pageCount: number;
+  documentVersion: number;
+}
+
+export interface DocumentVersionGetResult {
+  result: boolean;
+  documentId: number;
+  version: DocumentVersionResult;
+}
+
+export interface DocumentVersionResult {
+  documentVersion: number;
+  documentUrl: string;
+  script?: string;
+  scriptSegments?: ScriptSegmentObject[];
+  pageCount: number;
+  createdAt: string;
+}
+
+export interface DocumentVersionsResult {
+  result: boolean;
+  documentId: number;
+  documentVersion: number;
+  versions: DocumentVersionResult[];
+}
+
+export interface DuplicateCandidate {
+  questionId: number;
+  questionBody: string;
+  documentPage: number;
+  voteNum: number;
+  similarity: number;
+}
+
+export interface DuplicateQuestionsResult {
+  result: boolean;
+  meetingId: number;
+  questions: QuestionModerationResult[];
+}
+
+export interface ErrorResult {
+  messageType: string;
+  code: string;
+  message: string;
+  retryAfter: number;
+}
+
+export interface ExitMeetingRequest {
+  userId: string;
+  meetingId: number;
+  documentId: number;
+}
+
+export interface ExitMeetingResult {
+  result: boolean;
+}
+
+export interface FinishWordMessage {
+  messageType: string;
+  meetingId: number;
+  presenterId: string;
+  finishType: string;
+  questionUserId?: string;
+}
+
+export interface FollowMessage {
+  messageType: string;
+  follow: boolean;
+}
+
+export interface FollowUpAnswerRequest {
+  userId: string;
+  answerBody: string;
+}
+
+export interface FollowUpAnswerResponse {
+  result: boolean;
+  answerId: number;
+}
+
+export interface FollowUpAnswerResult {
+  messageType: string;
+  meetingId: number;
+  questionId: number;
+  questionBody: string;
+  documentId: number;
+  documentPage: number;
+  answerBody: string;
+  answeredBy: string;
+  answeredAt: string;
+}
+
+export interface HandResult {
+  userId: string;
+  userName: string;
+  position: number;
+  documentPage: number;
+  raisedAt: string;
+}
+
+export interface HandsModerateMessage {
+  messageType: string;
+  documentId: number;
+  userId: string;
+  action: string;
+  position?: number;
+}
+
+export interface HandsModerateRequest {
+  hostId: string;
+  userId: string;
+  action: string;
+  position: number;
+}
+
+export interface HandsQueueResult {
+  messageType: string;
+  meetingId: number;
+  documentId: number;
+  presenterId: string;
+  hands: HandResult[];
+}
+
+export interface HandsResult {
+  result: boolean;
+  meetingId: number;
+  documentId: number;
+  presenterId: string;
+  hands: HandResult[];
+}
+
+export interface HandsUpMessage {
+  messageType: string;
+  userId: string;
+  documentId: number;
+  documentPage?: number;
+  isUp: boolean;
+}
+
+export interface HandsUpResult {
+  messageType: string;
+  meetingId: number;
+  userId: string;
+  documentId: number;
+  isUp: boolean;
+  position: number;
+}
+
+export interface HealthResult {
+  status: string;
+  checks?: Record<string, string>;
+}
+
+export interface InboxMessageResult {
+  inboxMessageId: number;
+  messageType: string;
+  message: unknown;
+  createdAt: string;
+  isRead: boolean;
+}
+
+export interface InboxResult {
+  result: boolean;
+  userId: string;
+  messages: InboxMessageResult[];
+}
+
+export interface JoinMeetingRequest {
+  userId: string;
+  meetingId: number;
+}
+
+export interface JoinMeetingResult {
+  result: boolean;
+  meetingName: string;
+  meetingStartTime: string;
+  presenterNames: string[];
+  presenterIds: string[];
+  documentIds: number[];
+  anonymityLevel: string;
+}
+
+export interface MeetingResource {
+  meetingId: number;
+  meetingName: string;
+  meetingStartTime: string;
+  meetingEndTime: string;
+  meetingDone: boolean;
+  hostId: string;
+  preModeration: boolean;
+  anonymityLevel: string;
+  presenters: PresenterResource[];
+}
+
+export interface Message {
+  messageType: string;
+  message: string;
+}
+
+export interface ModerationQueueResult {
+  result: boolean;
+  meetingId: number;
+  questions: QuestionModerationResult[];
+}
+
+export interface ModeratorMsg {
+  messageType: string;
+  meetingId: number;
+  moderatorMsgBody: string;
+  isStartPresen: boolean;
+  questionId: number;
+  questionUserId: string;
+  presentOrder: number;
+}
+
+export interface MuteMessage {
+  messageType: string;
+  meetingId: number;
+  userId: string;
+  isMute: boolean;
+  duration?: number;
+}
+
+export interface MuteResult {
+  messageType: string;
+  meetingId: number;
+  userId: string;
+  isMute: boolean;
+  muteUntil: string;
+}
+
+export interface NotificationResult {
+  messageType: string;
+  meetingId: number;
+  kind: string;
+  roles: string[];
+  documentId: number;
+  documentPage?: number;
+  count: number;
+  message: string;
+  createdAt: string;
+}
+
+export interface PaceSuggestionResult {
+  messageType: string;
+  meetingId: number;
+  documentId: number;
+  documentPage: number;
+  kind: string;
+  reactionNum: number;
+  message: string;
+}
+
+export interface PageAnalytics {
+  documentPage: number;
+  dwellSeconds: number;
+  viewCount: number;
+  reactionNum: number;
+  reactions: Record<string, number>;
+  questionNum: number;
+  voteNum: number;
+  handsUpNum: number;
+}
+
+export interface PageChangeMessage {
+  messageType: string;
+  meetingId: number;
+  documentId: number;
+  documentPage: number;
+}
+
+export interface PageChangeResult {
+  messageType: string;
+  meetingId: number;
+  presenterId: string;
+  documentId: number;
+  documentPage: number;
+  documentVersion: number;
+  changedAt: string;
+}
+
+export interface PageReactionsResult {
+  documentPage: number;
+  counts: Record<string, number>;
+  emojiCounts: Record<string, number>;
+}
+
+export interface PreModerationRequest {
+  hostId: string;
+  enabled: boolean;
+}
+
+export interface PresenterResource {
+  userId: string;
+  userName: string;
+  documentId: number;
+}
+
+export interface QuestionDuplicateResult {
+  messageType: string;
+  meetingId: number;
+  questionId: number;
+  candidates: DuplicateCandidate[];
+}
+
+export interface QuestionEditMessage {
+  messageType: string;
+  questionId: number;
+  questionBody: string;
+}
+
+export interface QuestionEditRequest {
+  userId: string;
+  questionBody: string;
+}
+
+export interface QuestionEditResult {
+  userId: string;
+  previousBody: string;
+  editedAt: string;
+}
+
+export interface QuestionHistoryResult {
+  result: boolean;
+  questionId: number;
+  questionBody: string;
+  edits: QuestionEditResult[];
+}
+
+export interface QuestionListResult {
+  result: boolean;
+  meetingId: number;
+  questions: QuestionResult[];
+  nextCursor: string;
+}
+
+export interface QuestionMergeMessage {
+  messageType: string;
+  questionId: number;
+  mergeInto: number;
+}
+
+export interface QuestionMergeRequest {
+  userId: string;
+  userPassword: string;
+  mergeInto: number;
+}
+
+export interface QuestionMessage {
+  messageType: string;
+  userId: string;
+  meetingId: number;
+  questionBody: string;
+  documentId: number;
+  documentPage?: number;
+  questionTime: string;
+  documentVersion?: number;
+}
+
+export interface QuestionModerateMessage {
+  messageType: string;
+  questionId: number;
+  action: string;
+  questionBody?: string;
+  mergeInto?: number;
+}
+
+export interface QuestionModerateRequest {
+  hostId: string;
+  action: string;
+  questionBody: string;
+  mergeInto: number;
+}
+
+export interface QuestionModerationResult {
+  messageType: string;
+  meetingId: number;
+  action: string;
+  hostId: string;
+  askerId: string;
+  questionId: number;
+  questionBody: string;
+  documentId: number;
+  documentPage: number;
+  questionTime: string;
+  voteNum: number;
+  mergedInto: number;
+  duplicateOf: number;
+}
+
+export interface QuestionPageResource {
+  questions: QuestionResult[];
+  nextCursor: string;
+}
+
+export interface QuestionReplyMessage {
+  messageType: string;
+  questionId: number;
+  userId?: string;
+  body: string;
+  isAnswer?: boolean;
+  parentReplyId?: number;
+}
+
+export interface QuestionReplyResult {
+  messageType: string;
+  meetingId: number;
+  questionId: number;
+  isAnswer: boolean;
+  answerId: number;
+  replyId: number;
+  parentReplyId: number;
+  userId: string;
+  body: string;
+  repliedAt: string;
+}
+
+export interface QuestionResult {
+  messageType: string;
+  questionId: number;
+  meetingId: number;
+  questionBody: string;
+  documentId: number;
+  documentPage: number;
+  questionTime: string;
+  presenterId: string;
+  askerId: string;
+  voteNum: number;
+  isAnswered: boolean;
+  isVoice: boolean;
+  documentVersion: number;
+}
+
+export interface QuestionThread {
+  questionId: number;
+  answers: AnswerObject[];
+  replies: ReplyObject[];
+}
+
+export interface QuestionUpdateResult {
+  messageType: string;
+  meetingId: number;
+  questionId: number;
+  questionBody: string;
+  moderationStatus: string;
+  mergedInto: number;
+}
+
+export interface QuestionVoteMessage {
+  messageType: string;
+  questionId: number;
+  isVote: boolean;
+}
+
+export interface QuestionVoteResult {
+  messageType: string;
+  meetingId: number;
+  questionId: number;
+  voteNum: number;
+}
+
+export interface QuestionWithdrawMessage {
+  messageType: string;
+  questionId: number;
+}
+
+export interface QuestionWithdrawRequest {
+  userId: string;
+}
+
+export interface QuestionsGetRequest {
+  meetingId: number;
+}
+
+export interface QuestionsGetResult {
+  result: boolean;
+  meetingId: number;
+  questionIds: number[];
+  questionBodys: string[];
+  documentIds: number[];
+  documentPages: number[];
+  questionTimes: string[];
+  presenterIds: string[];
+  voteNums: number[];
+  askerIds: string[];
+  threads: QuestionThread[];
+}
+
+export interface ReactionMessage {
+  messageType: string;
+  documentId: number;
+  documentPage: number;
+  isReaction: boolean;
+  kind?: string;
+  emoji?: string;
+}
+
+export interface ReactionResult {
+  messageType: string;
+  meetingId: number;
+  documentId: number;
+  documentPage: number;
+  reactionNum: number;
+  kind: string;
+  emoji?: string;
+  counts: Record<string, number>;
+  emojiCounts: Record<string, number>;
+  documentVersion: number;
+}
+
+export interface ReplyObject {
+  replyId: number;
+  userId: string;
+  replyBody: string;
+  repliedAt: string;
+  replies: ReplyObject[];
+}
+
+export interface Result {
+  result: boolean;
+}
+
+export interface ScriptSegmentObject {
+  documentPage: number;
+  script: string;
+}
+
+export interface ServerShutdownResult {
+  messageType: string;
+  reconnectAfter: number;
+}
+
+export interface UnansweredQuestionResult {
+  questionId: number;
+  questionBody: string;
+  documentId: number;
+  documentPage: number;
+  voteNum: number;
+  questionTime: string;
+}
+
+export interface UnansweredQuestionsResult {
+  result: boolean;
+  meetingId: number;
+  questions: UnansweredQuestionResult[];
+}
+
+export interface UpdateDocumentRequest {
+  userId: string;
+  documentUrl: string | null;
+  script: string | null;
+  scriptSegments: ScriptSegmentObject[];
+}
+
+export interface UserLoginRequest {
+  userId: string;
+  userPassword: string;
+}
+
+export interface UserLoginResult {
+  result: boolean;
+  userName: string;
+}
+
+export interface UserResource {
+  userId: string;
+  userName: string;
+}
+
+export interface UserSignupRequest {
+  userId: string;
+  userName: string;
+  userPassword: string;
+}
+
+export interface ClientMessageMap {
+  message: Message & { messageType: "message" };
+  question: QuestionMessage & { messageType: "question" };
+  question_vote: QuestionVoteMessage & { messageType: "question_vote" };
+  handsup: HandsUpMessage & { messageType: "handsup" };
+  reaction: ReactionMessage & { messageType: "reaction" };
+  question_reply: QuestionReplyMessage & { messageType: "question_reply" };
+  question_moderate: QuestionModerateMessage & { messageType: "question_moderate" };
+  question_merge: QuestionMergeMessage & { messageType: "question_merge" };
+  question_edit: QuestionEditMessage & { messageType: "question_edit" };
+  question_withdraw: QuestionWithdrawMessage & { messageType: "question_withdraw" };
+  hands_moderate: HandsModerateMessage & { messageType: "hands_moderate" };
+  mute: MuteMessage & { messageType: "mute" };
+  page_change: PageChangeMessage & { messageType: "page_change" };
+  follow: FollowMessage & { messageType: "follow" };
+  finishword: FinishWordMessage & { messageType: "finishword" };
+}
+
+export type ClientMessage = ClientMessageMap[keyof ClientMessageMap];
+
+export interface ServerMessageMap {
+  message: Message & { messageType: "message" };
+  question: QuestionResult & { messageType: "question" };
+  question_vote: QuestionVoteResult & { messageType: "question_vote" };
+  handsup: HandsUpResult & { messageType: "handsup" };
+  hands_queue: HandsQueueResult & { messageType: "hands_queue" };
+  reaction: ReactionResult & { messageType: "reaction" };
+  pace_suggestion: PaceSuggestionResult & { messageType: "pace_suggestion" };
+  notification: NotificationResult & { messageType: "notification" };
+  question_reply: QuestionReplyResult & { messageType: "question_reply" };
+  mute: MuteResult & { messageType: "mute" };
+  page_change: PageChangeResult & { messageType: "page_change" };
+  document_update: DocumentUpdateResult & { messageType: "document_update" };
+  moderator_msg: ModeratorMsg & { messageType: "moderator_msg" };
+  question_moderation: QuestionModerationResult & { messageType: "question_moderation" };
+  question_update: QuestionUpdateResult & { messageType: "question_update" };
+  question_duplicate: QuestionDuplicateResult & { messageType: "question_duplicate" };
+  followup_answer: FollowUpAnswerResult & { messageType: "followup_answer" };
+  server_shutdown: ServerShutdownResult & { messageType: "server_shutdown" };
+  error: ErrorResult & { messageType: "error" };
+}
+
+export type ServerMessage = ServerMessageMap[keyof ServerMessageMap];
+
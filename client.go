@@ -1,437 +1,952 @@
-// Copyright 2013 The Gorilla WebSocket Authors. All rights reserved.
-// Use of this source code is governed by a BSD-style
-// license that can be found in the LICENSE file.
-
-package main
-
-import (
-	"encoding/json"
-	"fmt"
-	"log"
-	"net/http"
-	"time"
-
-	"github.com/gorilla/websocket"
-	"github.com/jinzhu/gorm"
-)
-
-const (
-	// Time allowed to write a message to the peer.
-	writeWait = 10 * time.Second
-
-	// Time allowed to read the next pong message from the peer.
-	pongWait = 60 * time.Second
-
-	// Send pings to peer with this period. Must be less than pongWait.
-	pingPeriod = (pongWait * 9) / 10
-
-	// Maximum message size allowed from peer.
-	maxMessageSize = 512
-)
-
-var (
-	newline    = []byte{'\n'}
-	space      = []byte{' '}
-	isReserved = map[int]bool{} // 会議開始の司会メッセージを通知予約したか
-)
-
-var (
-	db *gorm.DB
-)
-
-func dbsetting(database *gorm.DB) {
-	db = database
-}
-
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
-
-// Client is a middleman between the websocket connection and the hub.
-type Client struct {
-	hub *Hub // 親となるHub
-
-	// The websocket connection.
-	conn *websocket.Conn // 自分のwebsocket
-
-	// Buffered channel of outbound messages.
-	send chan []byte // broadcastのメッセージを受け取るチャネル
-}
-
-type Message struct {
-	MessageType string `json:"messageType"`
-	Message     string `json:"message"`
-}
-
-const (
-	ModeratorMsgType = "moderator_msg"
-)
-
-type DocumentUpdateResult struct {
-	MessageType string `json:"messageType"`
-	MeetingId   int    `json:"meetingId"`
-	DocumentId  int    `json:"documentId"`
-}
-
-type QuestionResult struct {
-	MessageType  string `json:"messageType"`
-	QuestionId   int    `json:"questionId"`
-	MeetingId    int    `json:"meetingId"`
-	QuestionBody string `json:"questionBody"`
-	DocumentId   int    `json:"documentId"`
-	DocumentPage int    `json:"documentPage"`
-	QuestionTime string `json:"questionTime"`
-	PresenterId  string `json:"presenterId"`
-}
-
-type QuestionVoteResult struct {
-	MessageType string `json:"messageType"`
-	MeetingId   int    `json:"meetingId"`
-	QuestionId  int    `json:"questionId"`
-	VoteNum     int    `json:"voteNum"`
-}
-
-type HandsUpResult struct {
-	MessageType string `json:"messageType"`
-	MeetingId   int    `json:"meetingId"`
-	UserId      string `json:"userId"`
-}
-
-type ReactionResult struct {
-	MessageType  string `json:"messageType"`
-	MeetingId    int    `json:"meetingId"`
-	DocumentId   int    `json:"documentId"`
-	DocumentPage int    `json:"documentPage"`
-	ReactionNum  int    `json:"reactionNum"`
-}
-
-type ModeratorMsg struct {
-	MessageType      string `json:"messageType"`
-	MeetingId        int    `json:"meetingId"`
-	ModeratorMsgBody string `json:"moderatorMsgBody"`
-	IsStartPresen    bool   `json:"isStartPresen"`
-	QuestionId       int    `json:"questionId"`
-	QuestionUserId   string `json:"questionUserId"`
-	PresentOrder     int    `json:"presentOrder"` // only if `IsStartPresen == true`, else = -1
-}
-
-var questionCount = make(map[int]int)
-
-const maxQuestionNum = 5
-
-func loadJson(byteArray []byte) (interface{}, error) {
-	var jsonObj interface{}
-	err := json.Unmarshal(byteArray, &jsonObj)
-	return jsonObj, err
-}
-
-// readPump pumps messages from the websocket connection to the hub.
-//
-// The application runs readPump in a per-connection goroutine. The application
-// ensures that there is at most one reader on a connection by executing all
-// reads from this goroutine.
-func (c *Client) readPump() {
-	defer func() {
-		c.hub.unregister <- c
-		c.conn.Close()
-		fmt.Println("Warning: Web SocketをCloseしました in readPump")
-	}()
-	c.conn.SetReadLimit(maxMessageSize)
-	c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(pongWait)); return nil })
-	for {
-		_, message, err := c.conn.ReadMessage()
-
-		// エラー処理
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("Error: error: %v in readPump\n", err)
-			}
-			break
-		}
-
-		// websocketで受け取ったデータの処理
-		jsonObj, jsonerr := loadJson(message)
-		if jsonerr != nil {
-			fmt.Printf("Error: loadJsonでエラーが発生しました in readPump\n")
-			continue
-		}
-		fmt.Printf("Log: Receive: " + string(message) + " in readPump\n")
-		message_type := jsonObj.(map[string]interface{})["messageType"].(string)
-
-		var messagestruct interface{}
-
-		switch message_type {
-		case "message":
-			message_jsonobj := jsonObj.(map[string]interface{})["message"].(string)
-			messagestruct = Message{MessageType: "message", Message: message_jsonobj}
-		case "question":
-			var (
-				layout      = "2006/01/02 15:04:05"
-				location, _ = time.LoadLocation("Asia/Tokyo")
-			)
-
-			userId := jsonObj.(map[string]interface{})["userId"].(string)
-			meetingId := int(jsonObj.(map[string]interface{})["meetingId"].(float64))
-			questionBody := jsonObj.(map[string]interface{})["questionBody"].(string)
-			documentId := int(jsonObj.(map[string]interface{})["documentId"].(float64))
-			documentPage := int(jsonObj.(map[string]interface{})["documentPage"].(float64))
-			questionTimeStr := jsonObj.(map[string]interface{})["questionTime"].(string)
-
-			questionTime, _ := time.ParseInLocation(layout, questionTimeStr, location)
-			question := Question{
-				UserId:       userId,
-				QuestionBody: questionBody,
-				DocumentId:   documentId,
-				DocumentPage: documentPage,
-				VoteNum:      0,
-				QuestionTime: questionTime,
-				IsVoice:      false,
-			}
-
-			isCreateQuestionOK, questionId := createQuestion(db, question)
-
-			if !isCreateQuestionOK {
-				return
-			}
-
-			presenterId := getPresenterId(db, documentId)
-
-			messagestruct = QuestionResult{
-				MessageType:  message_type,
-				QuestionId:   questionId,
-				MeetingId:    meetingId,
-				QuestionBody: questionBody,
-				DocumentId:   documentId,
-				DocumentPage: documentPage,
-				QuestionTime: questionTimeStr,
-				PresenterId:  presenterId,
-			}
-		case "question_vote":
-			questionId := int(jsonObj.(map[string]interface{})["questionId"].(float64))
-			isVote := jsonObj.(map[string]interface{})["isVote"].(bool)
-
-			meetingId, questionId, voteNum := voteQuestion(db, questionId, isVote)
-
-			messagestruct = QuestionVoteResult{
-				MessageType: message_type,
-				MeetingId:   meetingId,
-				QuestionId:  questionId,
-				VoteNum:     voteNum,
-			}
-		case "handsup":
-			userId := jsonObj.(map[string]interface{})["userId"].(string)
-			documentId := int(jsonObj.(map[string]interface{})["documentId"].(float64))
-			documentPage := int(jsonObj.(map[string]interface{})["documentPage"].(float64))
-			isUp := jsonObj.(map[string]interface{})["isUp"].(bool)
-
-			var meetingId int
-
-			if isUp {
-				meetingId = handsUp(db, userId, documentId, documentPage)
-			} else {
-				meetingId = handsDown(db, userId, documentId, documentPage)
-			}
-
-			messagestruct = HandsUpResult{
-				MessageType: message_type,
-				MeetingId:   meetingId,
-				UserId:      userId,
-			}
-		case "reaction":
-			documentId := int(jsonObj.(map[string]interface{})["documentId"].(float64))
-			documentPage := int(jsonObj.(map[string]interface{})["documentPage"].(float64))
-			isReaction := jsonObj.(map[string]interface{})["isReaction"].(bool)
-
-			var (
-				meetingId   int
-				reactionNum int
-			)
-
-			meetingId, reactionNum = voteReaction(db, documentId, documentPage, isReaction)
-
-			messagestruct = ReactionResult{
-				MessageType:  message_type,
-				MeetingId:    meetingId,
-				DocumentId:   documentId,
-				DocumentPage: documentPage,
-				ReactionNum:  reactionNum,
-			}
-		case "finishword":
-			meetingId := int(jsonObj.(map[string]interface{})["meetingId"].(float64))
-			presenterId := jsonObj.(map[string]interface{})["presenterId"].(string)
-			finishType := jsonObj.(map[string]interface{})["finishType"].(string)
-
-			var (
-				moderatorMsgBody string
-				questionId       int
-				questionUserId   string
-				isStartPresen    = false
-				nextOrder        = -1
-			)
-			// 規定の質問数に達した場合
-			if questionCount[meetingId] >= maxQuestionNum {
-				var (
-					endPresen  bool
-					nextUserId string
-				)
-				endPresen, nextUserId, nextOrder = getNextPresenterId(db, meetingId, presenterId)
-				if !endPresen {
-					moderatorMsgBody = personEnd(presenterId, nextUserId, meetingId)
-					isStartPresen = true
-					questionId = -1
-					questionUserId = ""
-				} else {
-					moderatorMsgBody = meetingEnd()
-					questionId = -1
-					questionUserId = ""
-				}
-				questionCount[meetingId] = 0
-			} else {
-				switch finishType {
-				case "present":
-					moderatorMsgBody, questionUserId, questionId = presenOrQuestionEnd(db, meetingId, presenterId, true, "")
-				case "question":
-					questionUserId = jsonObj.(map[string]interface{})["questionUserId"].(string)
-					moderatorMsgBody, questionUserId, questionId = presenOrQuestionEnd(db, meetingId, presenterId, false, questionUserId)
-				default:
-					fmt.Printf("Error: 予期せぬfinishType: %s in readPump\n", finishType)
-					continue
-				}
-				if questionCount[meetingId] == 0 {
-					questionCount[meetingId] = 1
-				} else {
-					questionCount[meetingId] += 1
-				}
-				fmt.Printf("Log: 現在の質問数：%d in readPump\n", questionCount[meetingId])
-			}
-
-			messagestruct = ModeratorMsg{
-				MessageType:      ModeratorMsgType,
-				MeetingId:        meetingId,
-				ModeratorMsgBody: moderatorMsgBody,
-				IsStartPresen:    isStartPresen,
-				QuestionId:       questionId,
-				QuestionUserId:   questionUserId,
-				PresentOrder:     nextOrder,
-			}
-		default:
-			continue
-		}
-		messagejson, _ := json.Marshal(messagestruct)
-
-		// 自分のメッセージをhubのbroadcastチャネルに送り込む
-		fmt.Printf("Log: Send: %+v in readPump\n", messagestruct)
-		c.hub.broadcast <- messagejson
-	}
-}
-
-func (hub *Hub) sendStartMeetingMessage(meetingId int, startTime time.Time) {
-	location, _ := time.LoadLocation("Asia/Tokyo")
-
-	if !isReserved[meetingId] {
-		isReserved[meetingId] = true
-		fmt.Printf("Log: 開始通知を予約しました: %s in sendStartMeetingMessage\n", startTime.In(location))
-		time.Sleep(time.Until(startTime.In(location)))
-		message := ModeratorMsg{
-			MessageType:      ModeratorMsgType,
-			MeetingId:        meetingId,
-			ModeratorMsgBody: meetingStart(meetingId),
-			IsStartPresen:    true,
-			QuestionId:       -1,
-			QuestionUserId:   "",
-			PresentOrder:     0,
-		}
-		messagejson, _ := json.Marshal(message)
-		hub.broadcast <- messagejson
-		fmt.Printf("Log: 開始通知を送信しました: %s in sendStartMeetingMessage\n", time.Now().In(location))
-		setMeetingDone(db, meetingId)
-	} else {
-		fmt.Printf("Log: 開始通知は既に予約済です: %s in sendStartMeetingMessage\n", startTime.In(location))
-	}
-}
-
-func (hub *Hub) sendDocumentUpdate(meetingId int, documentId int) {
-	messagestruct := DocumentUpdateResult{
-		MessageType: "document_update",
-		MeetingId:   meetingId,
-		DocumentId:  documentId,
-	}
-	messagejson, _ := json.Marshal(messagestruct)
-	hub.broadcast <- messagejson
-	fmt.Printf("Log: 資料更新通知を送信しました:%d, %d in sendDocumentUpdate\n", meetingId, documentId)
-}
-
-// writePump pumps messages from the hub to the websocket connection.
-//
-// A goroutine running writePump is started for each connection. The
-// application ensures that there is at most one writer to a connection by
-// executing all writes from this goroutine.
-func (c *Client) writePump() {
-	ticker := time.NewTicker(pingPeriod)
-	defer func() {
-		ticker.Stop()
-		c.conn.Close()
-		fmt.Println("Warning: Web SocketをCloseしました in writePump")
-	}()
-	for {
-		select {
-		case message, ok := <-c.send:
-			// タイムアウト時間の設定
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			// エラー処理
-			if !ok {
-				// The hub closed the channel.
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
-
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
-
-			// Add queued chat messages to the current websocket message.
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write(newline)
-				w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
-				return
-			}
-		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				return
-			}
-		}
-	}
-}
-
-// serveWs handles websocket requests from the peer.
-func serveWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
-	if err != nil {
-		fmt.Printf("Error: Web SocketへのUpgradeに失敗しました in serveWs\n")
-		log.Println(err)
-		return
-	} else {
-		fmt.Printf("Log: Web SocketへのUpgradeに成功しました in serveWs\n")
-	}
-	// sendは他の人からのメッセージが投入される
-	client := &Client{hub: hub, conn: conn, send: make(chan []byte, 256)}
-	client.hub.register <- client // hubのregisterチャネルに自分のClientを登録
-
-	// Allow collection of memory referenced by the caller by doing all work in
-	// new goroutines.
-	go client.writePump()
-	go client.readPump()
-}
+// Copyright 2013 The Gorilla WebSocket Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	newline    = []byte{'\n'}
+	space      = []byte{' '}
+	isReserved = map[int]bool{} // 会議開始の司会メッセージを通知予約したか
+	reservedMu sync.Mutex
+)
+
+var (
+	db *gorm.DB
+)
+
+func dbsetting(database *gorm.DB) {
+	db = database
+}
+
+// newUpgrader はCORSと同じ許可リストでOriginを確認するupgraderを返す
+// 許可リストが空の場合は同一オリジンのみ許可する(gorillaのデフォルト)
+func newUpgrader(allowedOrigins []string) websocket.Upgrader {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+	if len(allowedOrigins) != 0 {
+		upgrader.CheckOrigin = func(r *http.Request) bool {
+			return originAllowed(allowedOrigins, r.Header.Get("Origin"))
+		}
+	}
+	return upgrader
+}
+
+// Client is a middleman between the websocket connection and the hub.
+type Client struct {
+	hub *Hub // 親となるHub
+
+	// The websocket connection.
+	conn *websocket.Conn // 自分のwebsocket
+
+	// Buffered channel of outbound messages.
+	send chan []byte // broadcastのメッセージを受け取るチャネル
+
+	ip        string // 接続元のIPアドレス
+	userId    string // 接続時に指定されたユーザーID (未指定は空)
+	meetingId int    // 接続時に指定された会議ID (未指定は0)
+
+	claimedUserId string // userIdを指定していない接続でメッセージが最後に名乗ったユーザーID (readPumpのみが使う)
+
+	// 発表者のページに追従しない場合は1 (hubのゴルーチンからも読むためatomicで扱う)
+	unfollow int32
+}
+
+func (c *Client) following() bool {
+	return atomic.LoadInt32(&c.unfollow) == 0
+}
+
+func (c *Client) setFollowing(follow bool) {
+	if follow {
+		atomic.StoreInt32(&c.unfollow, 0)
+	} else {
+		atomic.StoreInt32(&c.unfollow, 1)
+	}
+}
+
+type Message struct {
+	MessageType string `json:"messageType"`
+	Message     string `json:"message"`
+}
+
+const (
+	ModeratorMsgType      = "moderator_msg"
+	PageChangeMsgType     = "page_change"
+	ServerShutdownMsgType = "server_shutdown"
+	ErrorMsgType          = "error"
+)
+
+type ErrorResult struct {
+	MessageType string `json:"messageType"`
+	Code        string `json:"code"`
+	Message     string `json:"message"`
+	RetryAfter  int    `json:"retryAfter"` // seconds
+}
+
+type MuteResult struct {
+	MessageType string `json:"messageType"`
+	MeetingId   int    `json:"meetingId"`
+	UserId      string `json:"userId"`
+	IsMute      bool   `json:"isMute"`
+	MuteUntil   string `json:"muteUntil"` // only if `IsMute == true`
+}
+
+type DocumentUpdateResult struct {
+	MessageType     string `json:"messageType"`
+	MeetingId       int    `json:"meetingId"`
+	DocumentId      int    `json:"documentId"`
+	DocumentVersion int    `json:"documentVersion"`
+}
+
+type PageChangeResult struct {
+	MessageType     string `json:"messageType"`
+	MeetingId       int    `json:"meetingId"`
+	PresenterId     string `json:"presenterId"`
+	DocumentId      int    `json:"documentId"`
+	DocumentPage    int    `json:"documentPage"`
+	DocumentVersion int    `json:"documentVersion"`
+	ChangedAt       string `json:"changedAt"`
+}
+
+type ServerShutdownResult struct {
+	MessageType    string `json:"messageType"`
+	ReconnectAfter int    `json:"reconnectAfter"` // seconds
+}
+
+type QuestionResult struct {
+	MessageType  string `json:"messageType"`
+	QuestionId   int    `json:"questionId"`
+	MeetingId    int    `json:"meetingId"`
+	QuestionBody string `json:"questionBody"`
+	DocumentId   int    `json:"documentId"`
+	DocumentPage int    `json:"documentPage"`
+	QuestionTime string `json:"questionTime"`
+	PresenterId  string `json:"presenterId"`
+	AskerId      string `json:"askerId"` // 質問者を明かす会議のみ (それ以外は空)
+	VoteNum      int    `json:"voteNum"`
+	IsAnswered   bool   `json:"isAnswered"`
+	IsVoice      bool   `json:"isVoice"`
+
+	DocumentVersion int `json:"documentVersion"`
+}
+
+type QuestionVoteResult struct {
+	MessageType string `json:"messageType"`
+	MeetingId   int    `json:"meetingId"`
+	QuestionId  int    `json:"questionId"`
+	VoteNum     int    `json:"voteNum"`
+}
+
+type HandsUpResult struct {
+	MessageType string `json:"messageType"`
+	MeetingId   int    `json:"meetingId"`
+	UserId      string `json:"userId"`
+	DocumentId  int    `json:"documentId"`
+	IsUp        bool   `json:"isUp"`
+	Position    int    `json:"position"` // 挙手の順番 (1から，下げた場合は0)
+}
+
+type ReactionResult struct {
+	MessageType  string `json:"messageType"`
+	MeetingId    int    `json:"meetingId"`
+	DocumentId   int    `json:"documentId"`
+	DocumentPage int    `json:"documentPage"`
+	ReactionNum  int    `json:"reactionNum"` // kindの数
+	Kind         string `json:"kind"`
+	Emoji        string `json:"emoji,omitempty"`
+
+	Counts      map[string]int `json:"counts"`      // ページへの種類毎の数
+	EmojiCounts map[string]int `json:"emojiCounts"` // ページへの絵文字毎の数
+
+	DocumentVersion int `json:"documentVersion"`
+}
+
+type ModeratorMsg struct {
+	MessageType      string `json:"messageType"`
+	MeetingId        int    `json:"meetingId"`
+	ModeratorMsgBody string `json:"moderatorMsgBody"`
+	IsStartPresen    bool   `json:"isStartPresen"`
+	QuestionId       int    `json:"questionId"`
+	QuestionUserId   string `json:"questionUserId"`
+	PresentOrder     int    `json:"presentOrder"` // only if `IsStartPresen == true`, else = -1
+}
+
+var questionCount = make(map[int]int)
+
+func loadJson(byteArray []byte) (interface{}, error) {
+	var jsonObj interface{}
+	err := json.Unmarshal(byteArray, &jsonObj)
+	return jsonObj, err
+}
+
+// readPump pumps messages from the websocket connection to the hub.
+//
+// The application runs readPump in a per-connection goroutine. The application
+// ensures that there is at most one reader on a connection by executing all
+// reads from this goroutine.
+func (c *Client) readPump() {
+	defer func() {
+		c.hub.unregister <- c
+		c.conn.Close()
+		fmt.Println("Warning: Web SocketをCloseしました in readPump")
+	}()
+	config := c.hub.config.WebSocket
+	c.conn.SetReadLimit(config.MaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(config.PongWait))
+	c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(config.PongWait)); return nil })
+	for {
+		_, message, err := c.conn.ReadMessage()
+
+		// エラー処理
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("Error: error: %v in readPump\n", err)
+			}
+			break
+		}
+
+		// websocketで受け取ったデータの処理
+		jsonObj, jsonerr := loadJson(message)
+		if jsonerr != nil {
+			fmt.Printf("Error: loadJsonでエラーが発生しました in readPump\n")
+			continue
+		}
+		fmt.Printf("Log: Receive: " + string(message) + " in readPump\n")
+		message_type := jsonObj.(map[string]interface{})["messageType"].(string)
+		messagesReceived.WithLabelValues(message_type).Inc()
+
+		if reason, retryAfter := c.throttle(jsonObj.(map[string]interface{}), message_type); reason != "" {
+			throttledEvents.WithLabelValues(message_type, reason).Inc()
+			fmt.Printf("Log: 流量制限によりメッセージを拒否しました: %s, %s in readPump\n", message_type, reason)
+			if reason == throttleMuted {
+				c.sendError(reason, "you are muted by the host", retryAfter)
+			} else {
+				c.sendError(reason, "too many "+message_type+" messages", retryAfter)
+			}
+			continue
+		}
+
+		var messagestruct interface{}
+
+		switch message_type {
+		case "message":
+			message_jsonobj := jsonObj.(map[string]interface{})["message"].(string)
+			messagestruct = Message{MessageType: "message", Message: message_jsonobj}
+		case "question":
+			var (
+				layout      = "2006/01/02 15:04:05"
+				location, _ = time.LoadLocation("Asia/Tokyo")
+			)
+
+			userId := jsonObj.(map[string]interface{})["userId"].(string)
+			meetingId := int(jsonObj.(map[string]interface{})["meetingId"].(float64))
+			questionBody := jsonObj.(map[string]interface{})["questionBody"].(string)
+			documentId := int(jsonObj.(map[string]interface{})["documentId"].(float64))
+			documentPage := c.documentPage(jsonObj.(map[string]interface{}), documentId)
+			questionTimeStr := jsonObj.(map[string]interface{})["questionTime"].(string)
+
+			// 省略した場合は資料の現在のバージョンへの質問になる
+			documentVersion := 0
+			if version, ok := jsonObj.(map[string]interface{})["documentVersion"].(float64); ok {
+				documentVersion = int(version)
+			}
+
+			// 完全匿名の会議では利用者IDの代わりに仮名を保存する
+			_, meeting := getDocumentMeeting(db, documentId)
+			askerId := meetingAskerId(meeting, userId)
+			if askerId == "" {
+				c.sendError("invalid_question", "failed to create the question", 0)
+				continue
+			}
+
+			questionTime, _ := time.ParseInLocation(layout, questionTimeStr, location)
+			question := Question{
+				UserId:       askerId,
+				QuestionBody: questionBody,
+				DocumentId:   documentId,
+				DocumentPage: documentPage,
+				VoteNum:      0,
+				QuestionTime: questionTime,
+				IsVoice:      false,
+
+				DocumentVersion: documentVersion,
+			}
+
+			isCreateQuestionOK, questionId, documentVersion, moderationStatus := createQuestion(db, question)
+
+			if !isCreateQuestionOK {
+				c.sendError("invalid_question", "failed to create the question", 0)
+				continue
+			}
+
+			question.QuestionId = questionId
+			question.DocumentVersion = documentVersion
+			question.ModerationStatus = moderationStatus
+			c.sendDuplicates(db, meetingId, question)
+			notifyQuestionQueues(c.hub, db, meetingId, documentId)
+
+			// 事前承認の会議では，承認されるまでホストと質問者にだけ見せる
+			if moderationStatus == questionPending {
+				pending := questionModerationResult(meetingId, questionPending, "", question)
+				sendToHosts(c.hub, db, meetingId, pending)
+				messagejson, _ := json.Marshal(pending)
+				c.hub.sendTo(c, messagejson)
+				continue
+			}
+
+			presenterId := getPresenterId(db, documentId)
+
+			messagestruct = questionResult(meetingId, presenterId, visibleAskerId(meeting.AnonymityLevel, false, question), question)
+		case "question_vote":
+			questionId := int(jsonObj.(map[string]interface{})["questionId"].(float64))
+			isVote := jsonObj.(map[string]interface{})["isVote"].(bool)
+
+			meetingId, questionId, voteNum := voteQuestion(db, questionId, isVote)
+			if meetingId == -1 {
+				c.sendError("invalid_vote", "failed to vote for the question", 0)
+				continue
+			}
+
+			messagestruct = QuestionVoteResult{
+				MessageType: message_type,
+				MeetingId:   meetingId,
+				QuestionId:  questionId,
+				VoteNum:     voteNum,
+			}
+		case "handsup":
+			userId := jsonObj.(map[string]interface{})["userId"].(string)
+			documentId := int(jsonObj.(map[string]interface{})["documentId"].(float64))
+			documentPage := c.documentPage(jsonObj.(map[string]interface{}), documentId)
+			isUp := jsonObj.(map[string]interface{})["isUp"].(bool)
+
+			var meetingId int
+
+			if isUp {
+				meetingId = handsUp(db, userId, documentId, documentPage)
+			} else {
+				meetingId = handsDown(db, userId, documentId, documentPage)
+			}
+			if meetingId == -1 {
+				c.sendError("invalid_handsup", "failed to update the raised hand", 0)
+				continue
+			}
+
+			messagestruct = HandsUpResult{
+				MessageType: message_type,
+				MeetingId:   meetingId,
+				UserId:      userId,
+				DocumentId:  documentId,
+				IsUp:        isUp,
+				Position:    handPosition(getHandsQueue(db, documentId), userId),
+			}
+			handsQueueBroadcast(c.hub, db, documentId)
+		case "reaction":
+			documentId := int(jsonObj.(map[string]interface{})["documentId"].(float64))
+			documentPage := int(jsonObj.(map[string]interface{})["documentPage"].(float64))
+			isReaction := jsonObj.(map[string]interface{})["isReaction"].(bool)
+			kind, _ := jsonObj.(map[string]interface{})["kind"].(string)
+			emoji, _ := jsonObj.(map[string]interface{})["emoji"].(string)
+			if kind == "" {
+				kind = reactionConfused
+			}
+			// 参加者毎のリアクションは接続時のuserIdで数える
+			userId := c.userId
+			if !validReaction(kind, emoji) {
+				c.sendError("invalid_reaction", "unknown reaction kind or emoji", 0)
+				continue
+			}
+			// 分からない以外は参加者毎に数える (userIdの無い接続の分からないは合計の数だけ増やす)
+			if userId == "" && kind != reactionConfused {
+				c.sendError("invalid_reaction", "connect with userId to send this reaction", 0)
+				continue
+			}
+
+			var (
+				meetingId       int
+				documentVersion int
+				changed         = true
+			)
+
+			if userId != "" {
+				if meetingId, documentVersion, changed = setPageReaction(db, userId, documentId, documentPage, kind, emoji, isReaction); meetingId == -1 {
+					c.sendError("invalid_reaction", "failed to update the reaction", 0)
+					continue
+				}
+				if !changed {
+					// 同じリアクションの付け直し・付いていないリアクションの取り消し
+					continue
+				}
+			}
+			if kind == reactionConfused {
+				if meetingId, _, documentVersion = voteReaction(db, documentId, documentPage, isReaction); meetingId == -1 {
+					c.sendError("invalid_reaction", "failed to update the reaction", 0)
+					continue
+				}
+			}
+
+			counts, emojiCounts := pageReactionSummary(db, documentId, documentVersion, documentPage)
+			reactionNum := counts[kind]
+			if kind == reactionEmoji {
+				reactionNum = emojiCounts[emoji]
+			}
+			messagestruct = ReactionResult{
+				MessageType:  message_type,
+				MeetingId:    meetingId,
+				DocumentId:   documentId,
+				DocumentPage: documentPage,
+				ReactionNum:  reactionNum,
+				Kind:         kind,
+				Emoji:        emoji,
+				Counts:       counts,
+				EmojiCounts:  emojiCounts,
+
+				DocumentVersion: documentVersion,
+			}
+			if isReaction && (kind == reactionTooFast || kind == reactionTooSlow) {
+				suggestPace(c.hub, db, documentId, kind)
+			}
+			if isReaction && kind == reactionConfused {
+				notifyConfused(c.hub, db, meetingId, documentId, documentPage, counts[reactionConfused])
+			}
+		case QuestionReplyMsgType:
+			id, okQuestion := jsonObj.(map[string]interface{})["questionId"].(float64)
+			body, okBody := jsonObj.(map[string]interface{})["body"].(string)
+			if !okQuestion || !okBody {
+				c.sendError("invalid_message", "questionId and body are required", 0)
+				continue
+			}
+			questionId := int(id)
+			userId := c.userId
+			if userId == "" {
+				userId, _ = jsonObj.(map[string]interface{})["userId"].(string)
+			}
+			isAnswer, _ := jsonObj.(map[string]interface{})["isAnswer"].(bool)
+			parentReplyId := 0
+			if id, ok := jsonObj.(map[string]interface{})["parentReplyId"].(float64); ok {
+				parentReplyId = int(id)
+			}
+
+			found, question := getQuestion(db, questionId)
+			if !found || strings.TrimSpace(body) == "" {
+				c.sendError("invalid_reply", "failed to reply to the question", 0)
+				continue
+			}
+			found, document := getDocument(db, question.DocumentId)
+			if !found {
+				c.sendError("invalid_reply", "failed to reply to the question", 0)
+				continue
+			}
+			if !isMeetingMember(db, document.MeetingId, userId) {
+				fmt.Printf("Error: 会議の参加者以外は返信できません: %d, %s in readPump\n", document.MeetingId, userId)
+				c.sendError("forbidden", "only participants of the meeting can reply", 0)
+				continue
+			}
+			// 承認待ち・却下された質問を会議に漏らさない
+			if question.ModerationStatus != questionApproved {
+				c.sendError("invalid_reply", "the question is not published", 0)
+				continue
+			}
+
+			result := QuestionReplyResult{
+				MessageType:   message_type,
+				MeetingId:     document.MeetingId,
+				QuestionId:    questionId,
+				IsAnswer:      isAnswer,
+				ParentReplyId: parentReplyId,
+				UserId:        meetingParticipantId(db, question.DocumentId, userId),
+				Body:          body,
+			}
+			if isAnswer {
+				// 回答は発表者とホストのみ
+				if userId != document.UserId && !isHost(db, document.MeetingId, userId) {
+					fmt.Printf("Error: 発表者とホスト以外は回答できません: %d, %s in readPump\n", questionId, userId)
+					c.sendError("forbidden", "only the presenter or the host can answer", 0)
+					continue
+				}
+				isCreateAnswerOK, answer := createAnswer(db, questionId, meetingParticipantId(db, question.DocumentId, userId), body)
+				if !isCreateAnswerOK {
+					c.sendError("invalid_reply", "failed to answer the question", 0)
+					continue
+				}
+				result.AnswerId = answer.AnswerId
+				result.ParentReplyId = 0
+				result.RepliedAt = answer.AnsweredAt.Format("2006/01/02 15:04:05")
+			} else {
+				isCreateReplyOK, reply := createReply(db, Reply{QuestionId: questionId, ParentReplyId: parentReplyId, UserId: meetingParticipantId(db, question.DocumentId, userId), ReplyBody: body})
+				if !isCreateReplyOK {
+					c.sendError("invalid_reply", "failed to reply to the question", 0)
+					continue
+				}
+				result.ReplyId = reply.ReplyId
+				result.RepliedAt = reply.RepliedAt.Format("2006/01/02 15:04:05")
+			}
+
+			messagejson, _ := json.Marshal(result)
+			c.hub.sendToMeeting(document.MeetingId, messagejson, false)
+			continue
+		case QuestionModerateMsgType:
+			questionId, okQuestion := jsonObj.(map[string]interface{})["questionId"].(float64)
+			action, okAction := jsonObj.(map[string]interface{})["action"].(string)
+			if !okQuestion || !okAction {
+				c.sendError("invalid_message", "questionId and action are required", 0)
+				continue
+			}
+			// ホストの操作は接続時のuserIdで認可する
+			request := QuestionModerateRequest{
+				HostId: c.userId,
+				Action: action,
+			}
+			request.QuestionBody, _ = jsonObj.(map[string]interface{})["questionBody"].(string)
+			if mergeInto, ok := jsonObj.(map[string]interface{})["mergeInto"].(float64); ok {
+				request.MergeInto = int(mergeInto)
+			}
+
+			if _, code := moderateQuestion(c.hub, db, request.HostId, int(questionId), request); code != "" {
+				c.sendError(code, "failed to moderate the question", 0)
+			}
+			continue
+		case QuestionMergeMsgType:
+			questionId, okQuestion := jsonObj.(map[string]interface{})["questionId"].(float64)
+			mergeInto, okMerge := jsonObj.(map[string]interface{})["mergeInto"].(float64)
+			if !okQuestion || !okMerge {
+				c.sendError("invalid_message", "questionId and mergeInto are required", 0)
+				continue
+			}
+			// 質問者は接続時のuserIdで確かめる
+			if c.userId == "" {
+				c.sendError("forbidden", "connect with userId to merge the question", 0)
+				continue
+			}
+
+			if _, code := mergeOwnQuestion(c.hub, db, c.userId, int(questionId), int(mergeInto)); code != "" {
+				c.sendError(code, "failed to merge the question", 0)
+			}
+			continue
+		case QuestionEditMsgType:
+			questionId, okQuestion := jsonObj.(map[string]interface{})["questionId"].(float64)
+			questionBody, okBody := jsonObj.(map[string]interface{})["questionBody"].(string)
+			if !okQuestion || !okBody {
+				c.sendError("invalid_message", "questionId and questionBody are required", 0)
+				continue
+			}
+			// 質問者は接続時のuserIdで確かめる
+			if c.userId == "" {
+				c.sendError("forbidden", "connect with userId to edit the question", 0)
+				continue
+			}
+
+			if _, code := editOwnQuestion(c.hub, db, c.userId, int(questionId), questionBody); code != "" {
+				c.sendError(code, "failed to edit the question", 0)
+			}
+			continue
+		case QuestionWithdrawMsgType:
+			questionId, ok := jsonObj.(map[string]interface{})["questionId"].(float64)
+			if !ok {
+				c.sendError("invalid_message", "questionId is required", 0)
+				continue
+			}
+			if c.userId == "" {
+				c.sendError("forbidden", "connect with userId to withdraw the question", 0)
+				continue
+			}
+
+			if _, code := withdrawQuestion(c.hub, db, c.userId, int(questionId)); code != "" {
+				c.sendError(code, "failed to withdraw the question", 0)
+			}
+			continue
+		case HandsModerateMsgType:
+			documentId, okDocument := jsonObj.(map[string]interface{})["documentId"].(float64)
+			userId, okUser := jsonObj.(map[string]interface{})["userId"].(string)
+			action, okAction := jsonObj.(map[string]interface{})["action"].(string)
+			if !okDocument || !okUser || !okAction {
+				c.sendError("invalid_message", "documentId, userId and action are required", 0)
+				continue
+			}
+			request := HandsModerateRequest{
+				HostId: c.userId,
+				UserId: userId,
+				Action: action,
+			}
+			if position, ok := jsonObj.(map[string]interface{})["position"].(float64); ok {
+				request.Position = int(position)
+			}
+
+			if _, code := moderateHand(c.hub, db, int(documentId), request); code != "" {
+				c.sendError(code, "failed to moderate the raised hand", 0)
+			}
+			continue
+		case "mute":
+			meetingId := int(jsonObj.(map[string]interface{})["meetingId"].(float64))
+			userId := jsonObj.(map[string]interface{})["userId"].(string)
+			isMute := jsonObj.(map[string]interface{})["isMute"].(bool)
+
+			if !isHost(db, meetingId, c.userId) {
+				fmt.Printf("Error: ホスト以外はミュートできません: %d, %s in readPump\n", meetingId, c.userId)
+				c.sendError("forbidden", "only the host can mute users", 0)
+				continue
+			}
+
+			muteUntil := ""
+			if isMute {
+				duration := c.hub.config.RateLimit.DefaultMuteDuration
+				if seconds, ok := jsonObj.(map[string]interface{})["duration"].(float64); ok && seconds > 0 {
+					duration = time.Duration(seconds) * time.Second
+				}
+				location, _ := time.LoadLocation("Asia/Tokyo")
+				muteUntil = c.hub.throttle.mute(meetingId, userId, duration).In(location).Format("2006/01/02 15:04:05")
+			} else {
+				c.hub.throttle.unmute(meetingId, userId)
+			}
+
+			messagestruct = MuteResult{
+				MessageType: message_type,
+				MeetingId:   meetingId,
+				UserId:      userId,
+				IsMute:      isMute,
+				MuteUntil:   muteUntil,
+			}
+		case PageChangeMsgType:
+			meetingId, okMeeting := jsonObj.(map[string]interface{})["meetingId"].(float64)
+			documentId, okDocument := jsonObj.(map[string]interface{})["documentId"].(float64)
+			documentPage, okPage := jsonObj.(map[string]interface{})["documentPage"].(float64)
+			if !okMeeting || !okDocument || !okPage {
+				c.sendError("invalid_message", "meetingId, documentId and documentPage are required", 0)
+				continue
+			}
+			// 発表者は接続時のuserIdで確かめる
+			presenterId := c.userId
+			if presenterId == "" {
+				c.sendError("forbidden", "connect with userId to change the page", 0)
+				continue
+			}
+
+			isChangeOK, documentVersion, changedAt := changePage(db, int(meetingId), presenterId, int(documentId), int(documentPage))
+			if !isChangeOK {
+				c.sendError("invalid_page_change", "only the current presenter can change to an existing page", 0)
+				continue
+			}
+
+			messagejson, _ := json.Marshal(PageChangeResult{
+				MessageType:     message_type,
+				MeetingId:       int(meetingId),
+				PresenterId:     presenterId,
+				DocumentId:      int(documentId),
+				DocumentPage:    int(documentPage),
+				DocumentVersion: documentVersion,
+				ChangedAt:       changedAt.Format("2006/01/02 15:04:05"),
+			})
+			// 追従しない参加者には送らない
+			c.hub.sendToMeeting(int(meetingId), messagejson, true)
+			continue
+		case "follow":
+			// 発表者のページへの追従の切り替え (この接続のみ)
+			follow := jsonObj.(map[string]interface{})["follow"].(bool)
+			c.setFollowing(follow)
+			fmt.Printf("Log: ページの追従を切り替えました: %s, %t in readPump\n", c.userId, follow)
+			continue
+		case "finishword":
+			meetingId := int(jsonObj.(map[string]interface{})["meetingId"].(float64))
+			presenterId := jsonObj.(map[string]interface{})["presenterId"].(string)
+			finishType := jsonObj.(map[string]interface{})["finishType"].(string)
+
+			var (
+				moderatorMsgBody string
+				questionId       int
+				questionUserId   string
+				isStartPresen    = false
+				nextOrder        = -1
+			)
+			// 規定の質問数に達した場合
+			if questionCount[meetingId] >= c.hub.config.Moderator.MaxQuestionNum {
+				var (
+					endPresen  bool
+					nextUserId string
+				)
+				endPresen, nextUserId, nextOrder = getNextPresenterId(db, meetingId, presenterId)
+				// 発表者が交代する(会議が終わる)ため，この発表者への挙手を下げる
+				lowerPresenterHands(c.hub, db, meetingId, presenterId)
+				endPresentation(db, meetingId)
+				if !endPresen {
+					// 次の発表者がページを切り替えるまで前の発表者の表示期間が続かないようにする
+					endPageView(db, meetingId)
+					moderatorMsgBody = personEnd(presenterId, nextUserId, meetingId)
+					isStartPresen = true
+					questionId = -1
+					questionUserId = ""
+					countModeratorTransition(meetingId, transitionNextPresen)
+				} else {
+					moderatorMsgBody = meetingEnd()
+					endPageView(db, meetingId)
+					setMeetingEnd(db, meetingId)
+					countModeratorTransition(meetingId, transitionMeetingEnd)
+					questionId = -1
+					questionUserId = ""
+				}
+				questionCount[meetingId] = 0
+			} else {
+				switch finishType {
+				case "present":
+					moderatorMsgBody, questionUserId, questionId = presenOrQuestionEnd(db, meetingId, presenterId, true, "")
+				case "question":
+					questionUserId = jsonObj.(map[string]interface{})["questionUserId"].(string)
+					moderatorMsgBody, questionUserId, questionId = presenOrQuestionEnd(db, meetingId, presenterId, false, questionUserId)
+				default:
+					fmt.Printf("Error: 予期せぬfinishType: %s in readPump\n", finishType)
+					continue
+				}
+				countModeratorTransition(meetingId, transitionQuestion)
+				// 挙手を当てた場合は順番が進む
+				handsQueueBroadcast(c.hub, db, getDocumentId(db, presenterId, meetingId))
+				if questionCount[meetingId] == 0 {
+					questionCount[meetingId] = 1
+				} else {
+					questionCount[meetingId] += 1
+				}
+				fmt.Printf("Log: 現在の質問数：%d in readPump\n", questionCount[meetingId])
+			}
+
+			messagestruct = ModeratorMsg{
+				MessageType:      ModeratorMsgType,
+				MeetingId:        meetingId,
+				ModeratorMsgBody: moderatorMsgBody,
+				IsStartPresen:    isStartPresen,
+				QuestionId:       questionId,
+				QuestionUserId:   questionUserId,
+				PresentOrder:     nextOrder,
+			}
+			saveModeratorMessage(db, meetingId, moderatorMsgBody)
+		default:
+			continue
+		}
+		messagejson, _ := json.Marshal(messagestruct)
+
+		// 自分のメッセージをhubのbroadcastチャネルに送り込む
+		fmt.Printf("Log: Send: %+v in readPump\n", messagestruct)
+		c.hub.broadcast <- messagejson
+	}
+}
+
+// throttle はメッセージの送信者が流量制限・ミュートに掛かっていないかを確認する
+func (c *Client) throttle(obj map[string]interface{}, messageType string) (string, time.Duration) {
+	userId, meetingId := c.sender(obj, messageMeetingId)
+	senderKey := "user:" + userId
+	if userId == "" {
+		senderKey = "ip:" + c.ip
+	}
+	return c.hub.throttle.check(meetingId, senderKey, userId, messageType)
+}
+
+// sender はメッセージの送信者と会議を返す
+// 接続時にuserId, meetingIdが指定されていればそれを使い，無ければメッセージから求める
+// userIdを付けないメッセージ(投票など)は，この接続で以前に名乗った利用者が送ったものとする
+func (c *Client) sender(obj map[string]interface{}, meetingOf func(map[string]interface{}) int) (string, int) {
+	userId := c.userId
+	if userId == "" {
+		if id, ok := obj["userId"].(string); ok && id != "" {
+			c.claimedUserId = id
+		}
+		userId = c.claimedUserId
+	}
+	meetingId := c.meetingId
+	if meetingId == 0 {
+		meetingId = meetingOf(obj)
+	}
+	return userId, meetingId
+}
+
+// messageMeetingId はメッセージのmeetingId，documentId，questionIdの順に会議を求める (分からなければ0)
+func messageMeetingId(obj map[string]interface{}) int {
+	if id, ok := obj["meetingId"].(float64); ok && id > 0 {
+		return int(id)
+	}
+	documentId := 0
+	if id, ok := obj["documentId"].(float64); ok {
+		documentId = int(id)
+	} else if id, ok := obj["questionId"].(float64); ok {
+		if found, question := getQuestion(db, int(id)); found {
+			documentId = question.DocumentId
+		}
+	}
+	if documentId == 0 {
+		return 0
+	}
+	if found, document := getDocument(db, documentId); found {
+		return document.MeetingId
+	}
+	return 0
+}
+
+// documentPage はメッセージのページ番号を返す
+// 省略された場合は発表者が表示しているページとする
+func (c *Client) documentPage(obj map[string]interface{}, documentId int) int {
+	if page, ok := obj["documentPage"].(float64); ok && page != 0 {
+		return int(page)
+	}
+	return getCurrentPage(db, documentId)
+}
+
+// sendError はこのクライアントにだけエラーを通知する
+func (c *Client) sendError(code string, message string, retryAfter time.Duration) {
+	messagejson, _ := json.Marshal(ErrorResult{
+		MessageType: ErrorMsgType,
+		Code:        code,
+		Message:     message,
+		RetryAfter:  int(math.Ceil(retryAfter.Seconds())),
+	})
+	c.hub.sendTo(c, messagejson)
+}
+
+func (hub *Hub) sendStartMeetingMessage(meetingId int, startTime time.Time) {
+	location, _ := time.LoadLocation("Asia/Tokyo")
+
+	reservedMu.Lock()
+	reserved := isReserved[meetingId]
+	isReserved[meetingId] = true
+	reservedMu.Unlock()
+
+	if !reserved {
+		fmt.Printf("Log: 開始通知を予約しました: %s in sendStartMeetingMessage\n", startTime.In(location))
+		select {
+		case <-time.After(time.Until(startTime.In(location))):
+		case <-hub.quit:
+			fmt.Printf("Log: シャットダウンにより開始通知を取り消しました: %d in sendStartMeetingMessage\n", meetingId)
+			return
+		}
+		message := ModeratorMsg{
+			MessageType:      ModeratorMsgType,
+			MeetingId:        meetingId,
+			ModeratorMsgBody: meetingStart(meetingId),
+			IsStartPresen:    true,
+			QuestionId:       -1,
+			QuestionUserId:   "",
+			PresentOrder:     0,
+		}
+		messagejson, _ := json.Marshal(message)
+		hub.broadcast <- messagejson
+		saveModeratorMessage(db, meetingId, message.ModeratorMsgBody)
+		countModeratorTransition(meetingId, transitionMeetingStart)
+		fmt.Printf("Log: 開始通知を送信しました: %s in sendStartMeetingMessage\n", time.Now().In(location))
+		setMeetingDone(db, meetingId)
+	} else {
+		fmt.Printf("Log: 開始通知は既に予約済です: %s in sendStartMeetingMessage\n", startTime.In(location))
+	}
+}
+
+func (hub *Hub) sendDocumentUpdate(meetingId int, documentId int, documentVersion int) {
+	messagestruct := DocumentUpdateResult{
+		MessageType:     "document_update",
+		MeetingId:       meetingId,
+		DocumentId:      documentId,
+		DocumentVersion: documentVersion,
+	}
+	messagejson, _ := json.Marshal(messagestruct)
+	hub.broadcast <- messagejson
+	fmt.Printf("Log: 資料更新通知を送信しました:%d, %d, %d in sendDocumentUpdate\n", meetingId, documentId, documentVersion)
+}
+
+// writePump pumps messages from the hub to the websocket connection.
+//
+// A goroutine running writePump is started for each connection. The
+// application ensures that there is at most one writer to a connection by
+// executing all writes from this goroutine.
+func (c *Client) writePump() {
+	config := c.hub.config.WebSocket
+	ticker := time.NewTicker(config.PingPeriod())
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+		c.hub.writers.Done()
+		fmt.Println("Warning: Web SocketをCloseしました in writePump")
+	}()
+	for {
+		select {
+		case message, ok := <-c.send:
+			// タイムアウト時間の設定
+			c.conn.SetWriteDeadline(time.Now().Add(config.WriteWait))
+			// エラー処理
+			if !ok {
+				// The hub closed the channel.
+				if c.hub.closing() {
+					c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown"))
+				} else {
+					c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				}
+				return
+			}
+
+			w, err := c.conn.NextWriter(websocket.TextMessage)
+			if err != nil {
+				return
+			}
+			w.Write(message)
+
+			// Add queued chat messages to the current websocket message.
+			n := len(c.send)
+			for i := 0; i < n; i++ {
+				w.Write(newline)
+				w.Write(<-c.send)
+			}
+
+			if err := w.Close(); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(config.WriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// serveWs handles websocket requests from the peer.
+// userId, meetingId はクエリパラメータで任意に指定する (/ws?userId=...&meetingId=...)
+// follow=false を指定すると発表者のページ切り替えを受け取らない
+func serveWs(hub *Hub, w http.ResponseWriter, r *http.Request, ip string) {
+	conn, err := hub.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		fmt.Printf("Error: Web SocketへのUpgradeに失敗しました in serveWs\n")
+		log.Println(err)
+		return
+	} else {
+		fmt.Printf("Log: Web SocketへのUpgradeに成功しました in serveWs\n")
+	}
+	// sendは他の人からのメッセージが投入される
+	client := &Client{hub: hub, conn: conn, send: make(chan []byte, hub.config.WebSocket.SendBufferSize), ip: ip}
+	client.userId = r.URL.Query().Get("userId")
+	client.meetingId, _ = strconv.Atoi(r.URL.Query().Get("meetingId"))
+	client.setFollowing(r.URL.Query().Get("follow") != "false")
+
+	if code, reason := hub.limiter.acquire(client); code != 0 {
+		fmt.Printf("Log: 接続数の上限により接続を拒否しました: %s, %s, %d, %s in serveWs\n", client.ip, client.userId, client.meetingId, reason)
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(hub.config.WebSocket.WriteWait))
+		conn.Close()
+		return
+	}
+
+	hub.writers.Add(1)
+	client.hub.register <- client // hubのregisterチャネルに自分のClientを登録
+
+	// Allow collection of memory referenced by the caller by doing all work in
+	// new goroutines.
+	go client.writePump()
+	go client.readPump()
+}
@@ -0,0 +1,146 @@
+// Package client はREST APIとWebSocketのクライアント
+// 型と操作毎のメソッドは zz_generated.go に生成する (go generate で更新する)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client はREST APIのクライアント
+type Client struct {
+	BaseURL    string // http://localhost:8080 など
+	HTTPClient *http.Client
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+// HTTPError は2xx以外の応答
+// v2 APIではCode，Message，Detailsに APIError の内容が入る
+type HTTPError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Details    map[string]interface{}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func (c *Client) newRequest(ctx context.Context, method string, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := c.BaseURL + path
+	if len(query) != 0 {
+		u += "?" + query.Encode()
+	}
+	return http.NewRequestWithContext(ctx, method, u, body)
+}
+
+// send はリクエストを送り，2xx以外の場合は本文をresultに読んだ上でHTTPErrorを返す
+// (v1 APIは失敗しても result: false の本文を返すため)
+func (c *Client) send(req *http.Request, result interface{}) error {
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		if result == nil || len(body) == 0 {
+			return nil
+		}
+		return json.Unmarshal(body, result)
+	}
+	httpErr := &HTTPError{StatusCode: res.StatusCode}
+	var apiErr APIError
+	if json.Unmarshal(body, &apiErr) == nil {
+		httpErr.Code, httpErr.Message, httpErr.Details = apiErr.Code, apiErr.Message, apiErr.Details
+	}
+	if result != nil {
+		json.Unmarshal(body, result)
+	}
+	return httpErr
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, request interface{}, result interface{}) error {
+	var body io.Reader
+	if request != nil {
+		b, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	if request != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, result)
+}
+
+// doRaw はJSON以外の応答(議事録やPDFなど)の本文をそのまま返す
+func (c *Client) doRaw(ctx context.Context, method string, path string, query url.Values) ([]byte, error) {
+	req, err := c.newRequest(ctx, method, path, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, &HTTPError{StatusCode: res.StatusCode}
+	}
+	return body, nil
+}
+
+// upload はファイルを multipart/form-data で送る
+func (c *Client) upload(ctx context.Context, path string, userId string, fileName string, file io.Reader, result interface{}) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("userId", userId); err != nil {
+		return err
+	}
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, path, nil, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return c.send(req, result)
+}
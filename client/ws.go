@@ -0,0 +1,99 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Conn はWebSocketの接続
+// 受信は Run，送信は Send や SendQuestion などで行う (送信は複数のゴルーチンから呼べる)
+type Conn struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+// Dial はbaseURL(http://localhost:8080 など)の /ws に接続する
+// follow が false の場合は発表者のページの切り替えが届かない
+func Dial(ctx context.Context, baseURL string, userId string, meetingId int, follow bool) (*Conn, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/") + "/ws")
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+	query := url.Values{}
+	if userId != "" {
+		query.Set("userId", userId)
+	}
+	if meetingId != 0 {
+		query.Set("meetingId", strconv.Itoa(meetingId))
+	}
+	if !follow {
+		query.Set("follow", "false")
+	}
+	u.RawQuery = query.Encode()
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{conn: conn}, nil
+}
+
+// Send はmessageをJSONにして送る (messageType は呼び出し側で設定する)
+func (c *Conn) Send(message interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(message)
+}
+
+// Run は接続が閉じるまでメッセージを受信し，messageType毎にhandlersを呼ぶ
+// サーバーは1つのフレームに改行区切りで複数のメッセージをまとめて送ることがある
+func (c *Conn) Run(handlers *Handlers) error {
+	for {
+		_, frame, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return nil
+			}
+			return err
+		}
+		decoder := json.NewDecoder(bytes.NewReader(frame))
+		for {
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			var header struct {
+				MessageType string `json:"messageType"`
+			}
+			if err := json.Unmarshal(raw, &header); err != nil {
+				return err
+			}
+			if err := handlers.dispatch(header.MessageType, raw); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *Conn) Close() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	return c.conn.Close()
+}
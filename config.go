@@ -0,0 +1,523 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config はサーバー全体の設定
+// 優先順位: 環境変数(.envを含む) > 設定ファイル(YAML) > デフォルト値
+type Config struct {
+	Port string `yaml:"port"`
+
+	// CORSとWebSocketのUpgradeで共通に使う許可Originの一覧 ("*" は全て許可，空の場合は同一オリジンのみ)
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+
+	// 完全匿名の会議の仮名を作る秘密 (DBには保存しない．未設定の場合は起動毎に作る)
+	AnonymitySecret string `yaml:"anonymitySecret"`
+
+	DB        DBConfig        `yaml:"db"`
+	WebSocket WebSocketConfig `yaml:"websocket"`
+	Moderator ModeratorConfig `yaml:"moderator"`
+	Shutdown  ShutdownConfig  `yaml:"shutdown"`
+	RateLimit RateLimitConfig `yaml:"rateLimit"`
+	Storage   StorageConfig   `yaml:"storage"`
+}
+
+type DBConfig struct {
+	DBMS     string `yaml:"dbms"`
+	User     string `yaml:"user"`
+	Pass     string `yaml:"pass"`
+	Protocol string `yaml:"protocol"`
+	Name     string `yaml:"name"`
+	TLS      bool   `yaml:"tls"`
+}
+
+type WebSocketConfig struct {
+	// Time allowed to write a message to the peer.
+	WriteWait time.Duration `yaml:"writeWait"`
+
+	// Time allowed to read the next pong message from the peer.
+	PongWait time.Duration `yaml:"pongWait"`
+
+	// Maximum message size allowed from peer.
+	MaxMessageSize int64 `yaml:"maxMessageSize"`
+
+	// Buffer size of each client's send channel.
+	SendBufferSize int `yaml:"sendBufferSize"`
+
+	// 同時接続数の上限 (0 は無制限)
+	// userIdは自己申告のため，userIdを変えた接続もMaxConnsPerIPで制限する
+	MaxConnsPerIP        int `yaml:"maxConnsPerIP"`
+	MaxConnsPerUser      int `yaml:"maxConnsPerUser"`
+	MaxClientsPerMeeting int `yaml:"maxClientsPerMeeting"`
+}
+
+// Send pings to peer with this period. Must be less than pongWait.
+func (c WebSocketConfig) PingPeriod() time.Duration {
+	return (c.PongWait * 9) / 10
+}
+
+type ModeratorConfig struct {
+	// 発表者一人あたりの質問数
+	MaxQuestionNum int `yaml:"maxQuestionNum"`
+
+	// 質問を重複の候補とみなす類似度 (0〜1，0 は検出しない)
+	DuplicateThreshold float64 `yaml:"duplicateThreshold"`
+
+	// 「速すぎる」「遅すぎる」のリアクションを数える期間
+	PaceWindow time.Duration `yaml:"paceWindow"`
+
+	// 発表者にペースを提案する，期間内にリアクションした参加者の割合 (0〜1，0 は提案しない)
+	PaceThreshold float64 `yaml:"paceThreshold"`
+
+	// 同じ提案を再び送るまでの間隔
+	PaceCooldown time.Duration `yaml:"paceCooldown"`
+
+	// 発表者とホストに通知する，ページを分からないと感じている人数 (0 は通知しない)
+	NotifyConfusedNum int `yaml:"notifyConfusedNum"`
+
+	// 承認待ち(ホスト)・未回答(発表者)の質問がこの件数以上になったら通知する (0 は通知しない)
+	NotifyQuestionNum int `yaml:"notifyQuestionNum"`
+
+	// 発表者一人あたりの時間 (0 は時間を計らない)
+	PresentationTime time.Duration `yaml:"presentationTime"`
+
+	// 発表の残り時間を通知する時間 (発表時間を計る場合のみ)
+	TimeWarning time.Duration `yaml:"timeWarning"`
+
+	// 同じ通知を再び送るまでの間隔
+	NotifyCooldown time.Duration `yaml:"notifyCooldown"`
+}
+
+type ShutdownConfig struct {
+	// 停止処理全体のタイムアウト
+	Timeout time.Duration `yaml:"timeout"`
+
+	// 終了通知で再接続までに待つよう伝える秒数
+	ReconnectAfter int `yaml:"reconnectAfter"`
+}
+
+type RateLimitConfig struct {
+	// messageType毎の流量制限 (ユーザー・会議毎)
+	Messages map[string]BucketConfig `yaml:"messages"`
+
+	// /user/login の流量制限 (IPアドレス毎，ユーザーID毎)
+	Login BucketConfig `yaml:"login"`
+
+	// ホストがミュートした時に時間の指定が無い場合のミュート時間
+	DefaultMuteDuration time.Duration `yaml:"defaultMuteDuration"`
+}
+
+// BucketConfig はトークンバケットの設定 (Rateが0なら無制限)
+type BucketConfig struct {
+	// 1秒あたりに補充されるトークン数
+	Rate float64 `yaml:"rate"`
+
+	// 溜められるトークンの最大数
+	Burst int `yaml:"burst"`
+}
+
+type StorageConfig struct {
+	// "local" もしくは "s3"
+	Driver string `yaml:"driver"`
+
+	// Driver が "local" の場合の保存先ディレクトリ
+	LocalDir string `yaml:"localDir"`
+
+	// Driver が "s3" の場合の接続先 (MinIOなどS3互換のもの)
+	S3 S3Config `yaml:"s3"`
+
+	// アップロードできるファイルの最大サイズ(バイト)
+	MaxUploadSize int64 `yaml:"maxUploadSize"`
+}
+
+type S3Config struct {
+	Endpoint  string `yaml:"endpoint"`
+	AccessKey string `yaml:"accessKey"`
+	SecretKey string `yaml:"secretKey"`
+	Bucket    string `yaml:"bucket"`
+	Region    string `yaml:"region"`
+	UseSSL    bool   `yaml:"useSSL"`
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Port:           "8080",
+		AllowedOrigins: []string{},
+		DB: DBConfig{
+			TLS: true,
+		},
+		WebSocket: WebSocketConfig{
+			WriteWait:            10 * time.Second,
+			PongWait:             60 * time.Second,
+			MaxMessageSize:       512,
+			SendBufferSize:       256,
+			MaxConnsPerIP:        20,
+			MaxConnsPerUser:      3,
+			MaxClientsPerMeeting: 200,
+		},
+		Moderator: ModeratorConfig{
+			MaxQuestionNum:     5,
+			DuplicateThreshold: 0.4,
+			PaceWindow:         time.Minute,
+			PaceThreshold:      0.3,
+			PaceCooldown:       2 * time.Minute,
+			NotifyConfusedNum:  3,
+			NotifyQuestionNum:  5,
+			TimeWarning:        2 * time.Minute,
+			NotifyCooldown:     time.Minute,
+		},
+		Shutdown: ShutdownConfig{
+			Timeout:        10 * time.Second,
+			ReconnectAfter: 5,
+		},
+		RateLimit: RateLimitConfig{
+			Messages: map[string]BucketConfig{
+				"question":          {Rate: 0.2, Burst: 3},
+				"question_vote":     {Rate: 2, Burst: 10},
+				"reaction":          {Rate: 1, Burst: 5},
+				"handsup":           {Rate: 0.5, Burst: 3},
+				"question_reply":    {Rate: 0.5, Burst: 5},
+				"question_edit":     {Rate: 0.2, Burst: 3},
+				"question_withdraw": {Rate: 0.2, Burst: 3},
+			},
+			Login:               BucketConfig{Rate: 0.2, Burst: 5},
+			DefaultMuteDuration: 5 * time.Minute,
+		},
+		Storage: StorageConfig{
+			Driver:        "local",
+			LocalDir:      "./uploads",
+			MaxUploadSize: 50 << 20,
+			S3: S3Config{
+				UseSSL: true,
+			},
+		},
+	}
+}
+
+// loadConfig はデフォルト値に設定ファイル，環境変数の順で上書きして検証する
+// path が空なら設定ファイルは読まない
+func loadConfig(path string) (*Config, error) {
+	config := defaultConfig()
+
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("設定ファイルを読み込めません: %w", err)
+		}
+		if err := yaml.UnmarshalStrict(data, config); err != nil {
+			return nil, fmt.Errorf("設定ファイルの形式が不正です: %w", err)
+		}
+	}
+
+	if err := config.loadEnv(); err != nil {
+		return nil, err
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *Config) loadEnv() error {
+	envString("PORT", &c.Port)
+	envString("DBMS", &c.DB.DBMS)
+	envString("DBUSER", &c.DB.User)
+	envString("DBPASS", &c.DB.Pass)
+	envString("DBPROTOCOL", &c.DB.Protocol)
+	envString("DBNAME", &c.DB.Name)
+	envList("ALLOWED_ORIGINS", &c.AllowedOrigins)
+	envString("ANONYMITY_SECRET", &c.AnonymitySecret)
+	envString("STORAGE_DRIVER", &c.Storage.Driver)
+	envString("STORAGE_LOCAL_DIR", &c.Storage.LocalDir)
+	envString("S3_ENDPOINT", &c.Storage.S3.Endpoint)
+	envString("S3_ACCESS_KEY", &c.Storage.S3.AccessKey)
+	envString("S3_SECRET_KEY", &c.Storage.S3.SecretKey)
+	envString("S3_BUCKET", &c.Storage.S3.Bucket)
+	envString("S3_REGION", &c.Storage.S3.Region)
+
+	for _, err := range []error{
+		envBool("DBTLS", &c.DB.TLS),
+		envDuration("WS_WRITE_WAIT", &c.WebSocket.WriteWait),
+		envDuration("WS_PONG_WAIT", &c.WebSocket.PongWait),
+		envInt64("WS_MAX_MESSAGE_SIZE", &c.WebSocket.MaxMessageSize),
+		envInt("WS_SEND_BUFFER_SIZE", &c.WebSocket.SendBufferSize),
+		envInt("WS_MAX_CONNS_PER_IP", &c.WebSocket.MaxConnsPerIP),
+		envInt("WS_MAX_CONNS_PER_USER", &c.WebSocket.MaxConnsPerUser),
+		envInt("WS_MAX_CLIENTS_PER_MEETING", &c.WebSocket.MaxClientsPerMeeting),
+		envInt("MAX_QUESTION_NUM", &c.Moderator.MaxQuestionNum),
+		envFloat64("DUPLICATE_THRESHOLD", &c.Moderator.DuplicateThreshold),
+		envDuration("PACE_WINDOW", &c.Moderator.PaceWindow),
+		envFloat64("PACE_THRESHOLD", &c.Moderator.PaceThreshold),
+		envDuration("PACE_COOLDOWN", &c.Moderator.PaceCooldown),
+		envInt("NOTIFY_CONFUSED_NUM", &c.Moderator.NotifyConfusedNum),
+		envInt("NOTIFY_QUESTION_NUM", &c.Moderator.NotifyQuestionNum),
+		envDuration("PRESENTATION_TIME", &c.Moderator.PresentationTime),
+		envDuration("TIME_WARNING", &c.Moderator.TimeWarning),
+		envDuration("NOTIFY_COOLDOWN", &c.Moderator.NotifyCooldown),
+		envDuration("SHUTDOWN_TIMEOUT", &c.Shutdown.Timeout),
+		envInt("SHUTDOWN_RECONNECT_AFTER", &c.Shutdown.ReconnectAfter),
+		envBucket("RATE_LIMIT_LOGIN", &c.RateLimit.Login),
+		envDuration("DEFAULT_MUTE_DURATION", &c.RateLimit.DefaultMuteDuration),
+		envInt64("STORAGE_MAX_UPLOAD_SIZE", &c.Storage.MaxUploadSize),
+		envBool("S3_USE_SSL", &c.Storage.S3.UseSSL),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	// RATE_LIMIT_QUESTION=0.2:3 のように messageType 毎に指定する
+	for messageType, bucket := range c.RateLimit.Messages {
+		if err := envBucket("RATE_LIMIT_"+strings.ToUpper(messageType), &bucket); err != nil {
+			return err
+		}
+		c.RateLimit.Messages[messageType] = bucket
+	}
+	return nil
+}
+
+func (c *Config) validate() error {
+	var errs []string
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port <= 0 || port > 65535 {
+		errs = append(errs, fmt.Sprintf("port が不正です: %q", c.Port))
+	}
+	if c.DB.DBMS == "" {
+		errs = append(errs, "db.dbms (DBMS) が未設定です")
+	}
+	if c.DB.Protocol == "" {
+		errs = append(errs, "db.protocol (DBPROTOCOL) が未設定です")
+	}
+	if c.DB.Name == "" {
+		errs = append(errs, "db.name (DBNAME) が未設定です")
+	}
+	if c.WebSocket.WriteWait <= 0 {
+		errs = append(errs, "websocket.writeWait は正の値にしてください")
+	}
+	if c.WebSocket.PingPeriod() <= 0 {
+		errs = append(errs, "websocket.pongWait は正の値にしてください")
+	}
+	if c.WebSocket.MaxMessageSize <= 0 {
+		errs = append(errs, "websocket.maxMessageSize は正の値にしてください")
+	}
+	if c.WebSocket.SendBufferSize <= 0 {
+		errs = append(errs, "websocket.sendBufferSize は正の値にしてください")
+	}
+	if c.WebSocket.MaxConnsPerIP < 0 || c.WebSocket.MaxConnsPerUser < 0 || c.WebSocket.MaxClientsPerMeeting < 0 {
+		errs = append(errs, "websocket の接続数上限は0以上にしてください")
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			errs = append(errs, fmt.Sprintf("allowedOrigins の形式が不正です: %q", origin))
+		}
+	}
+	if c.Moderator.MaxQuestionNum <= 0 {
+		errs = append(errs, "moderator.maxQuestionNum は正の値にしてください")
+	}
+	if c.Moderator.DuplicateThreshold < 0 || c.Moderator.DuplicateThreshold > 1 {
+		errs = append(errs, "moderator.duplicateThreshold は0から1の間にしてください")
+	}
+	if c.Moderator.PaceWindow <= 0 {
+		errs = append(errs, "moderator.paceWindow は正の値にしてください")
+	}
+	if c.Moderator.PaceThreshold < 0 || c.Moderator.PaceThreshold > 1 {
+		errs = append(errs, "moderator.paceThreshold は0から1の間にしてください")
+	}
+	if c.Moderator.PaceCooldown < 0 {
+		errs = append(errs, "moderator.paceCooldown は0以上にしてください")
+	}
+	if c.Moderator.NotifyConfusedNum < 0 {
+		errs = append(errs, "moderator.notifyConfusedNum は0以上にしてください")
+	}
+	if c.Moderator.NotifyQuestionNum < 0 {
+		errs = append(errs, "moderator.notifyQuestionNum は0以上にしてください")
+	}
+	if c.Moderator.PresentationTime < 0 {
+		errs = append(errs, "moderator.presentationTime は0以上にしてください")
+	}
+	if c.Moderator.PresentationTime > 0 && c.Moderator.TimeWarning <= 0 {
+		errs = append(errs, "moderator.timeWarning は正の値にしてください")
+	}
+	if c.Moderator.NotifyCooldown < 0 {
+		errs = append(errs, "moderator.notifyCooldown は0以上にしてください")
+	}
+	if c.Shutdown.Timeout <= 0 {
+		errs = append(errs, "shutdown.timeout は正の値にしてください")
+	}
+	if c.Shutdown.ReconnectAfter < 0 {
+		errs = append(errs, "shutdown.reconnectAfter は0以上にしてください")
+	}
+
+	for messageType, bucket := range c.RateLimit.Messages {
+		if !bucket.valid() {
+			errs = append(errs, fmt.Sprintf("rateLimit.messages.%s が不正です", messageType))
+		}
+	}
+	if !c.RateLimit.Login.valid() {
+		errs = append(errs, "rateLimit.login が不正です")
+	}
+	if c.RateLimit.DefaultMuteDuration <= 0 {
+		errs = append(errs, "rateLimit.defaultMuteDuration は正の値にしてください")
+	}
+	switch c.Storage.Driver {
+	case "local":
+		if c.Storage.LocalDir == "" {
+			errs = append(errs, "storage.localDir (STORAGE_LOCAL_DIR) が未設定です")
+		}
+	case "s3":
+		if c.Storage.S3.Endpoint == "" || c.Storage.S3.Bucket == "" {
+			errs = append(errs, "storage.s3.endpoint と storage.s3.bucket (S3_ENDPOINT, S3_BUCKET) を設定してください")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("storage.driver は local か s3 にしてください: %q", c.Storage.Driver))
+	}
+	if c.Storage.MaxUploadSize <= 0 {
+		errs = append(errs, "storage.maxUploadSize は正の値にしてください")
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("設定が不正です:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+func (b BucketConfig) valid() bool {
+	return b.Rate >= 0 && (b.Rate == 0 || b.Burst >= 1)
+}
+
+// redacted はパスワードなどの秘密情報を伏せたコピーを返す
+func (c *Config) redacted() *Config {
+	copied := *c
+	if copied.DB.Pass != "" {
+		copied.DB.Pass = "********"
+	}
+	if copied.Storage.S3.SecretKey != "" {
+		copied.Storage.S3.SecretKey = "********"
+	}
+	if copied.AnonymitySecret != "" {
+		copied.AnonymitySecret = "********"
+	}
+	return &copied
+}
+
+// printConfig は秘密情報を伏せた設定をYAMLで出力する (--print-config)
+func printConfig(c *Config) error {
+	data, err := yaml.Marshal(c.redacted())
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+func envString(key string, dst *string) {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		*dst = v
+	}
+}
+
+// envList はカンマ区切りの環境変数を読む
+func envList(key string, dst *[]string) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return
+	}
+	list := make([]string, 0, 4)
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*dst = list
+}
+
+func envBool(key string, dst *bool) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("環境変数 %s が不正です: %q", key, v)
+	}
+	*dst = b
+	return nil
+}
+
+func envInt(key string, dst *int) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("環境変数 %s が不正です: %q", key, v)
+	}
+	*dst = i
+	return nil
+}
+
+func envInt64(key string, dst *int64) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fmt.Errorf("環境変数 %s が不正です: %q", key, v)
+	}
+	*dst = i
+	return nil
+}
+
+func envFloat64(key string, dst *float64) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("環境変数 %s が不正です: %q", key, v)
+	}
+	*dst = f
+	return nil
+}
+
+// envBucket は "rate:burst" 形式の環境変数を読む
+func envBucket(key string, dst *BucketConfig) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	parts := strings.SplitN(v, ":", 2)
+	rate, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || len(parts) != 2 {
+		return fmt.Errorf("環境変数 %s が不正です (rate:burst): %q", key, v)
+	}
+	burst, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return fmt.Errorf("環境変数 %s が不正です (rate:burst): %q", key, v)
+	}
+	*dst = BucketConfig{Rate: rate, Burst: burst}
+	return nil
+}
+
+func envDuration(key string, dst *time.Duration) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("環境変数 %s が不正です: %q", key, v)
+	}
+	*dst = d
+	return nil
+}
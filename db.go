@@ -1,659 +1,2290 @@
-package main
-
-import (
-	"fmt"
-	"os"
-	"sort"
-	"time"
-
-	_ "github.com/go-sql-driver/mysql"
-	"github.com/jinzhu/gorm"
-)
-
-type User struct {
-	UserId       string //`gorm:"PRIMARY_KEY"`
-	UserName     string //`json:"user_name"`
-	UserPassword string //`json:"user_password"`
-}
-
-type Meeting struct {
-	MeetingId        int       `gorm:"AUTO_INCREMENT"`
-	MeetingName      string    //`json:"meeting_name`
-	MeetingStartTime time.Time //`json:meeting_start_time`
-	MeetingDone      bool      //`json:meeting_done`
-}
-
-type Participant struct {
-	MeetingId        int    //`gorm:"PRIMARY_KEY"`
-	UserId           string //`gorm:"PRIMARY_KEY"`
-	SpeakNum         int    //`json:"speaknum"`
-	ParticipantOrder int    //`json:"participantorder"`
-	IsJoining        bool
-}
-
-type Question struct {
-	QuestionId   int `gorm:"AUTO_INCREMENT"`
-	UserId       string
-	QuestionBody string
-	DocumentId   int
-	DocumentPage int
-	VoteNum      int
-	QuestionTime time.Time
-	QuestionOk   bool
-	IsVoice      bool
-}
-
-type QuestionAndPresenterId struct {
-	QuestionId   int
-	QuestionBody string
-	DocumentId   int
-	DocumentPage int
-	QuestionTime time.Time
-	UserId       string
-	VoteNum      int
-}
-
-type Document struct {
-	DocumentId  int `gorm:"AUTO_INCREMENT"`
-	UserId      string
-	MeetingId   int
-	DocumentUrl *string
-	Script      *string
-}
-
-type Reaction struct {
-	DocumentId   int //`gorm:"PRIMARY_KEY"`
-	DocumentPage int //`gorm:"PRIMARY_KEY"`
-	ReactionNum  int
-	SuggestionOk bool
-}
-
-type ByParticipantOrder []Participant
-
-func (p ByParticipantOrder) Len() int           { return len(p) }
-func (p ByParticipantOrder) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
-func (p ByParticipantOrder) Less(i, j int) bool { return p[i].ParticipantOrder < p[j].ParticipantOrder }
-
-type ByQuestionTime []Question
-
-func (q ByQuestionTime) Len() int           { return len(q) }
-func (q ByQuestionTime) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
-func (q ByQuestionTime) Less(i, j int) bool { return q[i].QuestionTime.Before(q[j].QuestionTime) }
-
-type ReverseByReactionNum []Reaction
-
-func (r ReverseByReactionNum) Len() int           { return len(r) }
-func (r ReverseByReactionNum) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
-func (r ReverseByReactionNum) Less(i, j int) bool { return r[i].ReactionNum > r[j].ReactionNum }
-
-type ReverseByVoteNum []Question
-
-func (q ReverseByVoteNum) Len() int           { return len(q) }
-func (q ReverseByVoteNum) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
-func (q ReverseByVoteNum) Less(i, j int) bool { return q[i].VoteNum > q[j].VoteNum }
-
-type BySpeakNum []Participant
-
-func (p BySpeakNum) Len() int           { return len(p) }
-func (p BySpeakNum) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
-func (p BySpeakNum) Less(i, j int) bool { return p[i].SpeakNum < p[j].SpeakNum }
-
-// SQLConnect DB接続
-func sqlConnect() (database *gorm.DB, err error) {
-	DBMS := os.Getenv("DBMS")
-	DBUSER := os.Getenv("DBUSER")
-	DBPASS := os.Getenv("DBPASS")
-	DBPROTOCOL := os.Getenv("DBPROTOCOL")
-	DBNAME := os.Getenv("DBNAME")
-
-	CONNECT := DBUSER + ":" + DBPASS + "@" + DBPROTOCOL + "/" + DBNAME + "?tls=true&charset=utf8&parseTime=true&loc=Asia%2FTokyo"
-	return gorm.Open(DBMS, CONNECT)
-}
-
-func connectDB() *gorm.DB {
-	// DB接続
-	db, err := sqlConnect()
-	if err != nil {
-		panic(err.Error())
-	} else {
-		fmt.Printf("Log: DBへの接続に成功しました in connectDB\n")
-	}
-
-	return db
-}
-
-func signupUser(db *gorm.DB, userId string, userName string, userPassword string) bool {
-	user := User{UserId: userId, UserName: userName, UserPassword: userPassword}
-	if err := db.Create(&user).Error; err == nil {
-		fmt.Printf("Log: signup成功: %s, %s, %s in signupUser\n", userId, userName, userPassword)
-		return true
-	} else {
-		fmt.Printf("Error: signup失敗: %s, %s, %s in signupUser\n", userId, userName, userPassword)
-		return false
-	}
-}
-
-func loginUser(db *gorm.DB, userId string, userPassword string) (bool, string) {
-	var user User
-	// err := db.Find(&user).Error
-	err := db.First(&user, "user_id = ? AND user_password = ?", userId, userPassword).Error
-	if err == nil {
-		fmt.Printf("Log: login成功: %s, %s in loginUser\n", userId, userPassword)
-		return true, user.UserName
-	} else {
-		fmt.Printf("Error: login失敗: %s, %s in loginUser\n", userId, userPassword)
-		return false, ""
-	}
-}
-
-func createMeeting(db *gorm.DB, meetingName string, startTimeStr string, presenterIds []string) (bool, int, string) {
-	var (
-		user         User
-		layout       = "2006/01/02 15:04:05"
-		location, _  = time.LoadLocation("Asia/Tokyo")
-		startTime, _ = time.ParseInLocation(layout, startTimeStr, location)
-		meeting      = Meeting{MeetingName: meetingName, MeetingStartTime: startTime, MeetingDone: false}
-	)
-
-	if err := db.Create(&meeting).Error; err == nil {
-		for i, presenter := range presenterIds {
-			if err := db.First(&user, "user_id = ?", presenter).Error; err == nil {
-				participant := Participant{MeetingId: meeting.MeetingId, UserId: user.UserId, SpeakNum: 0, ParticipantOrder: i, IsJoining: false}
-				if err := db.Create(&participant).Error; err == nil {
-					document := Document{UserId: user.UserId, MeetingId: meeting.MeetingId}
-					if err := db.Create(&document).Error; err != nil {
-						fmt.Printf("Error: create失敗(空の資料作成に失敗しました): %s, %d in createMeeting\n", user.UserId, meeting.MeetingId)
-						return false, -1, ""
-					}
-				} else { // TODO: transaction
-					fmt.Printf("Error: create失敗(発表者%sの登録に失敗しました): %s, %s, %s in createMeeting\n", presenter, meetingName, startTimeStr, presenterIds)
-					return false, -1, ""
-				}
-			} else {
-				fmt.Printf("Error: create失敗(発表者%sが見つかりません): %s, %s, %s in createMeeting\n", presenter, meetingName, startTimeStr, presenterIds)
-				return false, -1, ""
-			}
-		}
-		fmt.Printf("Log: create成功: %s, %s, %s in createMeeting\n", meetingName, startTimeStr, presenterIds)
-		return true, meeting.MeetingId, meeting.MeetingName
-	} else {
-		fmt.Printf("Error: create失敗(会議の登録に失敗しました): %s, %s, %s in createMeeting\n", meetingName, startTimeStr, presenterIds)
-		return false, -1, ""
-	}
-}
-
-func joinMeeting(db *gorm.DB, userId string, meetingId int) (bool, string, time.Time, []string, []string, []int) {
-	var user User
-	var meeting Meeting
-	var participant Participant
-	var document Document
-	participants := make([]Participant, 0, 10)
-	user_info := db.First(&user, "user_id = ?", userId)
-	meeting_info := db.First(&meeting, "meeting_id = ?", meetingId)
-	if user_info.Error == nil && meeting_info.Error == nil {
-		participant_info := db.First(&participant, "meeting_id = ? AND user_id = ?", meetingId, userId).Where(&participant, "meeting_id = ? AND user_id = ?", meetingId, userId).Update("is_joining", true)
-		if participant_info.Error != nil {
-			participant.MeetingId = meetingId
-			participant.UserId = userId
-			participant.SpeakNum = 0
-			participant.ParticipantOrder = -1
-			participant.IsJoining = true
-			if err := db.Create(&participant).Error; err == nil {
-				fmt.Printf("Log: 参加者追加成功: %s, %d in joinMeeting\n", userId, meetingId)
-			} else {
-				fmt.Printf("Error: 参加者追加失敗: %s, %d in joinMeeting\n", userId, meetingId)
-				return false, "false", time.Now(), []string{}, []string{}, []int{}
-			}
-		} else {
-			if participant_err := db.Model(&participant).Where("meeting_id = ? AND user_id = ?", meetingId, userId).Update("is_joining", true).Error; participant_err != nil {
-				fmt.Printf("Error: update失敗(参加者の参加状態の更新に失敗しました): %s, %d in joinMeeting\n", userId, meetingId)
-				return false, "false", time.Now(), []string{}, []string{}, []int{}
-			}
-		}
-		if db.Find(&participants, "meeting_id = ? AND participant_order != ?", meetingId, -1); len(participants) == 0 {
-			fmt.Printf("Error: 発表者非存在: %d in joinMeeting\n", meetingId)
-			return false, "false", time.Now(), []string{}, []string{}, []int{}
-		}
-		presenter_names := make([]string, 0, 10)
-		presenter_ids := make([]string, 0, 10)
-		document_ids := make([]int, 0, 10)
-
-		sort.Sort(ByParticipantOrder(participants))
-
-		for _, p := range participants {
-			presenter_id := p.UserId
-			user_err := db.First(&user, "user_id = ?", presenter_id).Error
-			if user_err != nil {
-				fmt.Printf("Error: ユーザー非存在: %s in joinMeeting\n", presenter_id)
-				return false, "false", time.Now(), []string{}, []string{}, []int{}
-			}
-			document_err := db.First(&document, "user_id = ? AND meeting_id = ?", p.UserId, p.MeetingId).Error
-			if document_err != nil {
-				fmt.Printf("Error: 資料非存在: %s, %d in joinMeeting\n", p.UserId, p.MeetingId)
-				return false, "false", time.Now(), []string{}, []string{}, []int{}
-			}
-			presenter_names = append(presenter_names, user.UserName)
-			presenter_ids = append(presenter_ids, user.UserId)
-			document_ids = append(document_ids, document.DocumentId)
-		}
-
-		fmt.Printf("Log: join成功: %s, %d in joinMeeting\n", userId, meetingId)
-		return true, meeting.MeetingName, meeting.MeetingStartTime, presenter_names, presenter_ids, document_ids
-
-	} else {
-		fmt.Printf("Error: ユーザーもしくは会議が非存在: %s, %d in joinMeeting\n", userId, meetingId)
-		return false, "false", time.Now(), []string{}, []string{}, []int{}
-	}
-}
-
-func exitMeeting(db *gorm.DB, userId string, meetingId int, documentId int) bool {
-	var participant Participant
-	var question Question
-	if participant_err := db.Model(&participant).Where("meeting_id = ? AND user_id = ?", meetingId, userId).Update("is_joining", false).Error; participant_err != nil {
-		fmt.Printf("Error: update失敗(参加者の参加状態の更新に失敗しました): %d, %s in exitMeeting\n", meetingId, userId)
-		return false
-	}
-	fmt.Printf("Log: update成功(参加者の参加状態の更新に成功しました): %d, %s in exitMeeting\n", meetingId, userId)
-	if delete_question_err := db.First(&question, "user_id = ? AND document_id = ? AND question_ok = ? AND is_voice = ?", userId, documentId, false, true).Delete(&question, "user_id = ? AND document_id = ? AND question_ok = ? AND is_voice = ?", userId, documentId, false, true).Error; delete_question_err != nil {
-		fmt.Printf("Log: delete失敗(質問が存在しないか，削除に失敗しました): %s, %d, %t, %t in exitMeeting\n", userId, documentId, false, true)
-	} else {
-		fmt.Printf("Log: delete成功(質問の削除に成功しました): %s, %d, %t, %t in exitMeeting\n", userId, documentId, false, true)
-	}
-
-	fmt.Printf("Log: exit成功: %s, %d in exitMeeting\n", userId, meetingId)
-	return true
-}
-
-func documentRegister(db *gorm.DB, documentId int, documentUrl string, script string) (bool, int) {
-	var document Document
-	if err := db.First(&document, "document_id = ?", documentId).Error; err != nil {
-		fmt.Printf("Error: 資料が非存在: %d in documentRegister\n", documentId)
-		return false, -1
-	}
-	if documentUrl != "" {
-		if document_err := db.Model(&document).Where("document_id = ?", document.DocumentId).Update("document_url", documentUrl).Error; document_err != nil {
-			fmt.Printf("Error: update失敗(資料URLの登録に失敗しました): %d in documentRegister\n", document.DocumentId)
-			return false, -1
-		} else {
-			fmt.Printf("Log: update成功(資料URLの登録に成功しました): %d in documentRegister\n", document.DocumentId)
-		}
-	}
-	if script != "" {
-		if script_err := db.Model(&document).Where("document_id = ?", document.DocumentId).Update("script", script).Error; script_err != nil {
-			fmt.Printf("Error: update失敗(原稿の登録に失敗しました): %d in documentRegister\n", document.DocumentId)
-			return false, -1
-		} else {
-			fmt.Printf("Log: update成功(原稿の登録に成功しました): %d in documentRegister\n", document.DocumentId)
-		}
-	}
-
-	return true, document.MeetingId
-}
-
-func createQuestion(db *gorm.DB, question Question) (bool, int) {
-	if err := db.Create(&question).Error; err != nil {
-		fmt.Printf("Error: create失敗(質問の登録に失敗しました): %s, %d, %s in createQuestion\n", question.UserId, question.DocumentId, question.QuestionTime)
-		return false, -1
-	}
-	fmt.Printf("Log: create成功(質問の登録に成功しました): %s, %d, %s in createQuestion\n", question.UserId, question.DocumentId, question.QuestionTime)
-	return true, question.QuestionId
-}
-
-func selectQuestion(db *gorm.DB, meetingId, documentId int, presenterId string, questionUserId string) (bool, bool, string, int) {
-	pickQuestioner := true
-	suggestQuestion := false
-	var question Question
-	var participant Participant
-	nextQuestionUserId := ""
-	location, _ := time.LoadLocation("Asia/Tokyo")
-
-	if voice_question_err := db.First(&question, "document_id = ? AND question_ok = ? AND is_voice = ?", documentId, false, true).Error; voice_question_err == nil {
-		if question_err := db.Model(&question).Where("question_id = ?", question.QuestionId).Update("question_ok", true).Error; question_err != nil {
-			fmt.Printf("Error: update失敗(質問の回答状況の更新に失敗しました): %d in selectQuestion\n", question.QuestionId)
-			return false, false, "", -1
-		}
-		if incSpeakNum_err := db.Model(&participant).Where("meeting_id = ? AND user_id = ?", meetingId, question.UserId).Update("speak_num", participant.SpeakNum+1).Error; incSpeakNum_err != nil {
-			fmt.Printf("Error: update失敗(参加者の話数の更新に失敗しました): %s, %d, %d in selectQuestion\n", participant.UserId, participant.MeetingId, participant.SpeakNum)
-			return false, false, "", -1
-		}
-		nextQuestionUserId = question.UserId
-		return pickQuestioner, suggestQuestion, nextQuestionUserId, question.QuestionId
-	} else {
-		questions := make([]Question, 0, 10)
-		if db.Find(&questions, "document_id = ? AND question_ok = ? AND is_voice = ?", documentId, false, false); len(questions) != 0 {
-			sort.Sort(ReverseByVoteNum(questions))
-			question = questions[0]
-			if question_err := db.Model(&question).Where("question_id = ?", question.QuestionId).Update("question_ok", true).Error; question_err != nil {
-				fmt.Printf("Error: update失敗(質問の回答状況の更新に失敗しました): %d in selectQuestion\n", question.QuestionId)
-				return false, false, "", -1
-			}
-			pickQuestioner = false
-			if incSpeakNum_err := db.Model(&participant).Where("meeting_id = ? AND user_id = ?", meetingId, question.UserId).Update("speak_num", participant.SpeakNum+1).Error; incSpeakNum_err != nil {
-				fmt.Printf("Error: update失敗(参加者の話数の更新に失敗しました): %s, %d, %d in selectQuestion\n", participant.UserId, participant.MeetingId, participant.SpeakNum)
-				return false, false, "", -1
-			}
-		}
-	}
-	if pickQuestioner {
-		participants := make([]Participant, 0, 10)
-		if db.Find(&participants, "meeting_id = ? AND user_id != ? AND user_id != ? AND is_joining = ?", meetingId, presenterId, questionUserId, true); len(participants) != 0 {
-			reactions := make([]Reaction, 0, 10)
-			if db.Find(&reactions, "document_id = ? AND suggestion_ok = ?", documentId, false); len(reactions) != 0 {
-				sort.Sort(ReverseByReactionNum(reactions))
-				if reactions[0].ReactionNum >= len(participants)/2 {
-					if reaction_err := db.Model(&reactions[0]).Where("document_id = ? AND document_page = ?", reactions[0].DocumentId, reactions[0].DocumentPage).Update("suggestion_ok", true).Error; reaction_err != nil {
-						fmt.Printf("Error: update失敗(資料リアクションの提案状況の更新に失敗しました): %d, %d in selectQuestion\n", reactions[0].DocumentId, reactions[0].DocumentPage)
-						return false, false, "", -1
-					}
-					question = Question{
-						UserId:       "Moderator",
-						QuestionBody: fmt.Sprintf("%dページについての詳しい説明を要求．", reactions[0].DocumentPage),
-						DocumentId:   reactions[0].DocumentId,
-						DocumentPage: reactions[0].DocumentPage,
-						VoteNum:      reactions[0].ReactionNum,
-						QuestionTime: time.Now().In(location),
-						QuestionOk:   true,
-						IsVoice:      false,
-					}
-					if err := db.Create(&question).Error; err != nil {
-						fmt.Printf("Error: create失敗(質問の登録に失敗しました): %s, %d, %s in selectQuestion\n", question.UserId, question.DocumentId, question.QuestionTime)
-						return false, false, "", -1
-					}
-					fmt.Printf("Log: create成功(質問の登録に成功しました): %s, %d, %s in selectQuestion\n", question.UserId, question.DocumentId, question.QuestionTime)
-					pickQuestioner = false
-					suggestQuestion = true
-					return pickQuestioner, suggestQuestion, nextQuestionUserId, question.QuestionId
-				}
-			}
-			sort.Sort(BySpeakNum(participants))
-			// rand_max := 3
-			// if len(participants) < 3 {
-			//	rand_max = len(participants)
-			// }
-			// participant = participants[rand.Intn(rand_max)]
-			participant = participants[0]
-			nextQuestionUserId = participant.UserId
-			question = Question{
-				UserId:       nextQuestionUserId,
-				QuestionBody: "",
-				DocumentId:   documentId,
-				DocumentPage: 1,
-				VoteNum:      0,
-				QuestionTime: time.Now().In(location),
-				QuestionOk:   true,
-				IsVoice:      true,
-			}
-			if err := db.Create(&question).Error; err != nil {
-				fmt.Printf("Error: create失敗(質問の登録に失敗しました): %s, %d, %s in selectQuestion\n", question.UserId, question.DocumentId, question.QuestionTime)
-				return false, false, "", -1
-			}
-			if err := db.Model(&participant).Where("user_id = ? AND meeting_id = ?", participant.UserId, participant.MeetingId).Update("speak_num", participant.SpeakNum+1).Error; err != nil {
-				fmt.Printf("Error: update失敗(参加者の話数の更新に失敗しました): %s, %d, %d in selectQuestion\n", participant.UserId, participant.MeetingId, participant.SpeakNum)
-				return false, false, "", -1
-			}
-			fmt.Printf("Log: create成功(質問の登録に成功しました): %s, %d, %s in selectQuestion\n", question.UserId, question.DocumentId, question.QuestionTime)
-
-		} else {
-			fmt.Printf("Error: 参加者が非存在: %d in selectQuestion\n", meetingId)
-			return false, false, "", -1
-		}
-	}
-	return pickQuestioner, suggestQuestion, nextQuestionUserId, question.QuestionId
-}
-
-func voteQuestion(db *gorm.DB, questionId int, isVote bool) (int, int, int) {
-	var question Question
-	var document Document
-	if err := db.First(&question, "question_id = ?", questionId).Error; err != nil {
-		fmt.Printf("Error: 質問が非存在: %d in voteQuestion\n", questionId)
-		return -1, -1, -1
-	}
-	voteNum := question.VoteNum
-	if isVote {
-		voteNum += 1
-	} else {
-		voteNum -= 1
-	}
-	if err := db.Model(&question).Where("question_id = ?", questionId).Update("vote_num", voteNum).Error; err != nil {
-		fmt.Printf("Error: update失敗(質問の投票数の更新に失敗しました): %d, %d in voteQuestion\n", questionId, voteNum)
-		return -1, -1, -1
-	}
-
-	if err := db.First(&document, "document_id = ?", question.DocumentId).Error; err != nil {
-		fmt.Printf("Error: 資料が非存在: %d in voteQuestion\n", question.DocumentId)
-		return -1, -1, -1
-	}
-
-	return document.MeetingId, questionId, voteNum
-}
-
-func handsUp(db *gorm.DB, userId string, documentId int, documentPage int) int {
-	var document Document
-	location, _ := time.LoadLocation("Asia/Tokyo")
-
-	if document_err := db.First(&document, "document_id = ?", documentId).Error; document_err != nil {
-		fmt.Printf("Error: 資料が非存在: %d in handsUp\n", documentId)
-		return -1
-	}
-
-	if user_err := db.First(&User{}, "user_id = ?", userId).Error; user_err != nil {
-		fmt.Printf("Error: ユーザーが非存在: %s in handsUp\n", userId)
-		return -1
-	}
-
-	question := Question{
-		UserId:       userId,
-		QuestionBody: "",
-		DocumentId:   document.DocumentId,
-		DocumentPage: documentPage,
-		VoteNum:      0,
-		QuestionTime: time.Now().In(location),
-		QuestionOk:   false,
-		IsVoice:      true,
-	}
-	if question_err := db.Create(&question).Error; question_err != nil {
-		fmt.Printf("Error: create失敗(質問の登録に失敗しました): %s, %d, %d, %s in handsUp\n", question.UserId, question.DocumentId, question.DocumentPage, question.QuestionTime)
-		return -1
-	}
-	fmt.Printf("Log: create成功(質問の登録に成功しました): %s, %d, %d, %s in handsUp\n", question.UserId, question.DocumentId, question.DocumentPage, question.QuestionTime)
-	return document.MeetingId
-}
-
-func handsDown(db *gorm.DB, userId string, documentId int, documentPage int) int {
-	var (
-		document Document
-		question Question
-	)
-
-	if document_err := db.First(&document, "document_id = ?", documentId).Error; document_err != nil {
-		fmt.Printf("Error: 資料が非存在: %d in handsDown\n", documentId)
-		return -1
-	}
-	if user_err := db.First(&User{}, "user_id = ?", userId).Error; user_err != nil {
-		fmt.Printf("Error: ユーザーが非存在: %s in handsDown\n", userId)
-		return -1
-	}
-	if question_err := db.First(&question, "user_id = ? AND document_id = ? AND document_page = ? AND question_ok = ? AND is_voice = ?", userId, document.DocumentId, documentPage, false, true).Error; question_err != nil {
-		fmt.Printf("Error: 質問が非存在: %s, %d, %d in handsDown\n", userId, document.DocumentId, documentPage)
-		return -1
-	}
-	if delete_question_err := db.Where("question_id = ?", question.QuestionId).Delete(&question).Error; delete_question_err != nil {
-		fmt.Printf("Error: delete失敗(質問の削除に失敗しました): %d in handsDown\n", question.QuestionId)
-		return -1
-	}
-	fmt.Printf("Log: delete成功(質問の削除に成功しました): %d in handsDown\n", question.QuestionId)
-	return document.MeetingId
-}
-
-func voteReaction(db *gorm.DB, documentId int, documentPage int, isReaction bool) (int, int) {
-	var document Document
-	var reaction Reaction
-
-	if document_err := db.First(&document, "document_id = ?", documentId).Error; document_err != nil {
-		fmt.Printf("Error: 資料が非存在: %d in voteReaction\n", documentId)
-		return -1, -1
-	}
-
-	if reaction_err := db.First(&reaction, "document_id = ? AND document_page = ?", documentId, documentPage).Error; reaction_err != nil {
-		if !isReaction {
-			fmt.Printf("Error: 資料リアクションが非存在: %d, %d in voteReaction\n", documentId, documentPage)
-			return -1, -1
-		}
-		reaction = Reaction{
-			DocumentId:   document.DocumentId,
-			DocumentPage: documentPage,
-			ReactionNum:  1,
-			SuggestionOk: false,
-		}
-		if create_reaction_err := db.Create(&reaction).Error; create_reaction_err != nil {
-			fmt.Printf("Error: create失敗(資料リアクションの登録に失敗しました): %d, %d in voteReaction\n", reaction.DocumentId, reaction.DocumentPage)
-			return -1, -1
-		}
-		fmt.Printf("Log: create成功(資料リアクションの登録に成功しました): %d, %d in voteReaction\n", reaction.DocumentId, reaction.DocumentPage)
-	} else {
-		reactionNum := reaction.ReactionNum
-		if isReaction {
-			reactionNum += 1
-		} else {
-			reactionNum -= 1
-		}
-		if update_reaction_num_err := db.Model(&reaction).Where("document_id = ? AND document_page = ?", reaction.DocumentId, reaction.DocumentPage).Update("reaction_num", reactionNum).Error; update_reaction_num_err != nil {
-			fmt.Printf("Error: update失敗(資料リアクションのリアクション数の更新に失敗しました): %d, %d in voteReaction\n", reaction.DocumentId, reaction.DocumentPage)
-			return -1, -1
-		}
-		fmt.Printf("Log: update成功(資料リアクションのリアクション数の更新に成功しました): %d, %d in voteReaction\n", reaction.DocumentId, reaction.DocumentPage)
-	}
-	return document.MeetingId, reaction.ReactionNum
-}
-
-func getNextPresenterId(db *gorm.DB, meetingId int, nowPresenterId string) (bool, string, int) {
-	var participant Participant
-	if participant_err := db.First(&participant, "meeting_id = ? AND user_id = ?", meetingId, nowPresenterId).Error; participant_err != nil {
-		fmt.Printf("Error: 参加者が非存在: %s in getNextPresenterId\n", nowPresenterId)
-		return false, "", -1
-	}
-	nextOrder := participant.ParticipantOrder + 1
-	if meeting_end_err := db.First(&participant, "meeting_id = ? AND participant_order = ?", meetingId, nextOrder).Error; meeting_end_err != nil {
-		fmt.Printf("Log: 会議終了につき次の発表者が非存在: %d in getNextPresenterId\n", nextOrder)
-		return true, "", -1
-	}
-	return false, participant.UserId, nextOrder
-}
-
-func getUserName(db *gorm.DB, userId string) string {
-	var user User
-	if err := db.First(&user, "user_id = ?", userId).Error; err != nil {
-		fmt.Printf("Error: ユーザーが非存在: %s in getUserName\n", userId)
-		return ""
-	}
-	return user.UserName
-}
-
-func getFirstPresenUserName(db *gorm.DB, meetingId int) string {
-	participants := make([]Participant, 0, 10)
-	if db.Find(&participants, "meeting_id = ? AND participant_order != -1", meetingId); len(participants) == 0 {
-		fmt.Printf("Error: 会議非存在: %d in getFirstPresenUserName\n", meetingId)
-		return ""
-	}
-
-	sort.Sort(ByParticipantOrder(participants))
-
-	userName := getUserName(db, participants[0].UserId)
-
-	return userName
-}
-
-func getQuestionBody(db *gorm.DB, questionId int) (string, int) {
-	var question Question
-	if err := db.First(&question, "question_id = ?", questionId).Error; err != nil {
-		fmt.Printf("Error: 質問が非存在: %d in getQuestionBody\n", questionId)
-		return "", -1
-	}
-	return question.QuestionBody, question.DocumentPage
-}
-
-func getQuestionDocumentPage(db *gorm.DB, questionId int) int {
-	var question Question
-	if err := db.First(&question, "question_id = ?", questionId).Error; err != nil {
-		fmt.Printf("Error: 質問が非存在: %d in getQuestionDocumentPage\n", questionId)
-		return -1
-	}
-	return question.DocumentPage
-}
-
-func getDocumentId(db *gorm.DB, userId string, meetingId int) int {
-	var document Document
-	if err := db.First(&document, "user_id = ? AND meeting_id = ?", userId, meetingId).Error; err != nil {
-		fmt.Printf("Error: 資料が非存在: %s, %d in getDocumentId\n", userId, meetingId)
-		return -1
-	}
-	return document.DocumentId
-}
-
-func documentGet(db *gorm.DB, documentId int) (bool, string, string) {
-	var (
-		document    Document
-		documentUrl *string
-		script      *string
-		emptyString = ""
-	)
-
-	if err := db.First(&document, "document_id = ?", documentId).Error; err != nil {
-		fmt.Printf("Error: 資料が非存在: %d in documentGet\n", documentId)
-		return false, "", ""
-	}
-	if documentUrl = document.DocumentUrl; documentUrl == nil {
-		fmt.Printf("Log: 資料URLが非存在: %d in documentGet\n", documentId)
-		documentUrl = &emptyString
-	}
-	if script = document.Script; script == nil {
-		fmt.Printf("Log: 原稿が非存在: %d in documentGet\n", documentId)
-		script = &emptyString
-	}
-	return true, *documentUrl, *script
-}
-
-func questionsGet(db *gorm.DB, meetingId int) (bool, int, []int, []string, []int, []int, []string, []string, []int) {
-	var (
-		layout        = "2006/01/02 15:04:05"
-		location, _   = time.LoadLocation("Asia/Tokyo")
-		questions     = make([]QuestionAndPresenterId, 0, 10)
-		questionIds   = make([]int, 0, 10)
-		questionBodys = make([]string, 0, 10)
-		documentIds   = make([]int, 0, 10)
-		documentPages = make([]int, 0, 10)
-		questionTimes = make([]string, 0, 10)
-		presenterIds  = make([]string, 0, 10)
-		voteNums      = make([]int, 0, 10)
-	)
-	if db.Table("documents").Select("questions.question_id, questions.question_body, questions.document_id, questions.document_page, questions.question_time, documents.user_id, questions.vote_num").Where("documents.meeting_id = ?", meetingId).Joins("right join questions on documents.document_id = questions.document_id").Scan(&questions); len(questions) == 0 {
-		fmt.Printf("Log: 質問が非存在: %d in questionsGet\n", meetingId)
-		return false, meetingId, []int{}, []string{}, []int{}, []int{}, []string{}, []string{}, []int{}
-	}
-	for _, q := range questions {
-		questionIds = append(questionIds, q.QuestionId)
-		questionBodys = append(questionBodys, q.QuestionBody)
-		documentIds = append(documentIds, q.DocumentId)
-		documentPages = append(documentPages, q.DocumentPage)
-		questionTimes = append(questionTimes, q.QuestionTime.In(location).Format(layout))
-		presenterIds = append(presenterIds, q.UserId)
-		voteNums = append(voteNums, q.VoteNum)
-	}
-
-	return true, meetingId, questionIds, questionBodys, documentIds, documentPages, questionTimes, presenterIds, voteNums
-}
-
-func getPresenterId(db *gorm.DB, documentId int) string {
-	var document Document
-	if err := db.First(&document, "document_id = ?", documentId).Error; err != nil {
-		fmt.Printf("Error: 資料が非存在: %d in getPresenterId\n", documentId)
-		return ""
-	}
-	return document.UserId
-}
-
-func setMeetingDone(db *gorm.DB, meetingId int) {
-	var meeting Meeting
-	db.First(&meeting, "meeting_id = ?", meetingId)
-	db.Model(&meeting).Where("meeting_id = ?", meetingId).Update("meeting_done", true)
-}
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+)
+
+type User struct {
+	UserId       string //`gorm:"PRIMARY_KEY"`
+	UserName     string //`json:"user_name"`
+	UserPassword string //`json:"user_password"`
+}
+
+type Meeting struct {
+	MeetingId        int        `gorm:"AUTO_INCREMENT"`
+	MeetingName      string     //`json:"meeting_name`
+	MeetingStartTime time.Time  //`json:meeting_start_time`
+	MeetingDone      bool       //`json:meeting_done`
+	HostUserId       string     // 会議のホスト(ミュートなどの権限を持つ)
+	MeetingEndTime   *time.Time // 司会が会議の終了を告げた時刻 (終了前はnil)
+	PreModeration    bool       // 質問をホストが承認してから公開する
+	AnonymityLevel   string     `gorm:"default:'anonymous'"` // anonymityRoom など
+	AnonymitySalt    string     `json:"-"`                   // 完全匿名の会議でサーバーの秘密と合わせて仮名を作る (これだけでは仮名を計算できない)
+}
+
+type Participant struct {
+	MeetingId        int    //`gorm:"PRIMARY_KEY"`
+	UserId           string //`gorm:"PRIMARY_KEY"`
+	SpeakNum         int    //`json:"speaknum"`
+	ParticipantOrder int    //`json:"participantorder"`
+	IsJoining        bool
+}
+
+type Question struct {
+	QuestionId   int `gorm:"AUTO_INCREMENT"`
+	UserId       string
+	QuestionBody string
+	DocumentId   int
+	DocumentPage int
+	VoteNum      int
+	QuestionTime time.Time
+	QuestionOk   bool
+	IsVoice      bool
+
+	DocumentVersion int  // 質問した時点の資料のバージョン
+	IsNominated     bool // 司会が指名した発言 (挙手ではない)
+
+	ModerationStatus string `gorm:"default:'approved'"` // questionApproved など
+	MergedInto       int    // 統合された場合は統合先の質問ID
+	DuplicateOf      int    // 重複の可能性が高い公開中の質問ID (検出されなければ0)
+	HandOrder        int    // 挙手の順番 (小さいほど先，挙手以外は0)
+	IsLowered        bool   `gorm:"default:false"` // ホストや発表者の交代で下げられた挙手 (報告書に残すため削除しない)
+}
+
+// 質問の公開状態 (事前承認の会議では承認されるまでpending)
+const (
+	questionApproved = "approved"
+	questionPending  = "pending"
+	questionRejected = "rejected"
+	questionMerged   = "merged"
+
+	questionWithdrawn = "withdrawn" // 質問者かホストが取り下げた
+)
+
+// QuestionEdit は質問の本文の編集履歴 (編集前の本文を残す)
+type QuestionEdit struct {
+	QuestionEditId int    `gorm:"AUTO_INCREMENT"`
+	QuestionId     int    `gorm:"index"`
+	UserId         string // 編集した質問者かホスト
+	PreviousBody   string `gorm:"type:text"`
+	EditedAt       time.Time
+}
+
+// Answer は質問への文章での回答
+type Answer struct {
+	AnswerId   int `gorm:"AUTO_INCREMENT"`
+	QuestionId int `gorm:"index"`
+	UserId     string
+	AnswerBody string `gorm:"type:text"`
+	AnsweredAt time.Time
+	IsFollowUp bool // 会議中に取り上げられなかった質問への事後の回答
+}
+
+// Reply は質問へのスレッド形式の返信
+type Reply struct {
+	ReplyId       int `gorm:"AUTO_INCREMENT"`
+	QuestionId    int `gorm:"index"`
+	ParentReplyId int // 返信への返信の場合は返信先のID (質問への返信は0)
+	UserId        string
+	ReplyBody     string `gorm:"type:text"`
+	RepliedAt     time.Time
+}
+
+// InboxMessage はオフラインだった利用者に後から届ける通知
+type InboxMessage struct {
+	InboxMessageId int    `gorm:"AUTO_INCREMENT"`
+	UserId         string `gorm:"index"`
+	MessageType    string
+	Body           string `gorm:"type:text"` // WebSocketで送るメッセージと同じJSON
+	CreatedAt      time.Time
+	IsRead         bool
+}
+
+// 司会が作成した質問の投稿者
+const moderatorUserId = "Moderator"
+
+type QuestionAndPresenterId struct {
+	QuestionId   int
+	QuestionBody string
+	DocumentId   int
+	DocumentPage int
+	QuestionTime time.Time
+	UserId       string
+	VoteNum      int
+	AskerId      string
+	IsVoice      bool
+}
+
+type Document struct {
+	DocumentId  int `gorm:"AUTO_INCREMENT"`
+	UserId      string
+	MeetingId   int
+	DocumentUrl *string
+	Script      *string
+	FileKey     *string // アップロードされたファイルのBlobStore上のキー
+	PageCount   int     // アップロードされたPDFのページ数 (未アップロードは0)
+	Version     int     // 現在のバージョン (未登録は0)
+}
+
+// DocumentVersion は資料を登録する毎に作られる変更不可のスナップショット
+type DocumentVersion struct {
+	DocumentId     int `gorm:"primary_key;auto_increment:false"`
+	Version        int `gorm:"primary_key;auto_increment:false"`
+	DocumentUrl    *string
+	Script         *string `gorm:"type:text"`
+	ScriptSegments string  `gorm:"type:text"` // []ScriptSegment のJSON
+	FileKey        *string
+	PageCount      int
+	CreatedAt      time.Time
+}
+
+// ScriptSegment はページ毎の原稿 (Markdown)
+type ScriptSegment struct {
+	DocumentId   int    `gorm:"primary_key;auto_increment:false"`
+	DocumentPage int    `gorm:"primary_key;auto_increment:false"`
+	Script       string `gorm:"type:text"`
+}
+
+type ByScriptSegmentPage []ScriptSegment
+
+func (s ByScriptSegmentPage) Len() int           { return len(s) }
+func (s ByScriptSegmentPage) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s ByScriptSegmentPage) Less(i, j int) bool { return s[i].DocumentPage < s[j].DocumentPage }
+
+// Page はアップロードされたPDFの1ページ
+type Page struct {
+	DocumentId   int    `gorm:"primary_key;auto_increment:false"`
+	DocumentPage int    `gorm:"primary_key;auto_increment:false"`
+	PageText     string `gorm:"type:text"`
+	ThumbnailKey string // レイアウトの簡易図 (PNG) のキー
+	Width        float64
+	Height       float64
+}
+
+type Reaction struct {
+	DocumentId      int //`gorm:"PRIMARY_KEY"`
+	DocumentPage    int //`gorm:"PRIMARY_KEY"`
+	ReactionNum     int
+	SuggestionOk    bool
+	DocumentVersion int // リアクションした時点の資料のバージョン
+}
+
+// PageReaction は参加者毎のページへのリアクション
+// 同じページ・バージョンには種類(絵文字は絵文字)毎に1つだけ
+type PageReaction struct {
+	PageReactionId  int `gorm:"AUTO_INCREMENT"`
+	DocumentId      int `gorm:"index"`
+	DocumentPage    int
+	DocumentVersion int
+	UserId          string
+	Kind            string // reactionConfused など
+	Emoji           string // Kind が reactionEmoji の場合のみ
+	CreatedAt       time.Time
+}
+
+// PageReactionCount は種類毎のリアクションの数
+type PageReactionCount struct {
+	DocumentVersion int // getDocumentPageReactionCounts のみ
+	DocumentPage    int
+	Kind            string
+	Emoji           string
+	ReactionNum     int
+}
+
+// ModeratorState は会議の司会進行の状態 (発表者が表示しているページ)
+type ModeratorState struct {
+	MeetingId     int `gorm:"primary_key;auto_increment:false"`
+	PresenterId   string
+	DocumentId    int
+	DocumentPage  int
+	PageChangedAt time.Time
+
+	PresenterStartedAt *time.Time // 発表者が最初にページを表示した時刻 (発表が終わるとnil)
+}
+
+// PageView は発表者がページを表示していた期間 (表示中はEndedAtがnil)
+type PageView struct {
+	PageViewId      int `gorm:"AUTO_INCREMENT"`
+	MeetingId       int `gorm:"index"`
+	DocumentId      int `gorm:"index"`
+	DocumentPage    int
+	DocumentVersion int
+	StartedAt       time.Time
+	EndedAt         *time.Time
+}
+
+// ModeratorMessage は司会が送信したメッセージの記録 (議事録用)
+type ModeratorMessage struct {
+	ModeratorMessageId int    `gorm:"AUTO_INCREMENT"`
+	MeetingId          int    `gorm:"index"`
+	Body               string `gorm:"type:text"`
+	SentAt             time.Time
+}
+
+type ByParticipantOrder []Participant
+
+func (p ByParticipantOrder) Len() int           { return len(p) }
+func (p ByParticipantOrder) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p ByParticipantOrder) Less(i, j int) bool { return p[i].ParticipantOrder < p[j].ParticipantOrder }
+
+type ByQuestionTime []Question
+
+func (q ByQuestionTime) Len() int           { return len(q) }
+func (q ByQuestionTime) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q ByQuestionTime) Less(i, j int) bool { return q[i].QuestionTime.Before(q[j].QuestionTime) }
+
+type ReverseByReactionNum []Reaction
+
+func (r ReverseByReactionNum) Len() int           { return len(r) }
+func (r ReverseByReactionNum) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r ReverseByReactionNum) Less(i, j int) bool { return r[i].ReactionNum > r[j].ReactionNum }
+
+type ReverseByVoteNum []Question
+
+func (q ReverseByVoteNum) Len() int           { return len(q) }
+func (q ReverseByVoteNum) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q ReverseByVoteNum) Less(i, j int) bool { return q[i].VoteNum > q[j].VoteNum }
+
+type BySpeakNum []Participant
+
+func (p BySpeakNum) Len() int           { return len(p) }
+func (p BySpeakNum) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p BySpeakNum) Less(i, j int) bool { return p[i].SpeakNum < p[j].SpeakNum }
+
+// SQLConnect DB接続
+func sqlConnect(config DBConfig) (database *gorm.DB, err error) {
+	CONNECT := config.User + ":" + config.Pass + "@" + config.Protocol + "/" + config.Name + "?tls=" + strconv.FormatBool(config.TLS) + "&charset=utf8&parseTime=true&loc=Asia%2FTokyo"
+	return gorm.Open(config.DBMS, CONNECT)
+}
+
+// migrateDB はテーブルに不足しているカラム・テーブルを追加する
+func migrateDB(db *gorm.DB) {
+	if err := db.AutoMigrate(&User{}, &Meeting{}, &Participant{}, &Question{}, &Document{}, &Reaction{}, &Page{}, &ScriptSegment{}, &DocumentVersion{}, &ModeratorState{}, &PageView{}, &ModeratorMessage{}, &Answer{}, &Reply{}, &InboxMessage{}, &QuestionEdit{}, &PageReaction{}).Error; err != nil {
+		panic(err.Error())
+	}
+	fmt.Printf("Log: DBのマイグレーションに成功しました in migrateDB\n")
+}
+
+func connectDB(config DBConfig) *gorm.DB {
+	// DB接続
+	db, err := sqlConnect(config)
+	if err != nil {
+		panic(err.Error())
+	} else {
+		fmt.Printf("Log: DBへの接続に成功しました in connectDB\n")
+	}
+
+	return db
+}
+
+func signupUser(db *gorm.DB, userId string, userName string, userPassword string) bool {
+	defer observeDBQuery("signupUser", time.Now())
+
+	user := User{UserId: userId, UserName: userName, UserPassword: userPassword}
+	if err := db.Create(&user).Error; err == nil {
+		fmt.Printf("Log: signup成功: %s, %s, %s in signupUser\n", userId, userName, userPassword)
+		return true
+	} else {
+		fmt.Printf("Error: signup失敗: %s, %s, %s in signupUser\n", userId, userName, userPassword)
+		return false
+	}
+}
+
+func loginUser(db *gorm.DB, userId string, userPassword string) (bool, string) {
+	defer observeDBQuery("loginUser", time.Now())
+
+	var user User
+	// err := db.Find(&user).Error
+	err := db.First(&user, "user_id = ? AND user_password = ?", userId, userPassword).Error
+	if err == nil {
+		fmt.Printf("Log: login成功: %s, %s in loginUser\n", userId, userPassword)
+		return true, user.UserName
+	} else {
+		fmt.Printf("Error: login失敗: %s, %s in loginUser\n", userId, userPassword)
+		return false, ""
+	}
+}
+
+func createMeeting(db *gorm.DB, meetingName string, startTimeStr string, presenterIds []string, hostId string, preModeration bool, anonymityLevel string) (bool, int, string) {
+	defer observeDBQuery("createMeeting", time.Now())
+
+	var (
+		user         User
+		layout       = "2006/01/02 15:04:05"
+		location, _  = time.LoadLocation("Asia/Tokyo")
+		startTime, _ = time.ParseInLocation(layout, startTimeStr, location)
+		meeting      = Meeting{MeetingName: meetingName, MeetingStartTime: startTime, MeetingDone: false, HostUserId: hostId, PreModeration: preModeration, AnonymityLevel: anonymityLevel}
+	)
+	if !meetingAnonymity(&meeting) {
+		fmt.Printf("Error: 匿名性の設定が不正です: %s in createMeeting\n", anonymityLevel)
+		return false, -1, ""
+	}
+
+	if err := db.Create(&meeting).Error; err == nil {
+		for i, presenter := range presenterIds {
+			if err := db.First(&user, "user_id = ?", presenter).Error; err == nil {
+				participant := Participant{MeetingId: meeting.MeetingId, UserId: user.UserId, SpeakNum: 0, ParticipantOrder: i, IsJoining: false}
+				if err := db.Create(&participant).Error; err == nil {
+					document := Document{UserId: user.UserId, MeetingId: meeting.MeetingId}
+					if err := db.Create(&document).Error; err != nil {
+						fmt.Printf("Error: create失敗(空の資料作成に失敗しました): %s, %d in createMeeting\n", user.UserId, meeting.MeetingId)
+						return false, -1, ""
+					}
+				} else { // TODO: transaction
+					fmt.Printf("Error: create失敗(発表者%sの登録に失敗しました): %s, %s, %s in createMeeting\n", presenter, meetingName, startTimeStr, presenterIds)
+					return false, -1, ""
+				}
+			} else {
+				fmt.Printf("Error: create失敗(発表者%sが見つかりません): %s, %s, %s in createMeeting\n", presenter, meetingName, startTimeStr, presenterIds)
+				return false, -1, ""
+			}
+		}
+		fmt.Printf("Log: create成功: %s, %s, %s in createMeeting\n", meetingName, startTimeStr, presenterIds)
+		return true, meeting.MeetingId, meeting.MeetingName
+	} else {
+		fmt.Printf("Error: create失敗(会議の登録に失敗しました): %s, %s, %s in createMeeting\n", meetingName, startTimeStr, presenterIds)
+		return false, -1, ""
+	}
+}
+
+func joinMeeting(db *gorm.DB, userId string, meetingId int) (bool, string, time.Time, []string, []string, []int) {
+	defer observeDBQuery("joinMeeting", time.Now())
+
+	var user User
+	var meeting Meeting
+	var participant Participant
+	var document Document
+	participants := make([]Participant, 0, 10)
+	user_info := db.First(&user, "user_id = ?", userId)
+	meeting_info := db.First(&meeting, "meeting_id = ?", meetingId)
+	if user_info.Error == nil && meeting_info.Error == nil {
+		participant_info := db.First(&participant, "meeting_id = ? AND user_id = ?", meetingId, userId).Where(&participant, "meeting_id = ? AND user_id = ?", meetingId, userId).Update("is_joining", true)
+		if participant_info.Error != nil {
+			participant.MeetingId = meetingId
+			participant.UserId = userId
+			participant.SpeakNum = 0
+			participant.ParticipantOrder = -1
+			participant.IsJoining = true
+			if err := db.Create(&participant).Error; err == nil {
+				fmt.Printf("Log: 参加者追加成功: %s, %d in joinMeeting\n", userId, meetingId)
+			} else {
+				fmt.Printf("Error: 参加者追加失敗: %s, %d in joinMeeting\n", userId, meetingId)
+				return false, "false", time.Now(), []string{}, []string{}, []int{}
+			}
+		} else {
+			if participant_err := db.Model(&participant).Where("meeting_id = ? AND user_id = ?", meetingId, userId).Update("is_joining", true).Error; participant_err != nil {
+				fmt.Printf("Error: update失敗(参加者の参加状態の更新に失敗しました): %s, %d in joinMeeting\n", userId, meetingId)
+				return false, "false", time.Now(), []string{}, []string{}, []int{}
+			}
+		}
+		if db.Find(&participants, "meeting_id = ? AND participant_order != ?", meetingId, -1); len(participants) == 0 {
+			fmt.Printf("Error: 発表者非存在: %d in joinMeeting\n", meetingId)
+			return false, "false", time.Now(), []string{}, []string{}, []int{}
+		}
+		presenter_names := make([]string, 0, 10)
+		presenter_ids := make([]string, 0, 10)
+		document_ids := make([]int, 0, 10)
+
+		sort.Sort(ByParticipantOrder(participants))
+
+		for _, p := range participants {
+			presenter_id := p.UserId
+			user_err := db.First(&user, "user_id = ?", presenter_id).Error
+			if user_err != nil {
+				fmt.Printf("Error: ユーザー非存在: %s in joinMeeting\n", presenter_id)
+				return false, "false", time.Now(), []string{}, []string{}, []int{}
+			}
+			document_err := db.First(&document, "user_id = ? AND meeting_id = ?", p.UserId, p.MeetingId).Error
+			if document_err != nil {
+				fmt.Printf("Error: 資料非存在: %s, %d in joinMeeting\n", p.UserId, p.MeetingId)
+				return false, "false", time.Now(), []string{}, []string{}, []int{}
+			}
+			presenter_names = append(presenter_names, user.UserName)
+			presenter_ids = append(presenter_ids, user.UserId)
+			document_ids = append(document_ids, document.DocumentId)
+		}
+
+		fmt.Printf("Log: join成功: %s, %d in joinMeeting\n", userId, meetingId)
+		return true, meeting.MeetingName, meeting.MeetingStartTime, presenter_names, presenter_ids, document_ids
+
+	} else {
+		fmt.Printf("Error: ユーザーもしくは会議が非存在: %s, %d in joinMeeting\n", userId, meetingId)
+		return false, "false", time.Now(), []string{}, []string{}, []int{}
+	}
+}
+
+func exitMeeting(db *gorm.DB, userId string, meetingId int, documentId int) bool {
+	defer observeDBQuery("exitMeeting", time.Now())
+
+	var participant Participant
+	var question Question
+	if participant_err := db.Model(&participant).Where("meeting_id = ? AND user_id = ?", meetingId, userId).Update("is_joining", false).Error; participant_err != nil {
+		fmt.Printf("Error: update失敗(参加者の参加状態の更新に失敗しました): %d, %s in exitMeeting\n", meetingId, userId)
+		return false
+	}
+	fmt.Printf("Log: update成功(参加者の参加状態の更新に成功しました): %d, %s in exitMeeting\n", meetingId, userId)
+	if delete_question_err := db.First(&question, "user_id = ? AND document_id = ? AND question_ok = ? AND is_voice = ? AND is_lowered = ?", userId, documentId, false, true, false).Delete(&question, "user_id = ? AND document_id = ? AND question_ok = ? AND is_voice = ? AND is_lowered = ?", userId, documentId, false, true, false).Error; delete_question_err != nil {
+		fmt.Printf("Log: delete失敗(質問が存在しないか，削除に失敗しました): %s, %d, %t, %t in exitMeeting\n", userId, documentId, false, true)
+	} else {
+		fmt.Printf("Log: delete成功(質問の削除に成功しました): %s, %d, %t, %t in exitMeeting\n", userId, documentId, false, true)
+	}
+
+	fmt.Printf("Log: exit成功: %s, %d in exitMeeting\n", userId, meetingId)
+	return true
+}
+
+// documentRegister は資料URLと原稿を登録する
+// 原稿は全体で1つの文字列(script)か，ページ毎の原稿(segments)のどちらかで登録する
+// ページ毎の原稿を登録した場合は，従来の原稿にもページ順に連結したものを登録する
+// 登録後に新しいバージョンを作り，会議IDとバージョンを返す
+func documentRegister(db *gorm.DB, documentId int, documentUrl string, script string, segments []ScriptSegment) (bool, int, int) {
+	defer observeDBQuery("documentRegister", time.Now())
+
+	var document Document
+	if err := db.First(&document, "document_id = ?", documentId).Error; err != nil {
+		fmt.Printf("Error: 資料が非存在: %d in documentRegister\n", documentId)
+		return false, -1, -1
+	}
+	// 何も変更しないうちに原稿のページを確かめる
+	if len(segments) != 0 {
+		for _, segment := range segments {
+			if !validDocumentPage(db, document.DocumentId, segment.DocumentPage) {
+				fmt.Printf("Error: 存在しないページの原稿です: %d, %d in documentRegister\n", document.DocumentId, segment.DocumentPage)
+				return false, -1, -1
+			}
+		}
+		sort.Sort(ByScriptSegmentPage(segments))
+		scripts := make([]string, 0, len(segments))
+		for _, segment := range segments {
+			scripts = append(scripts, segment.Script)
+		}
+		script = strings.Join(scripts, "\n\n")
+	}
+
+	var version int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if documentUrl != "" {
+			if err := tx.Model(&document).Where("document_id = ?", document.DocumentId).Update("document_url", documentUrl).Error; err != nil {
+				return err
+			}
+		}
+		if script != "" {
+			if err := tx.Model(&document).Where("document_id = ?", document.DocumentId).Update("script", script).Error; err != nil {
+				return err
+			}
+			// 全体の原稿を登録し直した場合，以前のページ毎の原稿は使わない
+			if err := tx.Delete(ScriptSegment{}, "document_id = ?", document.DocumentId).Error; err != nil {
+				return err
+			}
+			for _, segment := range segments {
+				segment.DocumentId = document.DocumentId
+				if err := tx.Create(&segment).Error; err != nil {
+					return err
+				}
+			}
+		}
+		ok, created := createDocumentVersion(tx, document.DocumentId)
+		if !ok {
+			return fmt.Errorf("資料のバージョンを作成できません: %d", document.DocumentId)
+		}
+		version = created
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error: update失敗(資料URLと原稿の登録に失敗しました): %d in documentRegister\n", document.DocumentId)
+		return false, -1, -1
+	}
+	fmt.Printf("Log: update成功(資料URLと原稿の登録に成功しました): %d, %dページ分 in documentRegister\n", document.DocumentId, len(segments))
+	return true, document.MeetingId, version
+}
+
+// createQuestion は質問を登録し，質問ID，資料のバージョン，公開状態を返す
+// バージョンが指定されていない場合は現在のバージョンへの質問とする
+func createQuestion(db *gorm.DB, question Question) (bool, int, int, string) {
+	defer observeDBQuery("createQuestion", time.Now())
+
+	if question.DocumentVersion == 0 {
+		if !validDocumentPage(db, question.DocumentId, question.DocumentPage) {
+			fmt.Printf("Error: 存在しないページへの質問です: %d, %d in createQuestion\n", question.DocumentId, question.DocumentPage)
+			return false, -1, -1, ""
+		}
+		question.DocumentVersion = getDocumentVersion(db, question.DocumentId)
+	} else if !validVersionPage(db, question.DocumentId, question.DocumentVersion, question.DocumentPage) {
+		fmt.Printf("Error: 存在しないバージョン・ページへの質問です: %d, %d, %d in createQuestion\n", question.DocumentId, question.DocumentVersion, question.DocumentPage)
+		return false, -1, -1, ""
+	}
+	question.ModerationStatus = questionApproved
+	if isPreModeration(db, question.DocumentId) {
+		question.ModerationStatus = questionPending
+	}
+	if err := db.Create(&question).Error; err != nil {
+		fmt.Printf("Error: create失敗(質問の登録に失敗しました): %s, %d, %s in createQuestion\n", question.UserId, question.DocumentId, question.QuestionTime)
+		return false, -1, -1, ""
+	}
+	fmt.Printf("Log: create成功(質問の登録に成功しました): %s, %d, %s in createQuestion\n", question.UserId, question.DocumentId, question.QuestionTime)
+	return true, question.QuestionId, question.DocumentVersion, question.ModerationStatus
+}
+
+func selectQuestion(db *gorm.DB, meetingId, documentId int, presenterId string, questionUserId string) (bool, bool, string, int) {
+	defer observeDBQuery("selectQuestion", time.Now())
+
+	pickQuestioner := true
+	suggestQuestion := false
+	var question Question
+	var participant Participant
+	nextQuestionUserId := ""
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	documentVersion := getDocumentVersion(db, documentId)
+
+	// 挙手は挙手した順(ホストが並べ替えた順)に当てる
+	if voice_question_err := db.Order("hand_order, question_id").First(&question, "document_id = ? AND question_ok = ? AND is_voice = ? AND is_nominated = ? AND is_lowered = ?", documentId, false, true, false, false).Error; voice_question_err == nil {
+		if question_err := db.Model(&question).Where("question_id = ?", question.QuestionId).Update("question_ok", true).Error; question_err != nil {
+			fmt.Printf("Error: update失敗(質問の回答状況の更新に失敗しました): %d in selectQuestion\n", question.QuestionId)
+			return false, false, "", -1
+		}
+		if incSpeakNum_err := db.Model(&participant).Where("meeting_id = ? AND user_id = ?", meetingId, question.UserId).Update("speak_num", participant.SpeakNum+1).Error; incSpeakNum_err != nil {
+			fmt.Printf("Error: update失敗(参加者の話数の更新に失敗しました): %s, %d, %d in selectQuestion\n", participant.UserId, participant.MeetingId, participant.SpeakNum)
+			return false, false, "", -1
+		}
+		nextQuestionUserId = question.UserId
+		return pickQuestioner, suggestQuestion, nextQuestionUserId, question.QuestionId
+	} else {
+		questions := make([]Question, 0, 10)
+		if db.Find(&questions, "document_id = ? AND question_ok = ? AND is_voice = ? AND moderation_status = ?", documentId, false, false, questionApproved); len(questions) != 0 {
+			sort.Sort(ReverseByVoteNum(questions))
+			question = questions[0]
+			if question_err := db.Model(&question).Where("question_id = ?", question.QuestionId).Update("question_ok", true).Error; question_err != nil {
+				fmt.Printf("Error: update失敗(質問の回答状況の更新に失敗しました): %d in selectQuestion\n", question.QuestionId)
+				return false, false, "", -1
+			}
+			pickQuestioner = false
+			if incSpeakNum_err := db.Model(&participant).Where("meeting_id = ? AND user_id = ?", meetingId, question.UserId).Update("speak_num", participant.SpeakNum+1).Error; incSpeakNum_err != nil {
+				fmt.Printf("Error: update失敗(参加者の話数の更新に失敗しました): %s, %d, %d in selectQuestion\n", participant.UserId, participant.MeetingId, participant.SpeakNum)
+				return false, false, "", -1
+			}
+		}
+	}
+	if pickQuestioner {
+		participants := make([]Participant, 0, 10)
+		if db.Find(&participants, "meeting_id = ? AND user_id != ? AND user_id != ? AND is_joining = ?", meetingId, presenterId, questionUserId, true); len(participants) != 0 {
+			reactions := make([]Reaction, 0, 10)
+			if db.Find(&reactions, "document_id = ? AND document_version = ? AND suggestion_ok = ?", documentId, documentVersion, false); len(reactions) != 0 {
+				sort.Sort(ReverseByReactionNum(reactions))
+				if reactions[0].ReactionNum >= len(participants)/2 {
+					if reaction_err := db.Model(&reactions[0]).Where("document_id = ? AND document_page = ? AND document_version = ?", reactions[0].DocumentId, reactions[0].DocumentPage, reactions[0].DocumentVersion).Update("suggestion_ok", true).Error; reaction_err != nil {
+						fmt.Printf("Error: update失敗(資料リアクションの提案状況の更新に失敗しました): %d, %d in selectQuestion\n", reactions[0].DocumentId, reactions[0].DocumentPage)
+						return false, false, "", -1
+					}
+					question = Question{
+						UserId:       moderatorUserId,
+						QuestionBody: fmt.Sprintf("%dページについての詳しい説明を要求．", reactions[0].DocumentPage),
+						DocumentId:   reactions[0].DocumentId,
+						DocumentPage: reactions[0].DocumentPage,
+						VoteNum:      reactions[0].ReactionNum,
+						QuestionTime: time.Now().In(location),
+						QuestionOk:   true,
+						IsVoice:      false,
+
+						DocumentVersion: documentVersion,
+					}
+					if err := db.Create(&question).Error; err != nil {
+						fmt.Printf("Error: create失敗(質問の登録に失敗しました): %s, %d, %s in selectQuestion\n", question.UserId, question.DocumentId, question.QuestionTime)
+						return false, false, "", -1
+					}
+					fmt.Printf("Log: create成功(質問の登録に成功しました): %s, %d, %s in selectQuestion\n", question.UserId, question.DocumentId, question.QuestionTime)
+					pickQuestioner = false
+					suggestQuestion = true
+					return pickQuestioner, suggestQuestion, nextQuestionUserId, question.QuestionId
+				}
+			}
+			sort.Sort(BySpeakNum(participants))
+			// rand_max := 3
+			// if len(participants) < 3 {
+			//	rand_max = len(participants)
+			// }
+			// participant = participants[rand.Intn(rand_max)]
+			participant = participants[0]
+			nextQuestionUserId = participant.UserId
+			question = Question{
+				UserId:       nextQuestionUserId,
+				QuestionBody: "",
+				DocumentId:   documentId,
+				DocumentPage: getCurrentPage(db, documentId),
+				VoteNum:      0,
+				QuestionTime: time.Now().In(location),
+				QuestionOk:   true,
+				IsVoice:      true,
+
+				DocumentVersion: documentVersion,
+				IsNominated:     true,
+			}
+			if err := db.Create(&question).Error; err != nil {
+				fmt.Printf("Error: create失敗(質問の登録に失敗しました): %s, %d, %s in selectQuestion\n", question.UserId, question.DocumentId, question.QuestionTime)
+				return false, false, "", -1
+			}
+			if err := db.Model(&participant).Where("user_id = ? AND meeting_id = ?", participant.UserId, participant.MeetingId).Update("speak_num", participant.SpeakNum+1).Error; err != nil {
+				fmt.Printf("Error: update失敗(参加者の話数の更新に失敗しました): %s, %d, %d in selectQuestion\n", participant.UserId, participant.MeetingId, participant.SpeakNum)
+				return false, false, "", -1
+			}
+			fmt.Printf("Log: create成功(質問の登録に成功しました): %s, %d, %s in selectQuestion\n", question.UserId, question.DocumentId, question.QuestionTime)
+
+		} else {
+			fmt.Printf("Error: 参加者が非存在: %d in selectQuestion\n", meetingId)
+			return false, false, "", -1
+		}
+	}
+	return pickQuestioner, suggestQuestion, nextQuestionUserId, question.QuestionId
+}
+
+func voteQuestion(db *gorm.DB, questionId int, isVote bool) (int, int, int) {
+	defer observeDBQuery("voteQuestion", time.Now())
+
+	var question Question
+	var document Document
+	if err := db.First(&question, "question_id = ?", questionId).Error; err != nil {
+		fmt.Printf("Error: 質問が非存在: %d in voteQuestion\n", questionId)
+		return -1, -1, -1
+	}
+	// 統合された質問への投票は統合先に数える
+	if question.ModerationStatus == questionMerged && question.MergedInto != 0 {
+		questionId = question.MergedInto
+		if err := db.First(&question, "question_id = ?", questionId).Error; err != nil {
+			fmt.Printf("Error: 統合先の質問が非存在: %d in voteQuestion\n", questionId)
+			return -1, -1, -1
+		}
+	}
+	if question.ModerationStatus != questionApproved {
+		fmt.Printf("Error: 公開されていない質問です: %d in voteQuestion\n", questionId)
+		return -1, -1, -1
+	}
+	voteNum := question.VoteNum
+	if isVote {
+		voteNum += 1
+	} else {
+		voteNum -= 1
+	}
+	if err := db.Model(&question).Where("question_id = ?", questionId).Update("vote_num", voteNum).Error; err != nil {
+		fmt.Printf("Error: update失敗(質問の投票数の更新に失敗しました): %d, %d in voteQuestion\n", questionId, voteNum)
+		return -1, -1, -1
+	}
+
+	if err := db.First(&document, "document_id = ?", question.DocumentId).Error; err != nil {
+		fmt.Printf("Error: 資料が非存在: %d in voteQuestion\n", question.DocumentId)
+		return -1, -1, -1
+	}
+
+	return document.MeetingId, questionId, voteNum
+}
+
+func handsUp(db *gorm.DB, userId string, documentId int, documentPage int) int {
+	defer observeDBQuery("handsUp", time.Now())
+
+	var document Document
+	location, _ := time.LoadLocation("Asia/Tokyo")
+
+	if document_err := db.First(&document, "document_id = ?", documentId).Error; document_err != nil {
+		fmt.Printf("Error: 資料が非存在: %d in handsUp\n", documentId)
+		return -1
+	}
+
+	if user_err := db.First(&User{}, "user_id = ?", userId).Error; user_err != nil {
+		fmt.Printf("Error: ユーザーが非存在: %s in handsUp\n", userId)
+		return -1
+	}
+
+	if !validDocumentPage(db, document.DocumentId, documentPage) {
+		fmt.Printf("Error: 存在しないページです: %d, %d in handsUp\n", document.DocumentId, documentPage)
+		return -1
+	}
+
+	// 既に挙手している場合は順番を変えない
+	queue := getHandsQueue(db, document.DocumentId)
+	for _, hand := range queue {
+		if hand.UserId == userId {
+			fmt.Printf("Log: 既に挙手しています: %s, %d in handsUp\n", userId, document.DocumentId)
+			return document.MeetingId
+		}
+	}
+	handOrder := 1
+	if len(queue) != 0 {
+		handOrder = queue[len(queue)-1].HandOrder + 1
+	}
+
+	question := Question{
+		UserId:       userId,
+		QuestionBody: "",
+		DocumentId:   document.DocumentId,
+		DocumentPage: documentPage,
+		VoteNum:      0,
+		QuestionTime: time.Now().In(location),
+		QuestionOk:   false,
+		IsVoice:      true,
+
+		DocumentVersion: document.Version,
+		HandOrder:       handOrder,
+	}
+	if question_err := db.Create(&question).Error; question_err != nil {
+		fmt.Printf("Error: create失敗(質問の登録に失敗しました): %s, %d, %d, %s in handsUp\n", question.UserId, question.DocumentId, question.DocumentPage, question.QuestionTime)
+		return -1
+	}
+	fmt.Printf("Log: create成功(質問の登録に成功しました): %s, %d, %d, %s in handsUp\n", question.UserId, question.DocumentId, question.DocumentPage, question.QuestionTime)
+	return document.MeetingId
+}
+
+// handsDown は参加者が自分の挙手を取り下げる
+// 挙手は発表者の枠(資料)毎に1つのため，documentPageは使わない (ログのみ)
+func handsDown(db *gorm.DB, userId string, documentId int, documentPage int) int {
+	defer observeDBQuery("handsDown", time.Now())
+
+	var (
+		document Document
+		question Question
+	)
+
+	if document_err := db.First(&document, "document_id = ?", documentId).Error; document_err != nil {
+		fmt.Printf("Error: 資料が非存在: %d in handsDown\n", documentId)
+		return -1
+	}
+	if user_err := db.First(&User{}, "user_id = ?", userId).Error; user_err != nil {
+		fmt.Printf("Error: ユーザーが非存在: %s in handsDown\n", userId)
+		return -1
+	}
+	// 挙手は発表者の枠毎に1つのため，挙手したページに関係なく下げる
+	if question_err := db.First(&question, "user_id = ? AND document_id = ? AND question_ok = ? AND is_voice = ? AND is_nominated = ? AND is_lowered = ?", userId, document.DocumentId, false, true, false, false).Error; question_err != nil {
+		fmt.Printf("Error: 質問が非存在: %s, %d, %d in handsDown\n", userId, document.DocumentId, documentPage)
+		return -1
+	}
+	if delete_question_err := db.Where("question_id = ?", question.QuestionId).Delete(&question).Error; delete_question_err != nil {
+		fmt.Printf("Error: delete失敗(質問の削除に失敗しました): %d in handsDown\n", question.QuestionId)
+		return -1
+	}
+	fmt.Printf("Log: delete成功(質問の削除に成功しました): %d in handsDown\n", question.QuestionId)
+	return document.MeetingId
+}
+
+// getHandsQueue は資料(発表者の枠)への挙手を当てる順に返す
+func getHandsQueue(db *gorm.DB, documentId int) []Question {
+	defer observeDBQuery("getHandsQueue", time.Now())
+
+	hands := make([]Question, 0, 10)
+	if err := db.Order("hand_order, question_id").Find(&hands, "document_id = ? AND question_ok = ? AND is_voice = ? AND is_nominated = ? AND is_lowered = ?", documentId, false, true, false, false).Error; err != nil {
+		fmt.Printf("Error: 挙手の取得に失敗しました: %d in getHandsQueue\n", documentId)
+		return []Question{}
+	}
+	return hands
+}
+
+// moveHand は挙手の順番をposition(1から挙手の数まで)に変える
+func moveHand(db *gorm.DB, documentId int, userId string, position int) bool {
+	defer observeDBQuery("moveHand", time.Now())
+
+	queue := getHandsQueue(db, documentId)
+	index := -1
+	for i, hand := range queue {
+		if hand.UserId == userId {
+			index = i
+		}
+	}
+	if index == -1 {
+		fmt.Printf("Error: 挙手が非存在: %s, %d in moveHand\n", userId, documentId)
+		return false
+	}
+	if position < 1 || position > len(queue) {
+		fmt.Printf("Error: 挙手の順番が範囲外です: %d, %d in moveHand\n", documentId, position)
+		return false
+	}
+	hand := queue[index]
+	queue = append(queue[:index], queue[index+1:]...)
+	queue = append(queue[:position-1], append([]Question{hand}, queue[position-1:]...)...)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i, q := range queue {
+			if err := tx.Model(&Question{}).Where("question_id = ?", q.QuestionId).Update("hand_order", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error: update失敗(挙手の順番の更新に失敗しました): %s, %d, %v in moveHand\n", userId, documentId, err)
+		return false
+	}
+	fmt.Printf("Log: update成功(挙手の順番を変更しました): %s, %d, %d in moveHand\n", userId, documentId, position)
+	return true
+}
+
+// callHand は挙手した参加者を順番に関係なく当て，その挙手の質問IDを返す
+func callHand(db *gorm.DB, meetingId int, documentId int, userId string) (bool, int) {
+	defer observeDBQuery("callHand", time.Now())
+
+	var hand Question
+	if err := db.First(&hand, "user_id = ? AND document_id = ? AND question_ok = ? AND is_voice = ? AND is_nominated = ? AND is_lowered = ?", userId, documentId, false, true, false, false).Error; err != nil {
+		fmt.Printf("Error: 挙手が非存在: %s, %d in callHand\n", userId, documentId)
+		return false, -1
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Question{}).Where("question_id = ?", hand.QuestionId).Update("question_ok", true).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Participant{}).Where("meeting_id = ? AND user_id = ?", meetingId, userId).Update("speak_num", gorm.Expr("speak_num + 1")).Error
+	})
+	if err != nil {
+		fmt.Printf("Error: update失敗(挙手した参加者を当てられませんでした): %d, %v in callHand\n", hand.QuestionId, err)
+		return false, -1
+	}
+	fmt.Printf("Log: update成功(挙手した参加者を当てました): %s, %d in callHand\n", userId, hand.QuestionId)
+	return true, hand.QuestionId
+}
+
+// lowerHands は資料へのuserIdsの挙手を下げる (userIdsを省略すると全て．発表者が交代した場合)
+// 報告書に残すため，削除せずに下げたことを記録する
+func lowerHands(db *gorm.DB, documentId int, userIds ...string) bool {
+	defer observeDBQuery("lowerHands", time.Now())
+
+	query := db.Model(&Question{}).Where("document_id = ? AND question_ok = ? AND is_voice = ? AND is_nominated = ? AND is_lowered = ?", documentId, false, true, false, false)
+	if len(userIds) > 0 {
+		query = query.Where("user_id IN (?)", userIds)
+	}
+	result := query.Update("is_lowered", true)
+	if result.Error != nil {
+		fmt.Printf("Error: update失敗(挙手を下げられませんでした): %d, %s in lowerHands\n", documentId, userIds)
+		return false
+	}
+	if len(userIds) > 0 && result.RowsAffected == 0 {
+		fmt.Printf("Error: 挙手が非存在: %d, %s in lowerHands\n", documentId, userIds)
+		return false
+	}
+	fmt.Printf("Log: update成功(挙手を下げました): %d, %s in lowerHands\n", documentId, userIds)
+	return true
+}
+
+// voteReaction は資料の現在のバージョンのページへのリアクションを増減し，
+// 会議ID，リアクション数，資料のバージョンを返す
+func voteReaction(db *gorm.DB, documentId int, documentPage int, isReaction bool) (int, int, int) {
+	defer observeDBQuery("voteReaction", time.Now())
+
+	var document Document
+	var reaction Reaction
+
+	if document_err := db.First(&document, "document_id = ?", documentId).Error; document_err != nil {
+		fmt.Printf("Error: 資料が非存在: %d in voteReaction\n", documentId)
+		return -1, -1, -1
+	}
+
+	if !validDocumentPage(db, document.DocumentId, documentPage) {
+		fmt.Printf("Error: 存在しないページです: %d, %d in voteReaction\n", document.DocumentId, documentPage)
+		return -1, -1, -1
+	}
+
+	if reaction_err := db.First(&reaction, "document_id = ? AND document_page = ? AND document_version = ?", documentId, documentPage, document.Version).Error; reaction_err != nil {
+		if !isReaction {
+			fmt.Printf("Error: 資料リアクションが非存在: %d, %d in voteReaction\n", documentId, documentPage)
+			return -1, -1, -1
+		}
+		reaction = Reaction{
+			DocumentId:   document.DocumentId,
+			DocumentPage: documentPage,
+			ReactionNum:  1,
+			SuggestionOk: false,
+
+			DocumentVersion: document.Version,
+		}
+		if create_reaction_err := db.Create(&reaction).Error; create_reaction_err != nil {
+			fmt.Printf("Error: create失敗(資料リアクションの登録に失敗しました): %d, %d in voteReaction\n", reaction.DocumentId, reaction.DocumentPage)
+			return -1, -1, -1
+		}
+		fmt.Printf("Log: create成功(資料リアクションの登録に成功しました): %d, %d in voteReaction\n", reaction.DocumentId, reaction.DocumentPage)
+	} else {
+		reactionNum := reaction.ReactionNum
+		if isReaction {
+			reactionNum += 1
+		} else {
+			reactionNum -= 1
+		}
+		if update_reaction_num_err := db.Model(&reaction).Where("document_id = ? AND document_page = ? AND document_version = ?", reaction.DocumentId, reaction.DocumentPage, reaction.DocumentVersion).Update("reaction_num", reactionNum).Error; update_reaction_num_err != nil {
+			fmt.Printf("Error: update失敗(資料リアクションのリアクション数の更新に失敗しました): %d, %d in voteReaction\n", reaction.DocumentId, reaction.DocumentPage)
+			return -1, -1, -1
+		}
+		fmt.Printf("Log: update成功(資料リアクションのリアクション数の更新に成功しました): %d, %d in voteReaction\n", reaction.DocumentId, reaction.DocumentPage)
+	}
+	return document.MeetingId, reaction.ReactionNum, document.Version
+}
+
+func getNextPresenterId(db *gorm.DB, meetingId int, nowPresenterId string) (bool, string, int) {
+	defer observeDBQuery("getNextPresenterId", time.Now())
+
+	var participant Participant
+	if participant_err := db.First(&participant, "meeting_id = ? AND user_id = ?", meetingId, nowPresenterId).Error; participant_err != nil {
+		fmt.Printf("Error: 参加者が非存在: %s in getNextPresenterId\n", nowPresenterId)
+		return false, "", -1
+	}
+	nextOrder := participant.ParticipantOrder + 1
+	if meeting_end_err := db.First(&participant, "meeting_id = ? AND participant_order = ?", meetingId, nextOrder).Error; meeting_end_err != nil {
+		fmt.Printf("Log: 会議終了につき次の発表者が非存在: %d in getNextPresenterId\n", nextOrder)
+		return true, "", -1
+	}
+	return false, participant.UserId, nextOrder
+}
+
+// currentPresenterId は会議で発表の順番が来ている発表者を返す (会議が終わっていれば空)
+// 発表中であればその発表者，発表を終えていれば次の発表者，まだ誰も発表していなければ最初の発表者とする
+func currentPresenterId(db *gorm.DB, meetingId int) string {
+	defer observeDBQuery("currentPresenterId", time.Now())
+
+	var meeting Meeting
+	if err := db.First(&meeting, "meeting_id = ?", meetingId).Error; err != nil || meeting.MeetingEndTime != nil {
+		return ""
+	}
+	if found, state := getModeratorState(db, meetingId); found && state.PresenterId != "" {
+		if state.PresenterStartedAt != nil {
+			return state.PresenterId
+		}
+		if end, nextUserId, _ := getNextPresenterId(db, meetingId, state.PresenterId); !end {
+			return nextUserId
+		}
+		return ""
+	}
+	var participant Participant
+	if err := db.Order("participant_order").First(&participant, "meeting_id = ? AND participant_order != -1", meetingId).Error; err != nil {
+		fmt.Printf("Error: 発表者が非存在: %d in currentPresenterId\n", meetingId)
+		return ""
+	}
+	return participant.UserId
+}
+
+func getUserName(db *gorm.DB, userId string) string {
+	defer observeDBQuery("getUserName", time.Now())
+
+	var user User
+	if err := db.First(&user, "user_id = ?", userId).Error; err != nil {
+		fmt.Printf("Error: ユーザーが非存在: %s in getUserName\n", userId)
+		return ""
+	}
+	return user.UserName
+}
+
+func getFirstPresenUserName(db *gorm.DB, meetingId int) string {
+	defer observeDBQuery("getFirstPresenUserName", time.Now())
+
+	participants := make([]Participant, 0, 10)
+	if db.Find(&participants, "meeting_id = ? AND participant_order != -1", meetingId); len(participants) == 0 {
+		fmt.Printf("Error: 会議非存在: %d in getFirstPresenUserName\n", meetingId)
+		return ""
+	}
+
+	sort.Sort(ByParticipantOrder(participants))
+
+	userName := getUserName(db, participants[0].UserId)
+
+	return userName
+}
+
+func getQuestionBody(db *gorm.DB, questionId int) (string, int) {
+	defer observeDBQuery("getQuestionBody", time.Now())
+
+	var question Question
+	if err := db.First(&question, "question_id = ?", questionId).Error; err != nil {
+		fmt.Printf("Error: 質問が非存在: %d in getQuestionBody\n", questionId)
+		return "", -1
+	}
+	return question.QuestionBody, question.DocumentPage
+}
+
+func getQuestionDocumentPage(db *gorm.DB, questionId int) int {
+	defer observeDBQuery("getQuestionDocumentPage", time.Now())
+
+	var question Question
+	if err := db.First(&question, "question_id = ?", questionId).Error; err != nil {
+		fmt.Printf("Error: 質問が非存在: %d in getQuestionDocumentPage\n", questionId)
+		return -1
+	}
+	return question.DocumentPage
+}
+
+func getDocumentId(db *gorm.DB, userId string, meetingId int) int {
+	defer observeDBQuery("getDocumentId", time.Now())
+
+	var document Document
+	if err := db.First(&document, "user_id = ? AND meeting_id = ?", userId, meetingId).Error; err != nil {
+		fmt.Printf("Error: 資料が非存在: %s, %d in getDocumentId\n", userId, meetingId)
+		return -1
+	}
+	return document.DocumentId
+}
+
+func documentGet(db *gorm.DB, documentId int) (bool, string, string, []ScriptSegment) {
+	defer observeDBQuery("documentGet", time.Now())
+
+	var (
+		document    Document
+		documentUrl *string
+		script      *string
+		emptyString = ""
+	)
+
+	if err := db.First(&document, "document_id = ?", documentId).Error; err != nil {
+		fmt.Printf("Error: 資料が非存在: %d in documentGet\n", documentId)
+		return false, "", "", []ScriptSegment{}
+	}
+	if documentUrl = document.DocumentUrl; documentUrl == nil {
+		fmt.Printf("Log: 資料URLが非存在: %d in documentGet\n", documentId)
+		documentUrl = &emptyString
+	}
+	if script = document.Script; script == nil {
+		fmt.Printf("Log: 原稿が非存在: %d in documentGet\n", documentId)
+		script = &emptyString
+	}
+	segments := make([]ScriptSegment, 0, document.PageCount)
+	if err := db.Order("document_page").Find(&segments, "document_id = ?", documentId).Error; err != nil {
+		fmt.Printf("Error: ページ毎の原稿の取得に失敗しました: %d in documentGet\n", documentId)
+		return false, "", "", []ScriptSegment{}
+	}
+	return true, *documentUrl, *script, segments
+}
+
+// questionsGet は会議の公開中の質問を返す
+// 質問者は会議の匿名性の設定で見せられる場合のみ返す
+func questionsGet(db *gorm.DB, meetingId int) (bool, int, []int, []string, []int, []int, []string, []string, []int, []string) {
+	defer observeDBQuery("questionsGet", time.Now())
+
+	var (
+		layout        = "2006/01/02 15:04:05"
+		location, _   = time.LoadLocation("Asia/Tokyo")
+		questions     = make([]QuestionAndPresenterId, 0, 10)
+		questionIds   = make([]int, 0, 10)
+		questionBodys = make([]string, 0, 10)
+		documentIds   = make([]int, 0, 10)
+		documentPages = make([]int, 0, 10)
+		questionTimes = make([]string, 0, 10)
+		presenterIds  = make([]string, 0, 10)
+		voteNums      = make([]int, 0, 10)
+		askerIds      = make([]string, 0, 10)
+		_, meeting    = getMeeting(db, meetingId)
+	)
+	if db.Table("documents").Select("questions.question_id, questions.question_body, questions.document_id, questions.document_page, questions.question_time, documents.user_id, questions.vote_num, questions.user_id AS asker_id, questions.is_voice").Where("documents.meeting_id = ? AND questions.moderation_status = ?", meetingId, questionApproved).Joins("JOIN questions ON documents.document_id = questions.document_id").Scan(&questions); len(questions) == 0 {
+		fmt.Printf("Log: 質問が非存在: %d in questionsGet\n", meetingId)
+		return false, meetingId, []int{}, []string{}, []int{}, []int{}, []string{}, []string{}, []int{}, []string{}
+	}
+	for _, q := range questions {
+		questionIds = append(questionIds, q.QuestionId)
+		questionBodys = append(questionBodys, q.QuestionBody)
+		documentIds = append(documentIds, q.DocumentId)
+		documentPages = append(documentPages, q.DocumentPage)
+		questionTimes = append(questionTimes, q.QuestionTime.In(location).Format(layout))
+		presenterIds = append(presenterIds, q.UserId)
+		voteNums = append(voteNums, q.VoteNum)
+		askerIds = append(askerIds, visibleAskerId(meeting.AnonymityLevel, false, Question{UserId: q.AskerId, IsVoice: q.IsVoice}))
+	}
+
+	return true, meetingId, questionIds, questionBodys, documentIds, documentPages, questionTimes, presenterIds, voteNums, askerIds
+}
+
+// QuestionFilter は会議の質問の一覧の絞り込み・並び順・ページ送りの条件
+type QuestionFilter struct {
+	MeetingId    int
+	DocumentId   int   // 0 は全ての資料
+	DocumentPage int   // 0 は全てのページ
+	Answered     *bool // nil は回答済み・未回答の両方
+	IsVoice      *bool // nil は挙手・文章の質問の両方
+	AskerId      string
+	SortByVotes  bool // falseは古い順，trueは投票数の多い順 (同数は古い順)
+	Limit        int
+
+	// 前のページの最後の質問 (最初のページはAfterIdが0)
+	AfterId      int
+	AfterTime    time.Time
+	AfterVoteNum int
+}
+
+// findQuestions は会議の公開中の質問をfilterで絞り込み，Limit件まで返す
+func findQuestions(db *gorm.DB, filter QuestionFilter) ([]Question, bool) {
+	defer observeDBQuery("findQuestions", time.Now())
+
+	query := db.Joins("JOIN documents ON documents.document_id = questions.document_id").Where("documents.meeting_id = ? AND questions.moderation_status = ?", filter.MeetingId, questionApproved)
+	if filter.DocumentId != 0 {
+		query = query.Where("questions.document_id = ?", filter.DocumentId)
+	}
+	if filter.DocumentPage != 0 {
+		query = query.Where("questions.document_page = ?", filter.DocumentPage)
+	}
+	if filter.Answered != nil {
+		query = query.Where("questions.question_ok = ?", *filter.Answered)
+	}
+	if filter.IsVoice != nil {
+		query = query.Where("questions.is_voice = ?", *filter.IsVoice)
+	}
+	if filter.AskerId != "" {
+		query = query.Where("questions.user_id = ?", filter.AskerId)
+	}
+	if filter.SortByVotes {
+		if filter.AfterId != 0 {
+			query = query.Where("questions.vote_num < ? OR (questions.vote_num = ? AND questions.question_id > ?)", filter.AfterVoteNum, filter.AfterVoteNum, filter.AfterId)
+		}
+		query = query.Order("questions.vote_num DESC").Order("questions.question_id")
+	} else {
+		if filter.AfterId != 0 {
+			query = query.Where("questions.question_time > ? OR (questions.question_time = ? AND questions.question_id > ?)", filter.AfterTime, filter.AfterTime, filter.AfterId)
+		}
+		query = query.Order("questions.question_time").Order("questions.question_id")
+	}
+
+	questions := make([]Question, 0, filter.Limit)
+	if err := query.Limit(filter.Limit).Find(&questions).Error; err != nil {
+		fmt.Printf("Error: 質問の取得に失敗しました: %d in findQuestions\n", filter.MeetingId)
+		return []Question{}, false
+	}
+	return questions, true
+}
+
+func getPresenterId(db *gorm.DB, documentId int) string {
+	defer observeDBQuery("getPresenterId", time.Now())
+
+	var document Document
+	if err := db.First(&document, "document_id = ?", documentId).Error; err != nil {
+		fmt.Printf("Error: 資料が非存在: %d in getPresenterId\n", documentId)
+		return ""
+	}
+	return document.UserId
+}
+
+func setMeetingDone(db *gorm.DB, meetingId int) {
+	defer observeDBQuery("setMeetingDone", time.Now())
+
+	var meeting Meeting
+	db.First(&meeting, "meeting_id = ?", meetingId)
+	db.Model(&meeting).Where("meeting_id = ?", meetingId).Update("meeting_done", true)
+}
+
+func pingDB(db *gorm.DB, ctx context.Context) error {
+	defer observeDBQuery("pingDB", time.Now())
+
+	return db.DB().PingContext(ctx)
+}
+
+// 開始通知を予約すべき(まだ開始していない)会議を取得する
+func getPendingMeetings(db *gorm.DB, since time.Time) []Meeting {
+	defer observeDBQuery("getPendingMeetings", time.Now())
+
+	meetings := make([]Meeting, 0, 10)
+	if err := db.Find(&meetings, "meeting_done = ? AND meeting_start_time >= ?", false, since).Error; err != nil {
+		fmt.Printf("Error: 未開始の会議の取得に失敗しました in getPendingMeetings\n")
+		return []Meeting{}
+	}
+	return meetings
+}
+
+// isHost はuserIdが会議のホストかを返す
+// ホストが登録されていない会議では発表者をホストとして扱う
+func isHost(db *gorm.DB, meetingId int, userId string) bool {
+	defer observeDBQuery("isHost", time.Now())
+
+	var meeting Meeting
+	if err := db.First(&meeting, "meeting_id = ?", meetingId).Error; err != nil {
+		fmt.Printf("Error: 会議が非存在: %d in isHost\n", meetingId)
+		return false
+	}
+	if meeting.HostUserId != "" {
+		return meeting.HostUserId == userId
+	}
+	var participant Participant
+	if err := db.First(&participant, "meeting_id = ? AND user_id = ? AND participant_order != ?", meetingId, userId, -1).Error; err != nil {
+		return false
+	}
+	return true
+}
+
+func getDocument(db *gorm.DB, documentId int) (bool, Document) {
+	defer observeDBQuery("getDocument", time.Now())
+
+	var document Document
+	if err := db.First(&document, "document_id = ?", documentId).Error; err != nil {
+		fmt.Printf("Error: 資料が非存在: %d in getDocument\n", documentId)
+		return false, Document{}
+	}
+	return true, document
+}
+
+// setDocumentFile はアップロードされたファイルのキーと配信URLを資料に登録する
+func setDocumentFile(db *gorm.DB, documentId int, fileKey string, documentUrl string) bool {
+	defer observeDBQuery("setDocumentFile", time.Now())
+
+	if err := db.Model(&Document{}).Where("document_id = ?", documentId).Updates(map[string]interface{}{"file_key": fileKey, "document_url": documentUrl}).Error; err != nil {
+		fmt.Printf("Error: update失敗(資料ファイルの登録に失敗しました): %d in setDocumentFile\n", documentId)
+		return false
+	}
+	fmt.Printf("Log: update成功(資料ファイルの登録に成功しました): %d in setDocumentFile\n", documentId)
+	return true
+}
+
+// isMeetingMember はuserIdが会議の参加者(発表者・ホストを含む)かを返す
+func isMeetingMember(db *gorm.DB, meetingId int, userId string) bool {
+	defer observeDBQuery("isMeetingMember", time.Now())
+
+	if userId == "" {
+		return false
+	}
+	if err := db.First(&Participant{}, "meeting_id = ? AND user_id = ?", meetingId, userId).Error; err == nil {
+		return true
+	}
+	var meeting Meeting
+	if err := db.First(&meeting, "meeting_id = ?", meetingId).Error; err != nil {
+		return false
+	}
+	return meeting.HostUserId == userId
+}
+
+// setDocumentPages はアップロードされたPDFのページ情報で置き換える
+func setDocumentPages(db *gorm.DB, documentId int, pages []Page) bool {
+	defer observeDBQuery("setDocumentPages", time.Now())
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(Page{}, "document_id = ?", documentId).Error; err != nil {
+			return err
+		}
+		for _, page := range pages {
+			if err := tx.Create(&page).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&Document{}).Where("document_id = ?", documentId).Update("page_count", len(pages)).Error
+	})
+	if err != nil {
+		fmt.Printf("Error: update失敗(資料のページ情報の登録に失敗しました): %d in setDocumentPages\n", documentId)
+		return false
+	}
+	fmt.Printf("Log: update成功(資料のページ情報の登録に成功しました): %d, %dページ in setDocumentPages\n", documentId, len(pages))
+	return true
+}
+
+func getDocumentPages(db *gorm.DB, documentId int) []Page {
+	defer observeDBQuery("getDocumentPages", time.Now())
+
+	pages := make([]Page, 0, 10)
+	if err := db.Order("document_page").Find(&pages, "document_id = ?", documentId).Error; err != nil {
+		fmt.Printf("Error: 資料のページ情報の取得に失敗しました: %d in getDocumentPages\n", documentId)
+		return []Page{}
+	}
+	return pages
+}
+
+func getDocumentPage(db *gorm.DB, documentId int, documentPage int) (bool, Page) {
+	defer observeDBQuery("getDocumentPage", time.Now())
+
+	var page Page
+	if err := db.First(&page, "document_id = ? AND document_page = ?", documentId, documentPage).Error; err != nil {
+		fmt.Printf("Error: ページが非存在: %d, %d in getDocumentPage\n", documentId, documentPage)
+		return false, Page{}
+	}
+	return true, page
+}
+
+// validDocumentPage はページ番号が資料の範囲内かを返す
+// PDFがアップロードされていない(ページ数が不明な)資料では検証しない
+func validDocumentPage(db *gorm.DB, documentId int, documentPage int) bool {
+	defer observeDBQuery("validDocumentPage", time.Now())
+
+	var document Document
+	if err := db.First(&document, "document_id = ?", documentId).Error; err != nil {
+		return false
+	}
+	if document.PageCount == 0 {
+		return true
+	}
+	return documentPage >= 1 && documentPage <= document.PageCount
+}
+
+// validVersionPage は資料の指定したバージョンが存在し，そのバージョンにページがあるかを返す
+func validVersionPage(db *gorm.DB, documentId int, version int, documentPage int) bool {
+	defer observeDBQuery("validVersionPage", time.Now())
+
+	found, snapshot := getDocumentVersionSnapshot(db, documentId, version)
+	if !found {
+		return false
+	}
+	if snapshot.PageCount == 0 {
+		return true
+	}
+	return documentPage >= 1 && documentPage <= snapshot.PageCount
+}
+
+func getDocumentVersion(db *gorm.DB, documentId int) int {
+	defer observeDBQuery("getDocumentVersion", time.Now())
+
+	var document Document
+	if err := db.First(&document, "document_id = ?", documentId).Error; err != nil {
+		fmt.Printf("Error: 資料が非存在: %d in getDocumentVersion\n", documentId)
+		return 0
+	}
+	return document.Version
+}
+
+// createDocumentVersion は資料の現在の状態をスナップショットとして保存し，
+// 新しいバージョン番号を返す
+func createDocumentVersion(db *gorm.DB, documentId int) (bool, int) {
+	defer observeDBQuery("createDocumentVersion", time.Now())
+
+	var version DocumentVersion
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var document Document
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&document, "document_id = ?", documentId).Error; err != nil {
+			return err
+		}
+		segments := make([]ScriptSegment, 0, document.PageCount)
+		if err := tx.Order("document_page").Find(&segments, "document_id = ?", documentId).Error; err != nil {
+			return err
+		}
+		segmentsJson, err := json.Marshal(segments)
+		if err != nil {
+			return err
+		}
+		version = DocumentVersion{
+			DocumentId:     documentId,
+			Version:        document.Version + 1,
+			DocumentUrl:    document.DocumentUrl,
+			Script:         document.Script,
+			ScriptSegments: string(segmentsJson),
+			FileKey:        document.FileKey,
+			PageCount:      document.PageCount,
+		}
+		if err := tx.Create(&version).Error; err != nil {
+			return err
+		}
+		return tx.Model(&document).Where("document_id = ?", documentId).Update("version", version.Version).Error
+	})
+	if err != nil {
+		fmt.Printf("Error: create失敗(資料のバージョンの作成に失敗しました): %d in createDocumentVersion\n", documentId)
+		return false, -1
+	}
+	fmt.Printf("Log: create成功(資料のバージョンの作成に成功しました): %d, %d in createDocumentVersion\n", documentId, version.Version)
+	return true, version.Version
+}
+
+// getDocumentVersions は資料の全てのバージョンを新しい順に返す
+func getDocumentVersions(db *gorm.DB, documentId int) []DocumentVersion {
+	defer observeDBQuery("getDocumentVersions", time.Now())
+
+	versions := make([]DocumentVersion, 0, 10)
+	if err := db.Order("version desc").Find(&versions, "document_id = ?", documentId).Error; err != nil {
+		fmt.Printf("Error: 資料のバージョンの取得に失敗しました: %d in getDocumentVersions\n", documentId)
+		return []DocumentVersion{}
+	}
+	return versions
+}
+
+func getDocumentVersionSnapshot(db *gorm.DB, documentId int, version int) (bool, DocumentVersion) {
+	defer observeDBQuery("getDocumentVersionSnapshot", time.Now())
+
+	var snapshot DocumentVersion
+	if err := db.First(&snapshot, "document_id = ? AND version = ?", documentId, version).Error; err != nil {
+		fmt.Printf("Error: 資料のバージョンが非存在: %d, %d in getDocumentVersionSnapshot\n", documentId, version)
+		return false, DocumentVersion{}
+	}
+	return true, snapshot
+}
+
+// restoreDocumentVersion は資料を以前のバージョンの内容に戻す
+// ページ情報は呼び出し側でPDFから作り直す
+func restoreDocumentVersion(db *gorm.DB, snapshot DocumentVersion) bool {
+	defer observeDBQuery("restoreDocumentVersion", time.Now())
+
+	segments := make([]ScriptSegment, 0, snapshot.PageCount)
+	if err := json.Unmarshal([]byte(snapshot.ScriptSegments), &segments); err != nil {
+		fmt.Printf("Error: ページ毎の原稿を読み込めません: %d, %d in restoreDocumentVersion\n", snapshot.DocumentId, snapshot.Version)
+		return false
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Document{}).Where("document_id = ?", snapshot.DocumentId).Updates(map[string]interface{}{
+			"document_url": snapshot.DocumentUrl,
+			"script":       snapshot.Script,
+			"file_key":     snapshot.FileKey,
+			"page_count":   snapshot.PageCount,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(ScriptSegment{}, "document_id = ?", snapshot.DocumentId).Error; err != nil {
+			return err
+		}
+		for _, segment := range segments {
+			if err := tx.Create(&segment).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error: update失敗(資料のバージョンの復元に失敗しました): %d, %d in restoreDocumentVersion\n", snapshot.DocumentId, snapshot.Version)
+		return false
+	}
+	fmt.Printf("Log: update成功(資料のバージョンの復元に成功しました): %d, %d in restoreDocumentVersion\n", snapshot.DocumentId, snapshot.Version)
+	return true
+}
+
+// changePage は発表者の表示しているページを記録し，前のページの表示を終える
+// 資料のバージョンと切り替えた時刻を返す
+func changePage(db *gorm.DB, meetingId int, presenterId string, documentId int, documentPage int) (bool, int, time.Time) {
+	defer observeDBQuery("changePage", time.Now())
+
+	var document Document
+	if err := db.First(&document, "document_id = ?", documentId).Error; err != nil {
+		fmt.Printf("Error: 資料が非存在: %d in changePage\n", documentId)
+		return false, -1, time.Time{}
+	}
+	// 切り替えられるのは発表の順番が来ている発表者の資料のみ
+	if document.MeetingId != meetingId || document.UserId != presenterId || currentPresenterId(db, meetingId) != presenterId {
+		fmt.Printf("Error: 発表中の発表者の資料ではありません: %d, %s, %d in changePage\n", meetingId, presenterId, documentId)
+		return false, -1, time.Time{}
+	}
+	if documentPage < 1 || !validDocumentPage(db, documentId, documentPage) {
+		fmt.Printf("Error: 存在しないページです: %d, %d in changePage\n", documentId, documentPage)
+		return false, -1, time.Time{}
+	}
+
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	now := time.Now().In(location)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&PageView{}).Where("meeting_id = ? AND ended_at IS NULL", meetingId).Update("ended_at", now).Error; err != nil {
+			return err
+		}
+		view := PageView{
+			MeetingId:       meetingId,
+			DocumentId:      documentId,
+			DocumentPage:    documentPage,
+			DocumentVersion: document.Version,
+			StartedAt:       now,
+		}
+		if err := tx.Create(&view).Error; err != nil {
+			return err
+		}
+		// 同じ発表者のページの切り替えでは発表の開始時刻を保つ
+		startedAt := &now
+		var current ModeratorState
+		if tx.First(&current, "meeting_id = ?", meetingId).Error == nil && current.PresenterId == presenterId && current.PresenterStartedAt != nil {
+			startedAt = current.PresenterStartedAt
+		}
+		state := ModeratorState{
+			MeetingId:     meetingId,
+			PresenterId:   presenterId,
+			DocumentId:    documentId,
+			DocumentPage:  documentPage,
+			PageChangedAt: now,
+
+			PresenterStartedAt: startedAt,
+		}
+		return tx.Save(&state).Error
+	})
+	if err != nil {
+		fmt.Printf("Error: update失敗(表示中のページの更新に失敗しました): %d, %d, %d in changePage\n", meetingId, documentId, documentPage)
+		return false, -1, time.Time{}
+	}
+	fmt.Printf("Log: update成功(表示中のページの更新に成功しました): %d, %d, %d in changePage\n", meetingId, documentId, documentPage)
+	return true, document.Version, now
+}
+
+// endPageView は会議の終了時などに表示中のページの表示を終える
+func endPageView(db *gorm.DB, meetingId int) {
+	defer observeDBQuery("endPageView", time.Now())
+
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	if err := db.Model(&PageView{}).Where("meeting_id = ? AND ended_at IS NULL", meetingId).Update("ended_at", time.Now().In(location)).Error; err != nil {
+		fmt.Printf("Error: update失敗(ページの表示の終了に失敗しました): %d in endPageView\n", meetingId)
+	}
+}
+
+func getModeratorState(db *gorm.DB, meetingId int) (bool, ModeratorState) {
+	defer observeDBQuery("getModeratorState", time.Now())
+
+	var state ModeratorState
+	if err := db.First(&state, "meeting_id = ?", meetingId).Error; err != nil {
+		return false, ModeratorState{}
+	}
+	return true, state
+}
+
+// getCurrentPage は資料の発表者が表示しているページを返す
+// 資料が表示されていなければ1ページ目とする
+func getCurrentPage(db *gorm.DB, documentId int) int {
+	defer observeDBQuery("getCurrentPage", time.Now())
+
+	var state ModeratorState
+	if err := db.First(&state, "document_id = ?", documentId).Error; err != nil || state.DocumentPage < 1 {
+		return 1
+	}
+	return state.DocumentPage
+}
+
+// getPageViews は資料のページの表示期間を古い順に返す
+func getPageViews(db *gorm.DB, documentId int) []PageView {
+	defer observeDBQuery("getPageViews", time.Now())
+
+	views := make([]PageView, 0, 10)
+	if err := db.Order("started_at").Find(&views, "document_id = ?", documentId).Error; err != nil {
+		fmt.Printf("Error: ページの表示期間の取得に失敗しました: %d in getPageViews\n", documentId)
+		return []PageView{}
+	}
+	return views
+}
+
+func getDocumentReactions(db *gorm.DB, documentId int) []Reaction {
+	defer observeDBQuery("getDocumentReactions", time.Now())
+
+	reactions := make([]Reaction, 0, 10)
+	if err := db.Find(&reactions, "document_id = ?", documentId).Error; err != nil {
+		fmt.Printf("Error: 資料リアクションの取得に失敗しました: %d in getDocumentReactions\n", documentId)
+		return []Reaction{}
+	}
+	return reactions
+}
+
+// getDocumentQuestions は資料への質問(挙手を含む)を古い順に返す
+func getDocumentQuestions(db *gorm.DB, documentId int) []Question {
+	defer observeDBQuery("getDocumentQuestions", time.Now())
+
+	questions := make([]Question, 0, 10)
+	if err := db.Order("question_time").Find(&questions, "document_id = ?", documentId).Error; err != nil {
+		fmt.Printf("Error: 質問の取得に失敗しました: %d in getDocumentQuestions\n", documentId)
+		return []Question{}
+	}
+	return questions
+}
+
+func saveModeratorMessage(db *gorm.DB, meetingId int, body string) {
+	defer observeDBQuery("saveModeratorMessage", time.Now())
+
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	message := ModeratorMessage{MeetingId: meetingId, Body: body, SentAt: time.Now().In(location)}
+	if err := db.Create(&message).Error; err != nil {
+		fmt.Printf("Error: create失敗(司会メッセージの記録に失敗しました): %d in saveModeratorMessage\n", meetingId)
+	}
+}
+
+func getModeratorMessages(db *gorm.DB, meetingId int) []ModeratorMessage {
+	defer observeDBQuery("getModeratorMessages", time.Now())
+
+	messages := make([]ModeratorMessage, 0, 10)
+	if err := db.Order("sent_at, moderator_message_id").Find(&messages, "meeting_id = ?", meetingId).Error; err != nil {
+		fmt.Printf("Error: 司会メッセージの取得に失敗しました: %d in getModeratorMessages\n", meetingId)
+		return []ModeratorMessage{}
+	}
+	return messages
+}
+
+func setMeetingEnd(db *gorm.DB, meetingId int) {
+	defer observeDBQuery("setMeetingEnd", time.Now())
+
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	if err := db.Model(&Meeting{}).Where("meeting_id = ?", meetingId).Update("meeting_end_time", time.Now().In(location)).Error; err != nil {
+		fmt.Printf("Error: update失敗(会議の終了時刻の更新に失敗しました): %d in setMeetingEnd\n", meetingId)
+	}
+}
+
+func getMeeting(db *gorm.DB, meetingId int) (bool, Meeting) {
+	defer observeDBQuery("getMeeting", time.Now())
+
+	var meeting Meeting
+	if err := db.First(&meeting, "meeting_id = ?", meetingId).Error; err != nil {
+		fmt.Printf("Error: 会議が非存在: %d in getMeeting\n", meetingId)
+		return false, Meeting{}
+	}
+	return true, meeting
+}
+
+// getPresenters は会議の発表者を発表順に返す
+func getPresenters(db *gorm.DB, meetingId int) []Participant {
+	defer observeDBQuery("getPresenters", time.Now())
+
+	participants := make([]Participant, 0, 10)
+	if err := db.Find(&participants, "meeting_id = ? AND participant_order != ?", meetingId, -1).Error; err != nil {
+		fmt.Printf("Error: 発表者の取得に失敗しました: %d in getPresenters\n", meetingId)
+		return []Participant{}
+	}
+	sort.Sort(ByParticipantOrder(participants))
+	return participants
+}
+
+func getQuestion(db *gorm.DB, questionId int) (bool, Question) {
+	defer observeDBQuery("getQuestion", time.Now())
+
+	var question Question
+	if err := db.First(&question, "question_id = ?", questionId).Error; err != nil {
+		fmt.Printf("Error: 質問が非存在: %d in getQuestion\n", questionId)
+		return false, Question{}
+	}
+	return true, question
+}
+
+// getUnansweredQuestions は会議中に取り上げられなかった資料への質問を返す
+// 挙手と司会が作成した質問は含めない
+func getUnansweredQuestions(db *gorm.DB, documentId int) []Question {
+	defer observeDBQuery("getUnansweredQuestions", time.Now())
+
+	questions := make([]Question, 0, 10)
+	if err := db.Order("vote_num desc, question_time").Find(&questions, "document_id = ? AND question_ok = ? AND is_voice = ? AND user_id != ? AND moderation_status = ?", documentId, false, false, moderatorUserId, questionApproved).Error; err != nil {
+		fmt.Printf("Error: 未回答の質問の取得に失敗しました: %d in getUnansweredQuestions\n", documentId)
+		return []Question{}
+	}
+	return questions
+}
+
+// createFollowUpAnswer は未回答の質問に事後の回答を登録し，質問を回答済みにする
+func createFollowUpAnswer(db *gorm.DB, questionId int, userId string, answerBody string) (bool, Answer) {
+	defer observeDBQuery("createFollowUpAnswer", time.Now())
+
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	answer := Answer{
+		QuestionId: questionId,
+		UserId:     userId,
+		AnswerBody: answerBody,
+		AnsweredAt: time.Now().In(location),
+		IsFollowUp: true,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 同時に回答された場合に二重に登録しないよう，公開中で未回答の場合のみ更新する
+		update := tx.Model(&Question{}).Where("question_id = ? AND question_ok = ? AND moderation_status = ?", questionId, false, questionApproved).Update("question_ok", true)
+		if update.Error != nil {
+			return update.Error
+		}
+		if update.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(&answer).Error
+	})
+	if err != nil {
+		fmt.Printf("Error: create失敗(事後の回答の登録に失敗しました): %d, %s in createFollowUpAnswer\n", questionId, userId)
+		return false, Answer{}
+	}
+	fmt.Printf("Log: create成功(事後の回答の登録に成功しました): %d, %s in createFollowUpAnswer\n", questionId, userId)
+	return true, answer
+}
+
+// getDocumentAnswers は資料への質問の回答を質問ID毎に返す
+func getDocumentAnswers(db *gorm.DB, documentId int) map[int][]Answer {
+	defer observeDBQuery("getDocumentAnswers", time.Now())
+
+	answers := make([]Answer, 0, 10)
+	if err := db.Joins("JOIN questions ON questions.question_id = answers.question_id").Where("questions.document_id = ?", documentId).Order("answers.answered_at").Find(&answers).Error; err != nil {
+		fmt.Printf("Error: 回答の取得に失敗しました: %d in getDocumentAnswers\n", documentId)
+		return map[int][]Answer{}
+	}
+	result := make(map[int][]Answer)
+	for _, answer := range answers {
+		result[answer.QuestionId] = append(result[answer.QuestionId], answer)
+	}
+	return result
+}
+
+func createInboxMessage(db *gorm.DB, userId string, messageType string, body string) bool {
+	defer observeDBQuery("createInboxMessage", time.Now())
+
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	message := InboxMessage{UserId: userId, MessageType: messageType, Body: body, CreatedAt: time.Now().In(location)}
+	if err := db.Create(&message).Error; err != nil {
+		fmt.Printf("Error: create失敗(受信箱への登録に失敗しました): %s, %s in createInboxMessage\n", userId, messageType)
+		return false
+	}
+	fmt.Printf("Log: create成功(受信箱への登録に成功しました): %s, %s in createInboxMessage\n", userId, messageType)
+	return true
+}
+
+// getFullAnonymityMeetings は完全匿名の会議を返す
+func getFullAnonymityMeetings(db *gorm.DB) []Meeting {
+	defer observeDBQuery("getFullAnonymityMeetings", time.Now())
+
+	meetings := make([]Meeting, 0, 10)
+	if err := db.Where("anonymity_level = ?", anonymityFull).Find(&meetings).Error; err != nil {
+		fmt.Printf("Error: 完全匿名の会議の取得に失敗しました in getFullAnonymityMeetings\n")
+		return []Meeting{}
+	}
+	return meetings
+}
+
+// getInboxMessages は利用者の受信箱を新しい順に返す
+// userIdsには利用者のIDと完全匿名の会議での仮名を渡す
+func getInboxMessages(db *gorm.DB, userIds []string, unreadOnly bool) []InboxMessage {
+	defer observeDBQuery("getInboxMessages", time.Now())
+
+	query := db.Order("created_at desc, inbox_message_id desc").Where("user_id IN (?)", userIds)
+	if unreadOnly {
+		query = query.Where("is_read = ?", false)
+	}
+	messages := make([]InboxMessage, 0, 10)
+	if err := query.Find(&messages).Error; err != nil {
+		fmt.Printf("Error: 受信箱の取得に失敗しました: %s in getInboxMessages\n", userIds[0])
+		return []InboxMessage{}
+	}
+	return messages
+}
+
+func readInboxMessage(db *gorm.DB, userIds []string, inboxMessageId int) bool {
+	defer observeDBQuery("readInboxMessage", time.Now())
+
+	update := db.Model(&InboxMessage{}).Where("inbox_message_id = ? AND user_id IN (?)", inboxMessageId, userIds).Update("is_read", true)
+	if update.Error != nil || update.RowsAffected == 0 {
+		fmt.Printf("Error: update失敗(受信箱の既読の更新に失敗しました): %s, %d in readInboxMessage\n", userIds[0], inboxMessageId)
+		return false
+	}
+	return true
+}
+
+// createAnswer は会議中の質問に文章で回答し，質問を回答済みにする
+func createAnswer(db *gorm.DB, questionId int, userId string, answerBody string) (bool, Answer) {
+	defer observeDBQuery("createAnswer", time.Now())
+
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	answer := Answer{
+		QuestionId: questionId,
+		UserId:     userId,
+		AnswerBody: answerBody,
+		AnsweredAt: time.Now().In(location),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 承認待ち・却下された質問には回答できない
+		update := tx.Model(&Question{}).Where("question_id = ? AND moderation_status = ?", questionId, questionApproved).Update("question_ok", true)
+		if update.Error != nil {
+			return update.Error
+		}
+		if update.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(&answer).Error
+	})
+	if err != nil {
+		fmt.Printf("Error: create失敗(回答の登録に失敗しました): %d, %s in createAnswer\n", questionId, userId)
+		return false, Answer{}
+	}
+	fmt.Printf("Log: create成功(回答の登録に成功しました): %d, %s in createAnswer\n", questionId, userId)
+	return true, answer
+}
+
+// createReply は質問への返信を登録する
+// 返信への返信は同じ質問の返信にのみできる
+func createReply(db *gorm.DB, reply Reply) (bool, Reply) {
+	defer observeDBQuery("createReply", time.Now())
+
+	// 承認待ち・却下された質問には返信できない
+	var question Question
+	if err := db.First(&question, "question_id = ? AND moderation_status = ?", reply.QuestionId, questionApproved).Error; err != nil {
+		fmt.Printf("Error: 公開中の質問ではありません: %d in createReply\n", reply.QuestionId)
+		return false, Reply{}
+	}
+	if reply.ParentReplyId != 0 {
+		var parent Reply
+		if err := db.First(&parent, "reply_id = ? AND question_id = ?", reply.ParentReplyId, reply.QuestionId).Error; err != nil {
+			fmt.Printf("Error: 返信先が非存在: %d, %d in createReply\n", reply.QuestionId, reply.ParentReplyId)
+			return false, Reply{}
+		}
+	}
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	reply.RepliedAt = time.Now().In(location)
+	if err := db.Create(&reply).Error; err != nil {
+		fmt.Printf("Error: create失敗(返信の登録に失敗しました): %d, %s in createReply\n", reply.QuestionId, reply.UserId)
+		return false, Reply{}
+	}
+	fmt.Printf("Log: create成功(返信の登録に成功しました): %d, %s in createReply\n", reply.QuestionId, reply.UserId)
+	return true, reply
+}
+
+// getQuestionThreads は質問毎の回答と返信を古い順に返す
+func getQuestionThreads(db *gorm.DB, questionIds []int) (map[int][]Answer, map[int][]Reply) {
+	defer observeDBQuery("getQuestionThreads", time.Now())
+
+	answers := make(map[int][]Answer)
+	replies := make(map[int][]Reply)
+	if len(questionIds) == 0 {
+		return answers, replies
+	}
+
+	answerRows := make([]Answer, 0, 10)
+	if err := db.Order("answered_at, answer_id").Find(&answerRows, "question_id IN (?)", questionIds).Error; err != nil {
+		fmt.Printf("Error: 回答の取得に失敗しました in getQuestionThreads\n")
+	}
+	for _, answer := range answerRows {
+		answers[answer.QuestionId] = append(answers[answer.QuestionId], answer)
+	}
+	replyRows := make([]Reply, 0, 10)
+	if err := db.Order("replied_at, reply_id").Find(&replyRows, "question_id IN (?)", questionIds).Error; err != nil {
+		fmt.Printf("Error: 返信の取得に失敗しました in getQuestionThreads\n")
+	}
+	for _, reply := range replyRows {
+		replies[reply.QuestionId] = append(replies[reply.QuestionId], reply)
+	}
+	return answers, replies
+}
+
+// isPreModeration は資料の会議が質問の事前承認をするかを返す
+func isPreModeration(db *gorm.DB, documentId int) bool {
+	defer observeDBQuery("isPreModeration", time.Now())
+
+	var meeting Meeting
+	if err := db.Joins("JOIN documents ON documents.meeting_id = meetings.meeting_id").First(&meeting, "documents.document_id = ?", documentId).Error; err != nil {
+		return false
+	}
+	return meeting.PreModeration
+}
+
+// getDocumentMeeting は資料が属する会議を返す
+func getDocumentMeeting(db *gorm.DB, documentId int) (bool, Meeting) {
+	defer observeDBQuery("getDocumentMeeting", time.Now())
+
+	var meeting Meeting
+	if err := db.Joins("JOIN documents ON documents.meeting_id = meetings.meeting_id").First(&meeting, "documents.document_id = ?", documentId).Error; err != nil {
+		fmt.Printf("Error: 資料の会議が非存在: %d in getDocumentMeeting\n", documentId)
+		return false, Meeting{}
+	}
+	return true, meeting
+}
+
+func setPreModeration(db *gorm.DB, meetingId int, enabled bool) bool {
+	defer observeDBQuery("setPreModeration", time.Now())
+
+	if err := db.Model(&Meeting{}).Where("meeting_id = ?", meetingId).Update("pre_moderation", enabled).Error; err != nil {
+		fmt.Printf("Error: update失敗(質問の事前承認の設定に失敗しました): %d in setPreModeration\n", meetingId)
+		return false
+	}
+	fmt.Printf("Log: update成功(質問の事前承認の設定に成功しました): %d, %t in setPreModeration\n", meetingId, enabled)
+	return true
+}
+
+// getHostIds は会議のホストを返す (ホストが未設定の会議は発表者)
+func getHostIds(db *gorm.DB, meetingId int) []string {
+	defer observeDBQuery("getHostIds", time.Now())
+
+	var meeting Meeting
+	if err := db.First(&meeting, "meeting_id = ?", meetingId).Error; err != nil {
+		fmt.Printf("Error: 会議が非存在: %d in getHostIds\n", meetingId)
+		return []string{}
+	}
+	if meeting.HostUserId != "" {
+		return []string{meeting.HostUserId}
+	}
+	hostIds := make([]string, 0, 10)
+	for _, presenter := range getPresenters(db, meetingId) {
+		hostIds = append(hostIds, presenter.UserId)
+	}
+	return hostIds
+}
+
+// getPendingQuestions は会議の承認待ちの質問を古い順に返す
+func getPendingQuestions(db *gorm.DB, meetingId int) []Question {
+	defer observeDBQuery("getPendingQuestions", time.Now())
+
+	questions := make([]Question, 0, 10)
+	if err := db.Joins("JOIN documents ON documents.document_id = questions.document_id").Where("documents.meeting_id = ? AND questions.moderation_status = ?", meetingId, questionPending).Order("questions.question_time").Find(&questions).Error; err != nil {
+		fmt.Printf("Error: 承認待ちの質問の取得に失敗しました: %d in getPendingQuestions\n", meetingId)
+		return []Question{}
+	}
+	return questions
+}
+
+// setQuestionStatus は質問の公開状態を変更する (現在の状態がfromのいずれかの場合のみ)
+func setQuestionStatus(db *gorm.DB, questionId int, from []string, status string) bool {
+	defer observeDBQuery("setQuestionStatus", time.Now())
+
+	update := db.Model(&Question{}).Where("question_id = ? AND moderation_status IN (?)", questionId, from).Update("moderation_status", status)
+	if update.Error != nil || update.RowsAffected == 0 {
+		fmt.Printf("Error: update失敗(質問の公開状態の更新に失敗しました): %d, %s in setQuestionStatus\n", questionId, status)
+		return false
+	}
+	fmt.Printf("Log: update成功(質問の公開状態の更新に成功しました): %d, %s in setQuestionStatus\n", questionId, status)
+	return true
+}
+
+// editQuestion は質問の本文を変更し，変更前の本文を編集履歴に残す
+// 公開中か承認待ちの文章の質問のみ編集できる
+func editQuestion(db *gorm.DB, questionId int, userId string, questionBody string) bool {
+	defer observeDBQuery("editQuestion", time.Now())
+
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var question Question
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&question, "question_id = ?", questionId).Error; err != nil {
+			return err
+		}
+		if question.IsVoice || (question.ModerationStatus != questionApproved && question.ModerationStatus != questionPending) {
+			return fmt.Errorf("編集できない質問です")
+		}
+		if err := tx.Create(&QuestionEdit{
+			QuestionId:   questionId,
+			UserId:       userId,
+			PreviousBody: question.QuestionBody,
+			EditedAt:     time.Now().In(location),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Question{}).Where("question_id = ?", questionId).Update("question_body", questionBody).Error
+	})
+	if err != nil {
+		fmt.Printf("Error: update失敗(質問の本文の更新に失敗しました): %d, %v in editQuestion\n", questionId, err)
+		return false
+	}
+	fmt.Printf("Log: update成功(質問の本文の更新に成功しました): %d, %s in editQuestion\n", questionId, userId)
+	return true
+}
+
+// getQuestionEdits は質問の編集履歴を古い順に返す
+func getQuestionEdits(db *gorm.DB, questionId int) []QuestionEdit {
+	defer observeDBQuery("getQuestionEdits", time.Now())
+
+	edits := make([]QuestionEdit, 0, 5)
+	if err := db.Order("edited_at, question_edit_id").Find(&edits, "question_id = ?", questionId).Error; err != nil {
+		fmt.Printf("Error: 質問の編集履歴の取得に失敗しました: %d in getQuestionEdits\n", questionId)
+		return []QuestionEdit{}
+	}
+	return edits
+}
+
+// getMergeCandidates は資料の統合先になれる質問(公開中の文章の質問)を古い順に返す
+func getMergeCandidates(db *gorm.DB, documentId int) []Question {
+	defer observeDBQuery("getMergeCandidates", time.Now())
+
+	questions := make([]Question, 0, 10)
+	if err := db.Where("document_id = ? AND moderation_status = ? AND is_voice = ? AND is_nominated = ?", documentId, questionApproved, false, false).Order("question_time").Find(&questions).Error; err != nil {
+		fmt.Printf("Error: 質問の取得に失敗しました: %d in getMergeCandidates\n", documentId)
+		return []Question{}
+	}
+	return questions
+}
+
+func setDuplicateOf(db *gorm.DB, questionId int, duplicateOf int) bool {
+	defer observeDBQuery("setDuplicateOf", time.Now())
+
+	if err := db.Model(&Question{}).Where("question_id = ?", questionId).Update("duplicate_of", duplicateOf).Error; err != nil {
+		fmt.Printf("Error: update失敗(重複の候補の記録に失敗しました): %d, %d in setDuplicateOf\n", questionId, duplicateOf)
+		return false
+	}
+	return true
+}
+
+// getDuplicateQuestions は会議の重複の可能性がある未統合の質問を古い順に返す
+func getDuplicateQuestions(db *gorm.DB, meetingId int) []Question {
+	defer observeDBQuery("getDuplicateQuestions", time.Now())
+
+	questions := make([]Question, 0, 10)
+	if err := db.Joins("JOIN documents ON documents.document_id = questions.document_id").Where("documents.meeting_id = ? AND questions.duplicate_of <> 0 AND questions.moderation_status IN (?)", meetingId, []string{questionApproved, questionPending}).Order("questions.question_time").Find(&questions).Error; err != nil {
+		fmt.Printf("Error: 重複の可能性がある質問の取得に失敗しました: %d in getDuplicateQuestions\n", meetingId)
+		return []Question{}
+	}
+	return questions
+}
+
+// mergeQuestion は質問fromIdをintoIdに統合し，投票数を合算する
+// 同じ資料の公開中の質問にのみ統合でき，統合後の投票数を返す
+func mergeQuestion(db *gorm.DB, fromId int, intoId int) (bool, int) {
+	defer observeDBQuery("mergeQuestion", time.Now())
+
+	if fromId == intoId {
+		return false, -1
+	}
+	var into Question
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var from Question
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&from, "question_id = ?", fromId).Error; err != nil {
+			return err
+		}
+		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&into, "question_id = ?", intoId).Error; err != nil {
+			return err
+		}
+		if from.DocumentId != into.DocumentId || from.IsVoice || into.IsVoice || into.ModerationStatus != questionApproved || from.ModerationStatus == questionMerged || from.ModerationStatus == questionWithdrawn {
+			return fmt.Errorf("統合できない質問です")
+		}
+		// 承認待ちの質問は統合先の投票数に数えない (投票できないため0)
+		into.VoteNum += from.VoteNum
+		if err := tx.Model(&Question{}).Where("question_id = ?", intoId).Update("vote_num", into.VoteNum).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Question{}).Where("question_id = ?", fromId).Updates(map[string]interface{}{
+			"moderation_status": questionMerged,
+			"merged_into":       intoId,
+		}).Error
+	})
+	if err != nil {
+		fmt.Printf("Error: update失敗(質問の統合に失敗しました): %d, %d, %v in mergeQuestion\n", fromId, intoId, err)
+		return false, -1
+	}
+	fmt.Printf("Log: update成功(質問の統合に成功しました): %d, %d in mergeQuestion\n", fromId, intoId)
+	return true, into.VoteNum
+}
+
+// 以下はv2 APIで使う操作で，失敗した理由をStoreErrorで返す
+
+func findUser(db *gorm.DB, userId string) (User, error) {
+	defer observeDBQuery("findUser", time.Now())
+
+	var user User
+	if err := db.First(&user, "user_id = ?", userId).Error; err != nil {
+		return User{}, dbError(err, "user", userId)
+	}
+	return user, nil
+}
+
+func insertUser(db *gorm.DB, user User) error {
+	defer observeDBQuery("insertUser", time.Now())
+
+	if user.UserId == "" || user.UserName == "" || user.UserPassword == "" {
+		return invalidError("invalid_user", "userId, userName and userPassword are required", nil)
+	}
+	if _, err := findUser(db, user.UserId); err == nil {
+		return &StoreError{Kind: errConflict, Code: "user_already_exists", Message: "user already exists", Details: map[string]interface{}{"id": user.UserId}}
+	}
+	if err := db.Create(&user).Error; err != nil {
+		fmt.Printf("Error: signup失敗: %s in insertUser\n", user.UserId)
+		return dbError(err, "user", user.UserId)
+	}
+	fmt.Printf("Log: signup成功: %s in insertUser\n", user.UserId)
+	return nil
+}
+
+func authenticateUser(db *gorm.DB, userId string, userPassword string) (User, error) {
+	defer observeDBQuery("authenticateUser", time.Now())
+
+	var user User
+	if err := db.First(&user, "user_id = ? AND user_password = ?", userId, userPassword).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			fmt.Printf("Error: login失敗: %s in authenticateUser\n", userId)
+			return User{}, &StoreError{Kind: errUnauthorized, Code: "invalid_credentials", Message: "invalid user id or password"}
+		}
+		return User{}, dbError(err, "user", userId)
+	}
+	fmt.Printf("Log: login成功: %s in authenticateUser\n", userId)
+	return user, nil
+}
+
+func findMeeting(db *gorm.DB, meetingId int) (Meeting, error) {
+	defer observeDBQuery("findMeeting", time.Now())
+
+	var meeting Meeting
+	if err := db.First(&meeting, "meeting_id = ?", meetingId).Error; err != nil {
+		return Meeting{}, dbError(err, "meeting", meetingId)
+	}
+	return meeting, nil
+}
+
+// insertMeeting は会議と発表者・発表者の空の資料を1つのトランザクションで登録する
+func insertMeeting(db *gorm.DB, meeting Meeting, presenterIds []string) (Meeting, error) {
+	defer observeDBQuery("insertMeeting", time.Now())
+
+	if meeting.MeetingName == "" || len(presenterIds) == 0 {
+		return Meeting{}, invalidError("invalid_meeting", "meetingName and presenterIds are required", nil)
+	}
+	if !meetingAnonymity(&meeting) {
+		return Meeting{}, invalidError("invalid_meeting", "unknown anonymityLevel", map[string]interface{}{"anonymityLevel": meeting.AnonymityLevel})
+	}
+	for _, presenterId := range presenterIds {
+		if _, err := findUser(db, presenterId); err != nil {
+			if errors.Is(err, errNotFound) {
+				return Meeting{}, invalidError("invalid_meeting", "presenter not found", map[string]interface{}{"presenterId": presenterId})
+			}
+			return Meeting{}, err
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&meeting).Error; err != nil {
+			return err
+		}
+		for i, presenterId := range presenterIds {
+			if err := tx.Create(&Participant{MeetingId: meeting.MeetingId, UserId: presenterId, ParticipantOrder: i}).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&Document{UserId: presenterId, MeetingId: meeting.MeetingId}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error: create失敗(会議の登録に失敗しました): %s, %s in insertMeeting\n", meeting.MeetingName, presenterIds)
+		return Meeting{}, dbError(err, "meeting", 0)
+	}
+	fmt.Printf("Log: create成功: %d, %s, %s in insertMeeting\n", meeting.MeetingId, meeting.MeetingName, presenterIds)
+	return meeting, nil
+}
+
+func findDocument(db *gorm.DB, documentId int) (Document, error) {
+	defer observeDBQuery("findDocument", time.Now())
+
+	var document Document
+	if err := db.First(&document, "document_id = ?", documentId).Error; err != nil {
+		return Document{}, dbError(err, "document", documentId)
+	}
+	return document, nil
+}
+
+func findScriptSegments(db *gorm.DB, documentId int) ([]ScriptSegment, error) {
+	defer observeDBQuery("findScriptSegments", time.Now())
+
+	segments := make([]ScriptSegment, 0, 10)
+	if err := db.Order("document_page").Find(&segments, "document_id = ?", documentId).Error; err != nil {
+		return []ScriptSegment{}, dbError(err, "document", documentId)
+	}
+	return segments, nil
+}
+
+// updateDocument は資料URLと原稿のうち指定されたものを登録し，新しいバージョンを作る
+// nilの項目は変更しない (documentRegister と同じく空にはできない)
+// 変更できるのは資料の発表者と会議のホストのみ
+func updateDocument(db *gorm.DB, userId string, documentId int, documentUrl *string, script *string, segments []ScriptSegment) (Document, error) {
+	document, err := findDocument(db, documentId)
+	if err != nil {
+		return Document{}, err
+	}
+	if userId != document.UserId && !isHost(db, document.MeetingId, userId) {
+		fmt.Printf("Error: 発表者・ホスト以外は資料を変更できません: %d, %s in updateDocument\n", documentId, userId)
+		return Document{}, &StoreError{Kind: errForbidden, Code: "forbidden", Message: "only the presenter or a host can update the document"}
+	}
+	if (documentUrl == nil || *documentUrl == "") && (script == nil || *script == "") && len(segments) == 0 {
+		return Document{}, invalidError("invalid_document", "documentUrl, script or scriptSegments is required", nil)
+	}
+	for _, segment := range segments {
+		if !validDocumentPage(db, documentId, segment.DocumentPage) {
+			return Document{}, invalidError("invalid_document", "page does not exist", map[string]interface{}{"documentPage": segment.DocumentPage})
+		}
+	}
+
+	var url, body string
+	if documentUrl != nil {
+		url = *documentUrl
+	}
+	if script != nil {
+		body = *script
+	}
+	if ok, _, _ := documentRegister(db, document.DocumentId, url, body, segments); !ok {
+		return Document{}, &StoreError{Code: "internal_error", Message: "failed to update the document"}
+	}
+	return findDocument(db, documentId)
+}
+
+func findQuestion(db *gorm.DB, questionId int) (Question, error) {
+	defer observeDBQuery("findQuestion", time.Now())
+
+	var question Question
+	if err := db.First(&question, "question_id = ?", questionId).Error; err != nil {
+		return Question{}, dbError(err, "question", questionId)
+	}
+	return question, nil
+}
+
+// setPageReaction は参加者のリアクションを付ける・外す
+// 会議ID，資料のバージョン，状態が変わったかを返す (失敗した場合は会議IDが-1)
+func setPageReaction(db *gorm.DB, userId string, documentId int, documentPage int, kind string, emoji string, isReaction bool) (int, int, bool) {
+	defer observeDBQuery("setPageReaction", time.Now())
+
+	found, document := getDocument(db, documentId)
+	if !found {
+		fmt.Printf("Error: 資料が非存在: %d in setPageReaction\n", documentId)
+		return -1, -1, false
+	}
+	if !validDocumentPage(db, documentId, documentPage) {
+		fmt.Printf("Error: 存在しないページです: %d, %d in setPageReaction\n", documentId, documentPage)
+		return -1, -1, false
+	}
+	if !isMeetingMember(db, document.MeetingId, userId) {
+		fmt.Printf("Error: 会議の参加者ではありません: %d, %s in setPageReaction\n", document.MeetingId, userId)
+		return -1, -1, false
+	}
+
+	var reaction PageReaction
+	exists := db.First(&reaction, "document_id = ? AND document_page = ? AND document_version = ? AND user_id = ? AND kind = ? AND emoji = ?", documentId, documentPage, document.Version, userId, kind, emoji).Error == nil
+	switch {
+	case isReaction && !exists:
+		reaction = PageReaction{
+			DocumentId:      documentId,
+			DocumentPage:    documentPage,
+			DocumentVersion: document.Version,
+			UserId:          userId,
+			Kind:            kind,
+			Emoji:           emoji,
+		}
+		if err := db.Create(&reaction).Error; err != nil {
+			fmt.Printf("Error: create失敗(リアクションの登録に失敗しました): %d, %d, %s in setPageReaction\n", documentId, documentPage, userId)
+			return -1, -1, false
+		}
+	case !isReaction && exists:
+		if err := db.Delete(&reaction).Error; err != nil {
+			fmt.Printf("Error: delete失敗(リアクションの削除に失敗しました): %d, %d, %s in setPageReaction\n", documentId, documentPage, userId)
+			return -1, -1, false
+		}
+	default:
+		return document.MeetingId, document.Version, false
+	}
+	fmt.Printf("Log: リアクションを更新しました: %d, %d, %s, %s in setPageReaction\n", documentId, documentPage, userId, kind)
+	return document.MeetingId, document.Version, true
+}
+
+// getPageReactionCounts は資料のバージョンへのリアクションをページ・種類毎に数える
+// sinceが零値でなければ，その時刻以降のリアクションのみ数える
+func getPageReactionCounts(db *gorm.DB, documentId int, version int, since time.Time) []PageReactionCount {
+	defer observeDBQuery("getPageReactionCounts", time.Now())
+
+	counts := make([]PageReactionCount, 0, 10)
+	query := db.Model(&PageReaction{}).Select("document_page, kind, emoji, count(*) as reaction_num").Where("document_id = ? AND document_version = ?", documentId, version)
+	if !since.IsZero() {
+		query = query.Where("created_at >= ?", since)
+	}
+	if err := query.Group("document_page, kind, emoji").Order("document_page").Scan(&counts).Error; err != nil {
+		fmt.Printf("Error: リアクションの集計に失敗しました: %d, %d in getPageReactionCounts\n", documentId, version)
+	}
+	return counts
+}
+
+// getDocumentPageReactionCounts は資料の全てのバージョンのページ毎・種類毎のリアクションの数を返す
+func getDocumentPageReactionCounts(db *gorm.DB, documentId int) []PageReactionCount {
+	defer observeDBQuery("getDocumentPageReactionCounts", time.Now())
+
+	counts := make([]PageReactionCount, 0, 10)
+	if err := db.Model(&PageReaction{}).Select("document_version, document_page, kind, count(*) as reaction_num").Where("document_id = ?", documentId).Group("document_version, document_page, kind").Order("document_version, document_page").Scan(&counts).Error; err != nil {
+		fmt.Printf("Error: リアクションの集計に失敗しました: %d in getDocumentPageReactionCounts\n", documentId)
+	}
+	return counts
+}
+
+// countReactionUsers はsince以降に資料へkindのリアクションをした参加者の人数を返す (ページに関係なく1人1回)
+func countReactionUsers(db *gorm.DB, documentId int, version int, kind string, since time.Time) int {
+	defer observeDBQuery("countReactionUsers", time.Now())
+
+	var count int
+	if err := db.Model(&PageReaction{}).Select("count(distinct user_id)").Where("document_id = ? AND document_version = ? AND kind = ? AND created_at >= ?", documentId, version, kind, since).Count(&count).Error; err != nil {
+		fmt.Printf("Error: リアクションした人数の集計に失敗しました: %d, %d, %s in countReactionUsers\n", documentId, version, kind)
+		return 0
+	}
+	return count
+}
+
+// countJoiningParticipants は会議に参加中の人数を返す (exceptUserIdを除く)
+func countJoiningParticipants(db *gorm.DB, meetingId int, exceptUserId string) int {
+	defer observeDBQuery("countJoiningParticipants", time.Now())
+
+	var count int
+	db.Model(&Participant{}).Where("meeting_id = ? AND user_id != ? AND is_joining = ?", meetingId, exceptUserId, true).Count(&count)
+	return count
+}
+
+// endPresentation は発表者の交代・会議の終了時に発表の時間の計測を終える
+func endPresentation(db *gorm.DB, meetingId int) {
+	defer observeDBQuery("endPresentation", time.Now())
+
+	if err := db.Model(&ModeratorState{}).Where("meeting_id = ?", meetingId).Update("presenter_started_at", gorm.Expr("NULL")).Error; err != nil {
+		fmt.Printf("Error: update失敗(発表の時間の計測の終了に失敗しました): %d in endPresentation\n", meetingId)
+	}
+}
+
+// getPresentingStates は終了していない会議のうち，発表中の会議の司会進行の状態を返す
+func getPresentingStates(db *gorm.DB) []ModeratorState {
+	defer observeDBQuery("getPresentingStates", time.Now())
+
+	states := make([]ModeratorState, 0, 10)
+	if err := db.Joins("JOIN meetings ON meetings.meeting_id = moderator_states.meeting_id").Where("moderator_states.presenter_started_at IS NOT NULL AND meetings.meeting_end_time IS NULL").Find(&states).Error; err != nil {
+		fmt.Printf("Error: 発表中の会議の取得に失敗しました in getPresentingStates\n")
+		return []ModeratorState{}
+	}
+	return states
+}
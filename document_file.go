@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+)
+
+var pdfMagic = []byte("%PDF-")
+
+// multipartのうちメモリに置く上限 (超えた分は一時ファイルになる)
+const multipartMemory = 8 << 20
+
+type DocumentUploadResult struct {
+	Result          bool   `json:"result"`
+	DocumentUrl     string `json:"documentUrl"`
+	PageCount       int    `json:"pageCount"`
+	DocumentVersion int    `json:"documentVersion"`
+}
+
+type DocumentPagesResult struct {
+	Result     bool                 `json:"result"`
+	DocumentId int                  `json:"documentId"`
+	PageCount  int                  `json:"pageCount"`
+	Pages      []DocumentPageResult `json:"pages"`
+}
+
+type DocumentPageResult struct {
+	DocumentPage int     `json:"documentPage"`
+	Text         string  `json:"text"`
+	LayoutUrl    string  `json:"layoutUrl"` // ページのレイアウトの簡易図 (ページの内容の描画ではない)
+	Width        float64 `json:"width"`     // points
+	Height       float64 `json:"height"`    // points
+}
+
+// documentFileUrl はアップロードされた資料の配信URL
+func documentFileUrl(documentId int) string {
+	return fmt.Sprintf("/document/%d/file", documentId)
+}
+
+// countingBody は読んだバイト数とEOF以外のエラーを覚える
+// MaxBytesReaderは上限を超えた場合にだけ，上限まで読んだところでエラーを返す
+// (上限ちょうどの大きさのファイルはエラーにならない)
+type countingBody struct {
+	io.ReadCloser
+	n   int64
+	err error
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+// tooLarge はMaxBytesReaderが上限を超えたことによるエラーを返したかを返す
+func (b *countingBody) tooLarge(limit int64) bool {
+	return b.err != nil && b.n == limit
+}
+
+// documentUpload は発表者が資料のPDFをアップロードする (multipart: userId, file)
+// PowerPointやKeynoteの資料はPDFに書き出してからアップロードする
+func documentUpload(hub *Hub, db *gorm.DB, store BlobStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		documentId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &DocumentUploadResult{Result: false})
+		}
+		found, document := getDocument(db, documentId)
+		if !found {
+			return c.JSON(http.StatusNotFound, &DocumentUploadResult{Result: false})
+		}
+
+		maxUploadSize := hub.config.Storage.MaxUploadSize
+		body := &countingBody{ReadCloser: http.MaxBytesReader(c.Response(), c.Request().Body, maxUploadSize)}
+		c.Request().Body = body
+		if err := c.Request().ParseMultipartForm(multipartMemory); err != nil {
+			fmt.Printf("Error: ファイルを受け取れませんでした: %d, %v in documentUpload\n", documentId, err)
+			if c.Request().ContentLength > maxUploadSize || body.tooLarge(maxUploadSize) {
+				return c.JSON(http.StatusRequestEntityTooLarge, &DocumentUploadResult{Result: false})
+			}
+			return c.JSON(http.StatusBadRequest, &DocumentUploadResult{Result: false})
+		}
+		userId := c.FormValue("userId")
+		if userId != document.UserId {
+			fmt.Printf("Error: 発表者以外は資料をアップロードできません: %d, %s in documentUpload\n", documentId, userId)
+			return c.JSON(http.StatusForbidden, &DocumentUploadResult{Result: false})
+		}
+		header, err := c.FormFile("file")
+		if err != nil {
+			fmt.Printf("Error: ファイルが指定されていません: %d in documentUpload\n", documentId)
+			return c.JSON(http.StatusBadRequest, &DocumentUploadResult{Result: false})
+		}
+		file, err := header.Open()
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &DocumentUploadResult{Result: false})
+		}
+		defer file.Close()
+
+		magic := make([]byte, len(pdfMagic))
+		if _, err := io.ReadFull(file, magic); err != nil || !bytes.Equal(magic, pdfMagic) {
+			fmt.Printf("Error: PDF以外のファイルです: %d, %s in documentUpload\n", documentId, header.Filename)
+			return c.JSON(http.StatusUnsupportedMediaType, &DocumentUploadResult{Result: false})
+		}
+		pdfPages, err := extractPdfPages(file, header.Size)
+		if err != nil {
+			fmt.Printf("Error: PDFを読み込めませんでした: %d, %v in documentUpload\n", documentId, err)
+			return c.JSON(http.StatusUnprocessableEntity, &DocumentUploadResult{Result: false})
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return c.JSON(http.StatusInternalServerError, &DocumentUploadResult{Result: false})
+		}
+
+		// 以前のファイルを参照している利用者のために，アップロード毎に別のキーにする
+		ctx := c.Request().Context()
+		prefix := fmt.Sprintf("documents/%d/%d", documentId, time.Now().UnixNano())
+		key := prefix + ".pdf"
+		if err := store.Put(ctx, key, file, header.Size, "application/pdf"); err != nil {
+			fmt.Printf("Error: ファイルの保存に失敗しました: %d, %v in documentUpload\n", documentId, err)
+			return c.JSON(http.StatusInternalServerError, &DocumentUploadResult{Result: false})
+		}
+		pages, err := storeDocumentPages(ctx, store, documentId, prefix, pdfPages)
+		if err != nil {
+			fmt.Printf("Error: レイアウトの簡易図の保存に失敗しました: %d, %v in documentUpload\n", documentId, err)
+			return c.JSON(http.StatusInternalServerError, &DocumentUploadResult{Result: false})
+		}
+
+		// 資料のファイル・ページ・バージョンは揃えて登録する (バージョンの無いファイルを残さない)
+		documentUrl := documentFileUrl(documentId)
+		var version int
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if !setDocumentFile(tx, documentId, key, documentUrl) || !setDocumentPages(tx, documentId, pages) {
+				return fmt.Errorf("資料のファイルを登録できません: %d, %s", documentId, key)
+			}
+			ok, created := createDocumentVersion(tx, documentId)
+			if !ok {
+				return fmt.Errorf("資料のバージョンを作成できません: %d", documentId)
+			}
+			version = created
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("Error: %v in documentUpload\n", err)
+			return c.JSON(http.StatusInternalServerError, &DocumentUploadResult{Result: false})
+		}
+		fmt.Printf("Log: 資料のアップロードに成功しました: %d, %s, %dページ in documentUpload\n", documentId, key, len(pages))
+
+		hub.sendDocumentUpdate(document.MeetingId, documentId, version)
+		return c.JSON(http.StatusOK, &DocumentUploadResult{Result: true, DocumentUrl: documentUrl, PageCount: len(pages), DocumentVersion: version})
+	}
+}
+
+// documentFile はアップロードされた資料を配信する (Range要求に対応)
+// 会議の参加者のみ取得できる (?userId=...)
+func documentFile(db *gorm.DB, store BlobStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		documentId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		found, document := getDocument(db, documentId)
+		if !found || document.FileKey == nil {
+			return c.JSON(http.StatusNotFound, &Result{Result: false})
+		}
+		if !isMeetingMember(db, document.MeetingId, c.QueryParam("userId")) {
+			fmt.Printf("Error: 会議の参加者以外は資料を取得できません: %d, %s in documentFile\n", documentId, c.QueryParam("userId"))
+			return c.JSON(http.StatusForbidden, &Result{Result: false})
+		}
+
+		reader, modTime, err := store.Get(c.Request().Context(), *document.FileKey)
+		if errors.Is(err, errBlobNotFound) {
+			fmt.Printf("Error: 資料ファイルが非存在: %d, %s in documentFile\n", documentId, *document.FileKey)
+			return c.JSON(http.StatusNotFound, &Result{Result: false})
+		} else if err != nil {
+			fmt.Printf("Error: 資料ファイルの取得に失敗しました: %d, %v in documentFile\n", documentId, err)
+			return c.JSON(http.StatusInternalServerError, &Result{Result: false})
+		}
+		defer reader.Close()
+
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("inline; filename=\"document-%d.pdf\"", documentId))
+		http.ServeContent(c.Response(), c.Request(), "document.pdf", modTime, reader)
+		return nil
+	}
+}
+
+// storeDocumentPages はページ毎のレイアウトの簡易図を prefix/page-N.png に保存し，登録するページ情報を返す
+func storeDocumentPages(ctx context.Context, store BlobStore, documentId int, prefix string, pdfPages []pdfPage) ([]Page, error) {
+	pages := make([]Page, 0, len(pdfPages))
+	for _, p := range pdfPages {
+		thumbnailKey := fmt.Sprintf("%s/page-%d.png", prefix, p.Num)
+		if err := store.Put(ctx, thumbnailKey, bytes.NewReader(p.Preview), int64(len(p.Preview)), "image/png"); err != nil {
+			return nil, err
+		}
+		pages = append(pages, Page{
+			DocumentId:   documentId,
+			DocumentPage: p.Num,
+			PageText:     p.Text,
+			ThumbnailKey: thumbnailKey,
+			Width:        p.Width,
+			Height:       p.Height,
+		})
+	}
+	return pages, nil
+}
+
+// documentLayoutUrl はページのレイアウトの簡易図の配信URL
+// 配信は会議の参加者に限るため，ページの一覧を取得した利用者のuserIdを付ける
+func documentLayoutUrl(documentId int, documentPage int, userId string) string {
+	return fmt.Sprintf("/document/%d/pages/%d/layout?userId=%s", documentId, documentPage, url.QueryEscape(userId))
+}
+
+// documentPages はアップロードされた資料のページ毎のテキストとレイアウトの簡易図のURLを返す
+// 会議の参加者のみ取得できる (?userId=...)
+func documentPages(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		documentId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &DocumentPagesResult{Result: false})
+		}
+		found, document := getDocument(db, documentId)
+		if !found {
+			return c.JSON(http.StatusNotFound, &DocumentPagesResult{Result: false})
+		}
+		if !isMeetingMember(db, document.MeetingId, c.QueryParam("userId")) {
+			return c.JSON(http.StatusForbidden, &DocumentPagesResult{Result: false})
+		}
+
+		result := &DocumentPagesResult{
+			Result:     true,
+			DocumentId: documentId,
+			PageCount:  document.PageCount,
+			Pages:      make([]DocumentPageResult, 0, document.PageCount),
+		}
+		for _, p := range getDocumentPages(db, documentId) {
+			result.Pages = append(result.Pages, DocumentPageResult{
+				DocumentPage: p.DocumentPage,
+				Text:         p.PageText,
+				LayoutUrl:    documentLayoutUrl(documentId, p.DocumentPage, c.QueryParam("userId")),
+				Width:        p.Width,
+				Height:       p.Height,
+			})
+		}
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+// documentPageLayout はページのレイアウトの簡易図(PNG)を配信する
+func documentPageLayout(db *gorm.DB, store BlobStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		documentId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		documentPage, err := strconv.Atoi(c.Param("page"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		found, document := getDocument(db, documentId)
+		if !found {
+			return c.JSON(http.StatusNotFound, &Result{Result: false})
+		}
+		if !isMeetingMember(db, document.MeetingId, c.QueryParam("userId")) {
+			return c.JSON(http.StatusForbidden, &Result{Result: false})
+		}
+		found, page := getDocumentPage(db, documentId, documentPage)
+		if !found {
+			return c.JSON(http.StatusNotFound, &Result{Result: false})
+		}
+
+		reader, modTime, err := store.Get(c.Request().Context(), page.ThumbnailKey)
+		if errors.Is(err, errBlobNotFound) {
+			return c.JSON(http.StatusNotFound, &Result{Result: false})
+		} else if err != nil {
+			fmt.Printf("Error: レイアウトの簡易図の取得に失敗しました: %d, %d, %v in documentPageLayout\n", documentId, documentPage, err)
+			return c.JSON(http.StatusInternalServerError, &Result{Result: false})
+		}
+		defer reader.Close()
+
+		http.ServeContent(c.Response(), c.Request(), "layout.png", modTime, reader)
+		return nil
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestCountingBodyTooLarge は上限ちょうどの本文は受け付け，上限を超えた本文だけを大き過ぎると判定することを確認する
+func TestCountingBodyTooLarge(t *testing.T) {
+	const limit = 16
+	for _, size := range []int{limit - 1, limit, limit + 1, limit * 4} {
+		reader := ioutil.NopCloser(strings.NewReader(strings.Repeat("x", size)))
+		body := &countingBody{ReadCloser: http.MaxBytesReader(httptest.NewRecorder(), reader, limit)}
+		_, err := ioutil.ReadAll(body)
+		if want := size > limit; body.tooLarge(limit) != want || (err != nil) != want {
+			t.Errorf("size %d: tooLarge = %t, err = %v, want %t", size, body.tooLarge(limit), err, want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+)
+
+type DocumentVersionsResult struct {
+	Result          bool                    `json:"result"`
+	DocumentId      int                     `json:"documentId"`
+	DocumentVersion int                     `json:"documentVersion"` // 現在のバージョン
+	Versions        []DocumentVersionResult `json:"versions"`        // 新しい順
+}
+
+type DocumentVersionResult struct {
+	DocumentVersion int                   `json:"documentVersion"`
+	DocumentUrl     string                `json:"documentUrl"`
+	Script          string                `json:"script,omitempty"`
+	ScriptSegments  []ScriptSegmentObject `json:"scriptSegments,omitempty"`
+	PageCount       int                   `json:"pageCount"`
+	CreatedAt       string                `json:"createdAt"`
+}
+
+type DocumentVersionGetResult struct {
+	Result     bool                  `json:"result"`
+	DocumentId int                   `json:"documentId"`
+	Version    DocumentVersionResult `json:"version"`
+}
+
+type DocumentRestoreRequest struct {
+	UserId string `json:"userId"`
+}
+
+type DocumentRestoreResult struct {
+	Result          bool `json:"result"`
+	DocumentVersion int  `json:"documentVersion"` // 復元により作られたバージョン
+}
+
+// documentVersionResult はスナップショットをレスポンスの形に変換する
+// withScript がfalseの場合は原稿を含めない (一覧用)
+func documentVersionResult(snapshot DocumentVersion, withScript bool) DocumentVersionResult {
+	result := DocumentVersionResult{
+		DocumentVersion: snapshot.Version,
+		PageCount:       snapshot.PageCount,
+		CreatedAt:       snapshot.CreatedAt.Format(time.RFC3339),
+	}
+	if snapshot.DocumentUrl != nil {
+		result.DocumentUrl = *snapshot.DocumentUrl
+	}
+	if !withScript {
+		return result
+	}
+	if snapshot.Script != nil {
+		result.Script = *snapshot.Script
+	}
+	segments := make([]ScriptSegment, 0, snapshot.PageCount)
+	if err := json.Unmarshal([]byte(snapshot.ScriptSegments), &segments); err != nil {
+		fmt.Printf("Error: ページ毎の原稿を読み込めません: %d, %d in documentVersionResult\n", snapshot.DocumentId, snapshot.Version)
+	}
+	result.ScriptSegments = make([]ScriptSegmentObject, 0, len(segments))
+	for _, segment := range segments {
+		result.ScriptSegments = append(result.ScriptSegments, ScriptSegmentObject{DocumentPage: segment.DocumentPage, Script: segment.Script})
+	}
+	return result
+}
+
+// documentVersions は資料のバージョンの一覧を返す
+// 会議の参加者のみ取得できる (?userId=...)
+func documentVersions(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		documentId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &DocumentVersionsResult{Result: false})
+		}
+		found, document := getDocument(db, documentId)
+		if !found {
+			return c.JSON(http.StatusNotFound, &DocumentVersionsResult{Result: false})
+		}
+		if !isMeetingMember(db, document.MeetingId, c.QueryParam("userId")) {
+			return c.JSON(http.StatusForbidden, &DocumentVersionsResult{Result: false})
+		}
+
+		versions := getDocumentVersions(db, documentId)
+		result := &DocumentVersionsResult{
+			Result:          true,
+			DocumentId:      documentId,
+			DocumentVersion: document.Version,
+			Versions:        make([]DocumentVersionResult, 0, len(versions)),
+		}
+		for _, version := range versions {
+			result.Versions = append(result.Versions, documentVersionResult(version, false))
+		}
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+// documentVersionGet は資料の指定したバージョンの内容を返す
+// 会議の参加者のみ取得できる (?userId=...)
+func documentVersionGet(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		documentId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &DocumentVersionGetResult{Result: false})
+		}
+		version, err := strconv.Atoi(c.Param("version"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &DocumentVersionGetResult{Result: false})
+		}
+		found, document := getDocument(db, documentId)
+		if !found {
+			return c.JSON(http.StatusNotFound, &DocumentVersionGetResult{Result: false})
+		}
+		if !isMeetingMember(db, document.MeetingId, c.QueryParam("userId")) {
+			return c.JSON(http.StatusForbidden, &DocumentVersionGetResult{Result: false})
+		}
+		found, snapshot := getDocumentVersionSnapshot(db, documentId, version)
+		if !found {
+			return c.JSON(http.StatusNotFound, &DocumentVersionGetResult{Result: false})
+		}
+		return c.JSON(http.StatusOK, &DocumentVersionGetResult{
+			Result:     true,
+			DocumentId: documentId,
+			Version:    documentVersionResult(snapshot, true),
+		})
+	}
+}
+
+// documentVersionRestore は発表者が資料を以前のバージョンに戻す
+// 復元も新しいバージョンとして記録するため，以前のバージョンは書き換えない
+func documentVersionRestore(hub *Hub, db *gorm.DB, store BlobStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		documentId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &DocumentRestoreResult{Result: false})
+		}
+		version, err := strconv.Atoi(c.Param("version"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &DocumentRestoreResult{Result: false})
+		}
+		request := new(DocumentRestoreRequest)
+		if err := c.Bind(request); err != nil {
+			return c.JSON(http.StatusBadRequest, &DocumentRestoreResult{Result: false})
+		}
+		found, document := getDocument(db, documentId)
+		if !found {
+			return c.JSON(http.StatusNotFound, &DocumentRestoreResult{Result: false})
+		}
+		if request.UserId != document.UserId {
+			fmt.Printf("Error: 発表者以外は資料を復元できません: %d, %s in documentVersionRestore\n", documentId, request.UserId)
+			return c.JSON(http.StatusForbidden, &DocumentRestoreResult{Result: false})
+		}
+		found, snapshot := getDocumentVersionSnapshot(db, documentId, version)
+		if !found {
+			return c.JSON(http.StatusNotFound, &DocumentRestoreResult{Result: false})
+		}
+
+		// ページ情報はバージョンには含まれないため，PDFから作り直す
+		pages := []Page{}
+		if snapshot.FileKey != nil {
+			pages, err = restoreDocumentPages(c, hub, store, documentId, *snapshot.FileKey)
+			if err != nil {
+				fmt.Printf("Error: ページ情報を復元できませんでした: %d, %d, %v in documentVersionRestore\n", documentId, version, err)
+				return c.JSON(http.StatusInternalServerError, &DocumentRestoreResult{Result: false})
+			}
+		}
+		// 資料の内容・ページ情報・新しいバージョンはまとめて更新する
+		var newVersion int
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if !restoreDocumentVersion(tx, snapshot) || !setDocumentPages(tx, documentId, pages) {
+				return fmt.Errorf("資料のバージョンを復元できません: %d, %d", documentId, version)
+			}
+			ok, created := createDocumentVersion(tx, documentId)
+			if !ok {
+				return fmt.Errorf("資料のバージョンを作成できません: %d", documentId)
+			}
+			newVersion = created
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("Error: %v in documentVersionRestore\n", err)
+			return c.JSON(http.StatusInternalServerError, &DocumentRestoreResult{Result: false})
+		}
+		fmt.Printf("Log: 資料をバージョン%dに戻しました: %d, %d in documentVersionRestore\n", version, documentId, newVersion)
+
+		hub.sendDocumentUpdate(document.MeetingId, documentId, newVersion)
+		return c.JSON(http.StatusOK, &DocumentRestoreResult{Result: true, DocumentVersion: newVersion})
+	}
+}
+
+// restoreDocumentPages は保存済みのPDFからページ情報とレイアウトの簡易図を作り直す
+func restoreDocumentPages(c echo.Context, hub *Hub, store BlobStore, documentId int, fileKey string) ([]Page, error) {
+	ctx := c.Request().Context()
+	reader, _, err := store.Get(ctx, fileKey)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, hub.config.Storage.MaxUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > hub.config.Storage.MaxUploadSize {
+		return nil, fmt.Errorf("ファイルが大きすぎます: %s", fileKey)
+	}
+	pdfPages, err := extractPdfPages(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return storeDocumentPages(ctx, store, documentId, strings.TrimSuffix(fileKey, ".pdf"), pdfPages)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+)
+
+const (
+	QuestionDuplicateMsgType = "question_duplicate"
+	QuestionMergeMsgType     = "question_merge"
+)
+
+// 質問者に提案する重複の候補の最大数
+const maxDuplicateCandidates = 3
+
+// QuestionDuplicateResult は投稿した質問と似た質問があることを質問者に知らせるメッセージ
+// 質問者は question_merge で自分の質問を候補に統合できる
+type QuestionDuplicateResult struct {
+	MessageType string               `json:"messageType"`
+	MeetingId   int                  `json:"meetingId"`
+	QuestionId  int                  `json:"questionId"`
+	Candidates  []DuplicateCandidate `json:"candidates"` // 類似度の高い順
+}
+
+type DuplicateCandidate struct {
+	QuestionId   int     `json:"questionId"`
+	QuestionBody string  `json:"questionBody"`
+	DocumentPage int     `json:"documentPage"`
+	VoteNum      int     `json:"voteNum"`
+	Similarity   float64 `json:"similarity"`
+}
+
+type DuplicateQuestionsResult struct {
+	Result    bool                       `json:"result"`
+	MeetingId int                        `json:"meetingId"`
+	Questions []QuestionModerationResult `json:"questions"` // 古い順，DuplicateOfが重複の候補
+}
+
+// QuestionMergeRequest の質問者はパスワードで確かめる
+type QuestionMergeRequest struct {
+	UserId       string `json:"userId"`
+	UserPassword string `json:"userPassword"`
+	MergeInto    int    `json:"mergeInto"`
+}
+
+// detectDuplicates は同じ資料の公開中の質問からquestionと似たものを探す
+// 見つかった場合は最も似た質問をDuplicateOfに記録する
+func detectDuplicates(db *gorm.DB, threshold float64, question Question) []DuplicateCandidate {
+	if threshold <= 0 {
+		return []DuplicateCandidate{}
+	}
+	similar := findSimilarQuestions(question, getMergeCandidates(db, question.DocumentId), threshold)
+	if len(similar) > maxDuplicateCandidates {
+		similar = similar[:maxDuplicateCandidates]
+	}
+	candidates := make([]DuplicateCandidate, 0, len(similar))
+	for _, s := range similar {
+		candidates = append(candidates, DuplicateCandidate{
+			QuestionId:   s.Question.QuestionId,
+			QuestionBody: s.Question.QuestionBody,
+			DocumentPage: s.Question.DocumentPage,
+			VoteNum:      s.Question.VoteNum,
+			Similarity:   s.Similarity,
+		})
+	}
+	if len(candidates) != 0 {
+		setDuplicateOf(db, question.QuestionId, candidates[0].QuestionId)
+		fmt.Printf("Log: 重複の可能性がある質問です: %d, %d in detectDuplicates\n", question.QuestionId, candidates[0].QuestionId)
+	}
+	return candidates
+}
+
+// sendDuplicates は投稿された質問の重複の候補を質問者に提案し，ホストにも知らせる
+func (c *Client) sendDuplicates(db *gorm.DB, meetingId int, question Question) {
+	candidates := detectDuplicates(db, c.hub.config.Moderator.DuplicateThreshold, question)
+	if len(candidates) == 0 {
+		return
+	}
+	messagejson, _ := json.Marshal(QuestionDuplicateResult{
+		MessageType: QuestionDuplicateMsgType,
+		MeetingId:   meetingId,
+		QuestionId:  question.QuestionId,
+		Candidates:  candidates,
+	})
+	c.hub.sendTo(c, messagejson)
+
+	question.DuplicateOf = candidates[0].QuestionId
+	sendToHosts(c.hub, db, meetingId, questionModerationResult(meetingId, moderationDuplicate, "", question))
+}
+
+// mergeOwnQuestion は質問者が自分の質問を似た質問に統合する
+// 失敗した場合はHTTPのステータスとエラーのcodeを返す
+func mergeOwnQuestion(hub *Hub, db *gorm.DB, userId string, questionId int, mergeInto int) (int, string) {
+	found, question := getQuestion(db, questionId)
+	if !found || question.IsVoice {
+		return http.StatusNotFound, "question_not_found"
+	}
+	if !isQuestionAsker(db, userId, question) {
+		fmt.Printf("Error: 質問者以外は質問を統合できません: %d, %s in mergeOwnQuestion\n", questionId, userId)
+		return http.StatusForbidden, "forbidden"
+	}
+	found, document := getDocument(db, question.DocumentId)
+	if !found {
+		return http.StatusNotFound, "question_not_found"
+	}
+
+	wasPublished := question.ModerationStatus == questionApproved
+	ok, voteNum := mergeQuestion(db, questionId, mergeInto)
+	if !ok {
+		return http.StatusConflict, "invalid_merge"
+	}
+	question.ModerationStatus = questionMerged
+	question.MergedInto = mergeInto
+	questionMergeBroadcast(hub, document.MeetingId, question, wasPublished, voteNum)
+	sendToHosts(hub, db, document.MeetingId, questionModerationResult(document.MeetingId, moderationMerge, question.UserId, question))
+	return http.StatusOK, ""
+}
+
+// duplicateQuestions は会議の重複の可能性がある質問を返す (?userId=ホスト)
+// ホストは question_moderate の merge で統合する
+func duplicateQuestions(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		meetingId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &DuplicateQuestionsResult{Result: false})
+		}
+		if !isHost(db, meetingId, c.QueryParam("userId")) {
+			return c.JSON(http.StatusForbidden, &DuplicateQuestionsResult{Result: false})
+		}
+		questions := getDuplicateQuestions(db, meetingId)
+		result := &DuplicateQuestionsResult{
+			Result:    true,
+			MeetingId: meetingId,
+			Questions: make([]QuestionModerationResult, 0, len(questions)),
+		}
+		for _, question := range questions {
+			result.Questions = append(result.Questions, questionModerationResult(meetingId, moderationDuplicate, "", question))
+		}
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+// questionMerge は質問者が自分の質問を似た質問に統合する
+// WebSocketの question_merge メッセージと同じ操作 (質問者はuserIdとuserPasswordで確かめる)
+func questionMerge(hub *Hub, db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		questionId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		request := new(QuestionMergeRequest)
+		if err := c.Bind(request); err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		if ok, _ := loginUser(db, request.UserId, request.UserPassword); !ok {
+			fmt.Printf("Error: 質問者を確認できません: %d, %s in questionMerge\n", questionId, request.UserId)
+			return c.JSON(http.StatusUnauthorized, &Result{Result: false})
+		}
+		if status, _ := mergeOwnQuestion(hub, db, request.UserId, questionId, request.MergeInto); status != http.StatusOK {
+			return c.JSON(status, &Result{Result: false})
+		}
+		return c.JSON(http.StatusOK, &Result{Result: true})
+	}
+}
@@ -1,255 +1,395 @@
-package main
-
-import (
-	"net/http"
-
-	"github.com/jinzhu/gorm"
-	"github.com/labstack/echo"
-)
-
-type Result struct {
-	Result bool `json:"result"`
-}
-
-type UserSignupRequest struct {
-	UserId       string `json:"userId"`
-	UserName     string `json:"userName"`
-	UserPassword string `json:"userPassword"`
-}
-
-type UserLoginRequest struct {
-	UserId       string `json:"userId"`
-	UserPassword string `json:"userPassword"`
-}
-
-type UserLoginResult struct {
-	Result   bool   `json:"result"`
-	UserName string `json:"userName"`
-}
-
-type CreateMeetingRequest struct {
-	MeetingName      string   `json:"meetingName"`
-	MeetingStartTime string   `json:"meetingStartTime"`
-	PresenterIds     []string `json:"presenterIds"`
-}
-
-type CreateMeetingResult struct {
-	Result      bool   `json:"result"`
-	MeetingId   int    `json:"meetingId"`
-	MeetingName string `json:"meetingName"`
-}
-
-type JoinMeetingRequest struct {
-	UserId    string `json:"userId"`
-	MeetingId int    `json:"meetingId"`
-}
-
-type JoinMeetingResult struct {
-	Result           bool     `json:"result"`
-	MeetingName      string   `json:"meetingName"`
-	MeetingStartTime string   `json:"meetingStartTime"`
-	PresenterNames   []string `json:"presenterNames"`
-	PresenterIds     []string `json:"presenterIds"`
-	DocumentIds      []int    `json:"documentIds"`
-}
-
-type ExitMeetingRequest struct {
-	UserId     string `json:"userId"`
-	MeetingId  int    `json:"meetingId"`
-	DocumentId int    `json:"documentId"`
-}
-
-type ExitMeetingResult struct {
-	Result bool `json:"result"`
-}
-
-type DocumentRegisterRequest struct {
-	DocumentId  int    `json:"documentId"`
-	DocumentUrl string `json:"documentUrl"`
-	Script      string `json:"script"`
-}
-
-type DocumentRegisterResult struct {
-	Result bool `json:"result"`
-}
-
-type DocumentGetRequest struct {
-	DocumentId int `json:"documentId"`
-}
-
-type DocumentGetResult struct {
-	Result      bool   `json:"result"`
-	DocumentUrl string `json:"documentUrl"`
-	Script      string `json:"script"`
-}
-
-type QuestionsGetRequest struct {
-	MeetingId int `json:"meetingId"`
-}
-
-type QuestionsGetResult struct {
-	Result        bool     `json:"result"`
-	MeetingId     int      `json:"meetingId"`
-	QuestionIds   []int    `json:"questionIds"`
-	QuestionBodys []string `json:"questionBodys"`
-	DocumentIds   []int    `json:"documentIds"`
-	DocumentPages []int    `json:"documentPages"`
-	QuestionTimes []string `json:"questionTimes"`
-	PresenterIds  []string `json:"presenterIds"`
-	VoteNums      []int    `json:"voteNums"`
-}
-
-func initRouting(e *echo.Echo, hub *Hub, db *gorm.DB) {
-
-	e.GET("/", func(c echo.Context) error {
-		serveHome(c.Response(), c.Request())
-		return nil
-	})
-
-	e.POST("/user/signup", func(c echo.Context) error {
-		request := new(UserSignupRequest)
-		err := c.Bind(request)
-		if err == nil {
-			result := &Result{
-				Result: signupUser(db, request.UserId, request.UserName, request.UserPassword),
-			}
-
-			return c.JSON(http.StatusOK, result)
-		} else {
-			return c.JSON(http.StatusBadRequest, &Result{Result: false})
-		}
-	})
-
-	e.POST("/user/login", func(c echo.Context) error {
-		request := new(UserLoginRequest)
-		err := c.Bind(request)
-		if err == nil {
-			resultLogin, userName := loginUser(db, request.UserId, request.UserPassword)
-			result := &UserLoginResult{
-				Result:   resultLogin,
-				UserName: userName,
-			}
-
-			return c.JSON(http.StatusOK, result)
-		} else {
-			return c.JSON(http.StatusBadRequest, &UserLoginResult{Result: false, UserName: ""})
-		}
-	})
-
-	e.POST("/meeting/join", func(c echo.Context) error {
-		request := new(JoinMeetingRequest)
-		err := c.Bind(request)
-		if err == nil {
-			resultJoinMeeting, meetingName, meetingStartTime, presenterNames, presenterIds, documentIds := joinMeeting(db, request.UserId, request.MeetingId)
-			layout := "2006/01/02 15:04:05"
-			meetingStartTimeString := meetingStartTime.Format(layout)
-			result := &JoinMeetingResult{
-				Result:           resultJoinMeeting,
-				MeetingName:      meetingName,
-				MeetingStartTime: meetingStartTimeString,
-				PresenterNames:   presenterNames,
-				PresenterIds:     presenterIds,
-				DocumentIds:      documentIds,
-			}
-			if result.Result {
-				go hub.sendStartMeetingMessage(request.MeetingId, meetingStartTime)
-			}
-			return c.JSON(http.StatusOK, result)
-		} else {
-			return c.JSON(http.StatusBadRequest, &Result{Result: false})
-		}
-
-	})
-
-	e.POST("/meeting/exit", func(c echo.Context) error {
-		request := new(ExitMeetingRequest)
-		err := c.Bind(request)
-		if err == nil {
-			resultExitMeeting := exitMeeting(db, request.UserId, request.MeetingId, request.DocumentId)
-			result := &ExitMeetingResult{
-				Result: resultExitMeeting,
-			}
-			return c.JSON(http.StatusOK, result)
-		} else {
-			return c.JSON(http.StatusBadRequest, &Result{Result: false})
-		}
-
-	})
-
-	e.GET("/ws", func(c echo.Context) error {
-		serveWs(hub, c.Response(), c.Request())
-		return nil
-	})
-
-	e.POST("/meeting/create", func(c echo.Context) error {
-		request := new(CreateMeetingRequest)
-		err := c.Bind(request)
-		if err == nil {
-			resultCreateMeeting, meetingId, meetingName := createMeeting(db, request.MeetingName, request.MeetingStartTime, request.PresenterIds)
-			result := &CreateMeetingResult{
-				Result:      resultCreateMeeting,
-				MeetingId:   meetingId,
-				MeetingName: meetingName,
-			}
-
-			return c.JSON(http.StatusOK, result)
-		} else {
-			return c.JSON(http.StatusBadRequest, &Result{Result: false})
-		}
-	})
-
-	e.POST("/document/register", func(c echo.Context) error {
-		request := new(DocumentRegisterRequest)
-		err := c.Bind(request)
-		if err == nil {
-			resultDocumentRegister, meetingId := documentRegister(db, request.DocumentId, request.DocumentUrl, request.Script)
-			result := &DocumentRegisterResult{
-				Result: resultDocumentRegister,
-			}
-			if result.Result {
-				hub.sendDocumentUpdate(meetingId, request.DocumentId)
-			}
-			return c.JSON(http.StatusOK, result)
-		} else {
-			return c.JSON(http.StatusBadRequest, &Result{Result: false})
-		}
-	})
-
-	e.POST("/document/get", func(c echo.Context) error {
-		request := new(DocumentGetRequest)
-		err := c.Bind(request)
-		if err == nil {
-			resultDocumentGet, documentUrl, script := documentGet(db, request.DocumentId)
-			result := &DocumentGetResult{
-				Result:      resultDocumentGet,
-				DocumentUrl: documentUrl,
-				Script:      script,
-			}
-			return c.JSON(http.StatusOK, result)
-		} else {
-			return c.JSON(http.StatusBadRequest, &Result{Result: false})
-		}
-	})
-
-	e.POST("/questions", func(c echo.Context) error {
-		request := new(QuestionsGetRequest)
-		err := c.Bind(request)
-		if err == nil {
-			resultQuestionsGet, meetingId, questionIds, questionBodys, documentIds, documentPages, questionTimes, presenterIds, voteNums := questionsGet(db, request.MeetingId)
-			result := &QuestionsGetResult{
-				Result:        resultQuestionsGet,
-				MeetingId:     meetingId,
-				QuestionIds:   questionIds,
-				QuestionBodys: questionBodys,
-				DocumentIds:   documentIds,
-				DocumentPages: documentPages,
-				QuestionTimes: questionTimes,
-				PresenterIds:  presenterIds,
-				VoteNums:      voteNums,
-			}
-			return c.JSON(http.StatusOK, result)
-		} else {
-			return c.JSON(http.StatusBadRequest, &Result{Result: false})
-		}
-	})
-}
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type Result struct {
+	Result bool `json:"result"`
+}
+
+type UserSignupRequest struct {
+	UserId       string `json:"userId"`
+	UserName     string `json:"userName"`
+	UserPassword string `json:"userPassword"`
+}
+
+type UserLoginRequest struct {
+	UserId       string `json:"userId"`
+	UserPassword string `json:"userPassword"`
+}
+
+type UserLoginResult struct {
+	Result   bool   `json:"result"`
+	UserName string `json:"userName"`
+}
+
+type CreateMeetingRequest struct {
+	MeetingName      string   `json:"meetingName"`
+	MeetingStartTime string   `json:"meetingStartTime"`
+	PresenterIds     []string `json:"presenterIds"`
+	HostId           string   `json:"hostId"`
+	PreModeration    bool     `json:"preModeration"`  // 質問をホストが承認してから公開する
+	AnonymityLevel   string   `json:"anonymityLevel"` // attributed, anonymous (デフォルト), full
+}
+
+type CreateMeetingResult struct {
+	Result      bool   `json:"result"`
+	MeetingId   int    `json:"meetingId"`
+	MeetingName string `json:"meetingName"`
+}
+
+type JoinMeetingRequest struct {
+	UserId    string `json:"userId"`
+	MeetingId int    `json:"meetingId"`
+}
+
+type JoinMeetingResult struct {
+	Result           bool     `json:"result"`
+	MeetingName      string   `json:"meetingName"`
+	MeetingStartTime string   `json:"meetingStartTime"`
+	PresenterNames   []string `json:"presenterNames"`
+	PresenterIds     []string `json:"presenterIds"`
+	DocumentIds      []int    `json:"documentIds"`
+	AnonymityLevel   string   `json:"anonymityLevel"` // 質問者を誰に見せるか
+}
+
+type ExitMeetingRequest struct {
+	UserId     string `json:"userId"`
+	MeetingId  int    `json:"meetingId"`
+	DocumentId int    `json:"documentId"`
+}
+
+type ExitMeetingResult struct {
+	Result bool `json:"result"`
+}
+
+type DocumentRegisterRequest struct {
+	DocumentId     int                   `json:"documentId"`
+	DocumentUrl    string                `json:"documentUrl"`
+	Script         string                `json:"script"`
+	ScriptSegments []ScriptSegmentObject `json:"scriptSegments"` // Scriptの代わりにページ毎に指定する
+}
+
+type ScriptSegmentObject struct {
+	DocumentPage int    `json:"documentPage"`
+	Script       string `json:"script"` // Markdown
+}
+
+type DocumentRegisterResult struct {
+	Result          bool `json:"result"`
+	DocumentVersion int  `json:"documentVersion"`
+}
+
+type DocumentGetRequest struct {
+	DocumentId int `json:"documentId"`
+}
+
+type DocumentGetResult struct {
+	Result         bool                  `json:"result"`
+	DocumentUrl    string                `json:"documentUrl"`
+	Script         string                `json:"script"`
+	ScriptSegments []ScriptSegmentObject `json:"scriptSegments"` // ページ順
+}
+
+type CurrentPageResult struct {
+	Result        bool   `json:"result"`
+	MeetingId     int    `json:"meetingId"`
+	PresenterId   string `json:"presenterId"`
+	DocumentId    int    `json:"documentId"`
+	DocumentPage  int    `json:"documentPage"`
+	PageChangedAt string `json:"pageChangedAt"`
+}
+
+type QuestionsGetRequest struct {
+	MeetingId int `json:"meetingId"`
+}
+
+type QuestionsGetResult struct {
+	Result        bool     `json:"result"`
+	MeetingId     int      `json:"meetingId"`
+	QuestionIds   []int    `json:"questionIds"`
+	QuestionBodys []string `json:"questionBodys"`
+	DocumentIds   []int    `json:"documentIds"`
+	DocumentPages []int    `json:"documentPages"`
+	QuestionTimes []string `json:"questionTimes"`
+	PresenterIds  []string `json:"presenterIds"`
+	VoteNums      []int    `json:"voteNums"`
+	AskerIds      []string `json:"askerIds"` // 質問者を明かす会議のみ (それ以外は空文字列)
+
+	Threads []QuestionThread `json:"threads"` // QuestionIdsと同じ順
+}
+
+func initRouting(e *echo.Echo, hub *Hub, db *gorm.DB, store BlobStore) {
+	loginLimiter := newRateLimiter()
+
+	initRoutingV2(e.Group("/api/v2"), hub, db, loginLimiter)
+
+	e.GET("/", func(c echo.Context) error {
+		serveHome(c.Response(), c.Request())
+		return nil
+	})
+
+	e.POST("/user/signup", func(c echo.Context) error {
+		request := new(UserSignupRequest)
+		err := c.Bind(request)
+		if err == nil {
+			result := &Result{
+				Result: signupUser(db, request.UserId, request.UserName, request.UserPassword),
+			}
+
+			return c.JSON(http.StatusOK, result)
+		} else {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+	})
+
+	e.POST("/user/login", func(c echo.Context) error {
+		request := new(UserLoginRequest)
+		err := c.Bind(request)
+		if err == nil {
+			// 総当たり対策としてIPアドレス毎，ユーザーID毎に制限する
+			ipOk, _ := loginLimiter.allow("ip/"+c.RealIP(), hub.config.RateLimit.Login)
+			userOk, _ := loginLimiter.allow("user/"+request.UserId, hub.config.RateLimit.Login)
+			if !ipOk || !userOk {
+				fmt.Printf("Log: ログイン試行の上限に達しました: %s, %s in /user/login\n", c.RealIP(), request.UserId)
+				throttledEvents.WithLabelValues("login", throttleRateLimited).Inc()
+				return c.JSON(http.StatusTooManyRequests, &UserLoginResult{Result: false, UserName: ""})
+			}
+			resultLogin, userName := loginUser(db, request.UserId, request.UserPassword)
+			result := &UserLoginResult{
+				Result:   resultLogin,
+				UserName: userName,
+			}
+
+			return c.JSON(http.StatusOK, result)
+		} else {
+			return c.JSON(http.StatusBadRequest, &UserLoginResult{Result: false, UserName: ""})
+		}
+	})
+
+	e.POST("/meeting/join", func(c echo.Context) error {
+		request := new(JoinMeetingRequest)
+		err := c.Bind(request)
+		if err == nil {
+			resultJoinMeeting, meetingName, meetingStartTime, presenterNames, presenterIds, documentIds := joinMeeting(db, request.UserId, request.MeetingId)
+			layout := "2006/01/02 15:04:05"
+			meetingStartTimeString := meetingStartTime.Format(layout)
+			result := &JoinMeetingResult{
+				Result:           resultJoinMeeting,
+				MeetingName:      meetingName,
+				MeetingStartTime: meetingStartTimeString,
+				PresenterNames:   presenterNames,
+				PresenterIds:     presenterIds,
+				DocumentIds:      documentIds,
+			}
+			if result.Result {
+				_, meeting := getMeeting(db, request.MeetingId)
+				result.AnonymityLevel = meeting.AnonymityLevel
+				go hub.sendStartMeetingMessage(request.MeetingId, meetingStartTime)
+			}
+			return c.JSON(http.StatusOK, result)
+		} else {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+
+	})
+
+	e.POST("/meeting/exit", func(c echo.Context) error {
+		request := new(ExitMeetingRequest)
+		err := c.Bind(request)
+		if err == nil {
+			resultExitMeeting := exitMeeting(db, request.UserId, request.MeetingId, request.DocumentId)
+			result := &ExitMeetingResult{
+				Result: resultExitMeeting,
+			}
+			if result.Result {
+				// 退出した参加者の挙手は下げられる
+				handsQueueBroadcast(hub, db, request.DocumentId)
+			}
+			return c.JSON(http.StatusOK, result)
+		} else {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+
+	})
+
+	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+
+	e.GET("/openapi.json", openAPISpec)
+
+	e.GET("/asyncapi.json", asyncAPISpec)
+
+	e.GET("/healthz", healthz)
+
+	e.GET("/readyz", readyz(hub, db))
+
+	e.GET("/ws", func(c echo.Context) error {
+		serveWs(hub, c.Response(), c.Request(), c.RealIP())
+		return nil
+	})
+
+	e.POST("/meeting/create", func(c echo.Context) error {
+		request := new(CreateMeetingRequest)
+		err := c.Bind(request)
+		if err == nil {
+			resultCreateMeeting, meetingId, meetingName := createMeeting(db, request.MeetingName, request.MeetingStartTime, request.PresenterIds, request.HostId, request.PreModeration, request.AnonymityLevel)
+			result := &CreateMeetingResult{
+				Result:      resultCreateMeeting,
+				MeetingId:   meetingId,
+				MeetingName: meetingName,
+			}
+
+			return c.JSON(http.StatusOK, result)
+		} else {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+	})
+
+	e.GET("/meeting/:id/report", meetingReport(db))
+
+	e.GET("/meeting/:id/unanswered", unansweredQuestions(db))
+
+	e.GET("/meeting/:id/moderation", moderationQueue(db))
+
+	e.GET("/meeting/:id/duplicates", duplicateQuestions(db))
+
+	e.POST("/meeting/:id/moderation", meetingPreModeration(db))
+
+	e.POST("/question/:id/moderate", questionModerate(hub, db))
+
+	e.POST("/question/:id/merge", questionMerge(hub, db))
+
+	e.POST("/question/:id/edit", questionEditHandler(hub, db))
+
+	e.POST("/question/:id/withdraw", questionWithdrawHandler(hub, db))
+
+	e.GET("/question/:id/history", questionHistory(db))
+
+	e.POST("/question/:id/followup", followUpAnswer(hub, db))
+
+	e.GET("/document/:id/hands", documentHands(db))
+
+	e.POST("/document/:id/hands/moderate", handsModerate(hub, db))
+
+	e.GET("/document/:id/reactions", documentReactions(db))
+
+	e.GET("/user/:id/inbox", inbox(db))
+
+	e.POST("/user/:id/inbox/:messageId/read", inboxRead(db))
+
+	// 途中から参加した人が発表者の表示しているページに合わせるために使う
+	e.GET("/meeting/:id/page", func(c echo.Context) error {
+		meetingId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &CurrentPageResult{Result: false})
+		}
+		found, state := getModeratorState(db, meetingId)
+		if !found {
+			return c.JSON(http.StatusNotFound, &CurrentPageResult{Result: false, MeetingId: meetingId})
+		}
+		location, _ := time.LoadLocation("Asia/Tokyo")
+		result := &CurrentPageResult{
+			Result:        true,
+			MeetingId:     meetingId,
+			PresenterId:   state.PresenterId,
+			DocumentId:    state.DocumentId,
+			DocumentPage:  state.DocumentPage,
+			PageChangedAt: state.PageChangedAt.In(location).Format("2006/01/02 15:04:05"),
+		}
+		return c.JSON(http.StatusOK, result)
+	})
+
+	e.POST("/document/register", func(c echo.Context) error {
+		request := new(DocumentRegisterRequest)
+		err := c.Bind(request)
+		if err == nil {
+			segments := make([]ScriptSegment, 0, len(request.ScriptSegments))
+			for _, segment := range request.ScriptSegments {
+				segments = append(segments, ScriptSegment{DocumentPage: segment.DocumentPage, Script: segment.Script})
+			}
+			resultDocumentRegister, meetingId, documentVersion := documentRegister(db, request.DocumentId, request.DocumentUrl, request.Script, segments)
+			result := &DocumentRegisterResult{
+				Result:          resultDocumentRegister,
+				DocumentVersion: documentVersion,
+			}
+			if result.Result {
+				hub.sendDocumentUpdate(meetingId, request.DocumentId, documentVersion)
+			}
+			return c.JSON(http.StatusOK, result)
+		} else {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+	})
+
+	e.POST("/document/:id/upload", documentUpload(hub, db, store))
+
+	e.GET("/document/:id/file", documentFile(db, store))
+
+	e.GET("/document/:id/pages", documentPages(db))
+
+	e.GET("/document/:id/pages/:page/layout", documentPageLayout(db, store))
+
+	e.GET("/document/:id/versions", documentVersions(db))
+
+	e.GET("/document/:id/analytics", documentAnalytics(db))
+
+	e.GET("/document/:id/versions/:version", documentVersionGet(db))
+
+	e.POST("/document/:id/versions/:version/restore", documentVersionRestore(hub, db, store))
+
+	e.POST("/document/get", func(c echo.Context) error {
+		request := new(DocumentGetRequest)
+		err := c.Bind(request)
+		if err == nil {
+			resultDocumentGet, documentUrl, script, segments := documentGet(db, request.DocumentId)
+			scriptSegments := make([]ScriptSegmentObject, 0, len(segments))
+			for _, segment := range segments {
+				scriptSegments = append(scriptSegments, ScriptSegmentObject{DocumentPage: segment.DocumentPage, Script: segment.Script})
+			}
+			result := &DocumentGetResult{
+				Result:         resultDocumentGet,
+				DocumentUrl:    documentUrl,
+				Script:         script,
+				ScriptSegments: scriptSegments,
+			}
+			return c.JSON(http.StatusOK, result)
+		} else {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+	})
+
+	e.GET("/meetings/:id/questions", meetingQuestions(db))
+
+	e.POST("/questions", func(c echo.Context) error {
+		request := new(QuestionsGetRequest)
+		err := c.Bind(request)
+		if err == nil {
+			resultQuestionsGet, meetingId, questionIds, questionBodys, documentIds, documentPages, questionTimes, presenterIds, voteNums, askerIds := questionsGet(db, request.MeetingId)
+			result := &QuestionsGetResult{
+				Result:        resultQuestionsGet,
+				MeetingId:     meetingId,
+				QuestionIds:   questionIds,
+				QuestionBodys: questionBodys,
+				DocumentIds:   documentIds,
+				DocumentPages: documentPages,
+				QuestionTimes: questionTimes,
+				PresenterIds:  presenterIds,
+				VoteNums:      voteNums,
+				AskerIds:      askerIds,
+			}
+			answers, replies := getQuestionThreads(db, questionIds)
+			result.Threads = buildQuestionThreads(questionIds, answers, replies)
+			return c.JSON(http.StatusOK, result)
+		} else {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jinzhu/gorm"
+)
+
+// DBの操作が失敗した理由の種類 (v2 APIではHTTPのステータスに対応させる)
+var (
+	errNotFound     = errors.New("not found")
+	errConflict     = errors.New("conflict")
+	errInvalid      = errors.New("invalid")
+	errForbidden    = errors.New("forbidden")
+	errUnauthorized = errors.New("unauthorized")
+)
+
+// StoreError はDBの操作が失敗した理由
+// errors.Is(err, errNotFound) のように種類で判定できる
+type StoreError struct {
+	Kind    error                  // errNotFound など (nilはDBのエラー)
+	Code    string                 // "meeting_not_found" など
+	Message string                 // 利用者向けの説明 (英語)
+	Details map[string]interface{} // 失敗した項目など (無ければnil)
+	Err     error                  // 元のエラー (無ければnil)
+}
+
+func (e *StoreError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *StoreError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+func (e *StoreError) Unwrap() error {
+	return e.Err
+}
+
+func notFoundError(resource string, id interface{}) *StoreError {
+	return &StoreError{
+		Kind:    errNotFound,
+		Code:    resource + "_not_found",
+		Message: resource + " not found",
+		Details: map[string]interface{}{"id": id},
+	}
+}
+
+func invalidError(code string, message string, details map[string]interface{}) *StoreError {
+	return &StoreError{Kind: errInvalid, Code: code, Message: message, Details: details}
+}
+
+// dbError はgormのエラーをStoreErrorにする (レコードが無い場合はnot_found)
+func dbError(err error, resource string, id interface{}) *StoreError {
+	if gorm.IsRecordNotFoundError(err) {
+		return notFoundError(resource, id)
+	}
+	return &StoreError{Code: "internal_error", Message: "database error", Err: err}
+}
+
+// statusError は (HTTPのステータス, code) を返す操作の失敗をStoreErrorにする
+func statusError(status int, code string) *StoreError {
+	storeErr := &StoreError{Code: code, Message: http.StatusText(status)}
+	switch status {
+	case http.StatusNotFound:
+		storeErr.Kind = errNotFound
+	case http.StatusConflict:
+		storeErr.Kind = errConflict
+	case http.StatusBadRequest:
+		storeErr.Kind = errInvalid
+	case http.StatusForbidden:
+		storeErr.Kind = errForbidden
+	case http.StatusUnauthorized:
+		storeErr.Kind = errUnauthorized
+	}
+	return storeErr
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+)
+
+const FollowUpAnswerMsgType = "followup_answer"
+
+// FollowUpAnswerResult は事後の回答を質問者に知らせるメッセージ
+type FollowUpAnswerResult struct {
+	MessageType  string `json:"messageType"`
+	MeetingId    int    `json:"meetingId"`
+	QuestionId   int    `json:"questionId"`
+	QuestionBody string `json:"questionBody"`
+	DocumentId   int    `json:"documentId"`
+	DocumentPage int    `json:"documentPage"`
+	AnswerBody   string `json:"answerBody"`
+	AnsweredBy   string `json:"answeredBy"`
+	AnsweredAt   string `json:"answeredAt"`
+}
+
+type UnansweredQuestionsResult struct {
+	Result    bool                       `json:"result"`
+	MeetingId int                        `json:"meetingId"`
+	Questions []UnansweredQuestionResult `json:"questions"` // 投票数の多い順
+}
+
+type UnansweredQuestionResult struct {
+	QuestionId   int    `json:"questionId"`
+	QuestionBody string `json:"questionBody"`
+	DocumentId   int    `json:"documentId"`
+	DocumentPage int    `json:"documentPage"`
+	VoteNum      int    `json:"voteNum"`
+	QuestionTime string `json:"questionTime"`
+}
+
+type FollowUpAnswerRequest struct {
+	UserId     string `json:"userId"`
+	AnswerBody string `json:"answerBody"`
+}
+
+type FollowUpAnswerResponse struct {
+	Result   bool `json:"result"`
+	AnswerId int  `json:"answerId"`
+}
+
+// unansweredQuestions は発表者の資料への未回答の質問を返す (?userId=発表者)
+// 質問者は会議の匿名性の設定に関わらず返さない
+func unansweredQuestions(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		meetingId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &UnansweredQuestionsResult{Result: false})
+		}
+		userId := c.QueryParam("userId")
+		documentId := getDocumentId(db, userId, meetingId)
+		if documentId == -1 {
+			fmt.Printf("Error: 発表者ではありません: %d, %s in unansweredQuestions\n", meetingId, userId)
+			return c.JSON(http.StatusForbidden, &UnansweredQuestionsResult{Result: false})
+		}
+
+		location, _ := time.LoadLocation("Asia/Tokyo")
+		questions := getUnansweredQuestions(db, documentId)
+		result := &UnansweredQuestionsResult{
+			Result:    true,
+			MeetingId: meetingId,
+			Questions: make([]UnansweredQuestionResult, 0, len(questions)),
+		}
+		for _, question := range questions {
+			result.Questions = append(result.Questions, UnansweredQuestionResult{
+				QuestionId:   question.QuestionId,
+				QuestionBody: question.QuestionBody,
+				DocumentId:   question.DocumentId,
+				DocumentPage: question.DocumentPage,
+				VoteNum:      question.VoteNum,
+				QuestionTime: question.QuestionTime.In(location).Format("2006/01/02 15:04:05"),
+			})
+		}
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+// followUpAnswer は発表者(またはホスト)が未回答の質問に文章で回答する
+// 質問者が接続していればWebSocketで，していなければ受信箱に届ける
+func followUpAnswer(hub *Hub, db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		questionId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &FollowUpAnswerResponse{Result: false})
+		}
+		request := new(FollowUpAnswerRequest)
+		if err := c.Bind(request); err != nil || strings.TrimSpace(request.AnswerBody) == "" {
+			return c.JSON(http.StatusBadRequest, &FollowUpAnswerResponse{Result: false})
+		}
+		found, question := getQuestion(db, questionId)
+		if !found {
+			return c.JSON(http.StatusNotFound, &FollowUpAnswerResponse{Result: false})
+		}
+		found, document := getDocument(db, question.DocumentId)
+		if !found {
+			return c.JSON(http.StatusNotFound, &FollowUpAnswerResponse{Result: false})
+		}
+		if request.UserId != document.UserId && !isHost(db, document.MeetingId, request.UserId) {
+			fmt.Printf("Error: 発表者とホスト以外は回答できません: %d, %s in followUpAnswer\n", questionId, request.UserId)
+			return c.JSON(http.StatusForbidden, &FollowUpAnswerResponse{Result: false})
+		}
+		if question.QuestionOk || question.IsVoice || question.ModerationStatus != questionApproved {
+			fmt.Printf("Error: 未回答の質問ではありません: %d in followUpAnswer\n", questionId)
+			return c.JSON(http.StatusConflict, &FollowUpAnswerResponse{Result: false})
+		}
+
+		ok, answer := createFollowUpAnswer(db, questionId, meetingParticipantId(db, question.DocumentId, request.UserId), request.AnswerBody)
+		if !ok {
+			return c.JSON(http.StatusConflict, &FollowUpAnswerResponse{Result: false})
+		}
+
+		_, meeting := getMeeting(db, document.MeetingId)
+		result := FollowUpAnswerResult{
+			MessageType:  FollowUpAnswerMsgType,
+			MeetingId:    document.MeetingId,
+			QuestionId:   questionId,
+			QuestionBody: question.QuestionBody,
+			DocumentId:   question.DocumentId,
+			DocumentPage: question.DocumentPage,
+			AnswerBody:   answer.AnswerBody,
+			AnsweredBy:   answer.UserId,
+			AnsweredAt:   answer.AnsweredAt.Format("2006/01/02 15:04:05"),
+		}
+		// 匿名の会議では，受信箱から質問者が分からないよう質問の本文を残さない
+		inboxResult := result
+		if meeting.AnonymityLevel != anonymityAttributed {
+			inboxResult.QuestionBody = ""
+		}
+		// 完全匿名の会議ではquestion.UserIdは質問者の仮名になる
+		notifyAsker(hub, db, meeting, question.UserId, FollowUpAnswerMsgType, result, inboxResult)
+		return c.JSON(http.StatusOK, &FollowUpAnswerResponse{Result: true, AnswerId: answer.AnswerId})
+	}
+}
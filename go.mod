@@ -10,5 +10,10 @@ require (
 	github.com/joho/godotenv v1.4.0
 	github.com/labstack/echo v3.3.10+incompatible
 	github.com/labstack/gommon v0.3.0 // indirect
+	github.com/ledongthuc/pdf v0.0.0-20220302134840-0c2507a12d80
+	github.com/minio/minio-go/v7 v7.0.12
+	github.com/prometheus/client_golang v1.11.0
 	golang.org/x/crypto v0.0.0-20210711020723-a769d52b0f97 // indirect
+	golang.org/x/text v0.3.3
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+)
+
+const (
+	HandsQueueMsgType    = "hands_queue"
+	HandsModerateMsgType = "hands_moderate"
+)
+
+// ホストによる挙手の操作
+const (
+	handsMove  = "move"  // 順番を変える
+	handsCall  = "call"  // 順番に関係なく当てる
+	handsLower = "lower" // 挙手を下げる
+)
+
+// HandsQueueResult は発表者の枠への挙手の順番
+// 挙手・取り下げ・ホストの操作・司会による指名の度に会議の全員に送る
+type HandsQueueResult struct {
+	MessageType string       `json:"messageType"`
+	MeetingId   int          `json:"meetingId"`
+	DocumentId  int          `json:"documentId"`
+	PresenterId string       `json:"presenterId"`
+	Hands       []HandResult `json:"hands"` // 当てる順
+}
+
+type HandResult struct {
+	UserId       string `json:"userId"`
+	UserName     string `json:"userName"`
+	Position     int    `json:"position"` // 1から
+	DocumentPage int    `json:"documentPage"`
+	RaisedAt     string `json:"raisedAt"`
+}
+
+type HandsResult struct {
+	Result      bool         `json:"result"`
+	MeetingId   int          `json:"meetingId"`
+	DocumentId  int          `json:"documentId"`
+	PresenterId string       `json:"presenterId"`
+	Hands       []HandResult `json:"hands"` // 当てる順
+}
+
+type HandsModerateRequest struct {
+	HostId   string `json:"hostId"`
+	UserId   string `json:"userId"` // 操作する挙手の参加者
+	Action   string `json:"action"` // move, call, lower
+	Position int    `json:"position"`
+}
+
+func handResults(db *gorm.DB, queue []Question) []HandResult {
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	hands := make([]HandResult, 0, len(queue))
+	for i, hand := range queue {
+		hands = append(hands, HandResult{
+			UserId:       hand.UserId,
+			UserName:     getUserName(db, hand.UserId),
+			Position:     i + 1,
+			DocumentPage: hand.DocumentPage,
+			RaisedAt:     hand.QuestionTime.In(location).Format("2006/01/02 15:04:05"),
+		})
+	}
+	return hands
+}
+
+// handPosition はuserIdの挙手の順番を返す (挙手していなければ0)
+func handPosition(queue []Question, userId string) int {
+	for i, hand := range queue {
+		if hand.UserId == userId {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// handsQueueBroadcast は資料への挙手の順番を会議に送る
+func handsQueueBroadcast(hub *Hub, db *gorm.DB, documentId int) {
+	found, document := getDocument(db, documentId)
+	if !found {
+		return
+	}
+	messagejson, _ := json.Marshal(HandsQueueResult{
+		MessageType: HandsQueueMsgType,
+		MeetingId:   document.MeetingId,
+		DocumentId:  documentId,
+		PresenterId: document.UserId,
+		Hands:       handResults(db, getHandsQueue(db, documentId)),
+	})
+	hub.sendToMeeting(document.MeetingId, messagejson, false)
+}
+
+// lowerPresenterHands は発表者が交代する際に，その発表者への挙手を下げる
+func lowerPresenterHands(hub *Hub, db *gorm.DB, meetingId int, presenterId string) {
+	documentId := getDocumentId(db, presenterId, meetingId)
+	if documentId == -1 || len(getHandsQueue(db, documentId)) == 0 {
+		return
+	}
+	if lowerHands(db, documentId) {
+		handsQueueBroadcast(hub, db, documentId)
+	}
+}
+
+// moderateHand はホストによる挙手の並べ替え・指名・取り下げを行う
+// 失敗した場合はHTTPのステータスとエラーのcodeを返す
+func moderateHand(hub *Hub, db *gorm.DB, documentId int, request HandsModerateRequest) (int, string) {
+	found, document := getDocument(db, documentId)
+	if !found {
+		return http.StatusNotFound, "document_not_found"
+	}
+	meetingId := document.MeetingId
+	if !isHost(db, meetingId, request.HostId) {
+		fmt.Printf("Error: ホスト以外は挙手を操作できません: %d, %s in moderateHand\n", meetingId, request.HostId)
+		return http.StatusForbidden, "forbidden"
+	}
+	queue := getHandsQueue(db, documentId)
+	if handPosition(queue, request.UserId) == 0 {
+		return http.StatusNotFound, "hand_not_found"
+	}
+
+	switch request.Action {
+	case handsMove:
+		if request.Position < 1 || request.Position > len(queue) {
+			return http.StatusBadRequest, "invalid_hands_moderation"
+		}
+		if !moveHand(db, documentId, request.UserId, request.Position) {
+			return http.StatusConflict, "invalid_hands_moderation"
+		}
+	case handsCall:
+		ok, questionId := callHand(db, meetingId, documentId, request.UserId)
+		if !ok {
+			return http.StatusConflict, "invalid_hands_moderation"
+		}
+		body := fmt.Sprintf(questionPersonMessage, getUserName(db, request.UserId))
+		messagejson, _ := json.Marshal(ModeratorMsg{
+			MessageType:      ModeratorMsgType,
+			MeetingId:        meetingId,
+			ModeratorMsgBody: body,
+			QuestionId:       questionId,
+			QuestionUserId:   request.UserId,
+			PresentOrder:     -1,
+		})
+		saveModeratorMessage(db, meetingId, body)
+		hub.sendToMeeting(meetingId, messagejson, false)
+	case handsLower:
+		if !lowerHands(db, documentId, request.UserId) {
+			return http.StatusConflict, "invalid_hands_moderation"
+		}
+	default:
+		return http.StatusBadRequest, "invalid_hands_moderation"
+	}
+
+	handsQueueBroadcast(hub, db, documentId)
+	return http.StatusOK, ""
+}
+
+// documentHands は資料への挙手の順番を返す
+// 会議の参加者のみ取得できる (?userId=...)
+func documentHands(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		documentId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &HandsResult{Result: false})
+		}
+		found, document := getDocument(db, documentId)
+		if !found {
+			return c.JSON(http.StatusNotFound, &HandsResult{Result: false})
+		}
+		if !isMeetingMember(db, document.MeetingId, c.QueryParam("userId")) {
+			return c.JSON(http.StatusForbidden, &HandsResult{Result: false})
+		}
+		return c.JSON(http.StatusOK, &HandsResult{
+			Result:      true,
+			MeetingId:   document.MeetingId,
+			DocumentId:  documentId,
+			PresenterId: document.UserId,
+			Hands:       handResults(db, getHandsQueue(db, documentId)),
+		})
+	}
+}
+
+// handsModerate はホストが挙手を並べ替え・指名・取り下げる
+// WebSocketの hands_moderate メッセージと同じ操作
+func handsModerate(hub *Hub, db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		documentId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		request := new(HandsModerateRequest)
+		if err := c.Bind(request); err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		if status, _ := moderateHand(hub, db, documentId, *request); status != http.StatusOK {
+			return c.JSON(status, &Result{Result: false})
+		}
+		return c.JSON(http.StatusOK, &Result{Result: true})
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+)
+
+const (
+	// readyzの各チェックのタイムアウト
+	readyCheckTimeout = 2 * time.Second
+
+	// 停止中に開始時刻を過ぎた会議も，この時間内なら開始通知を送り直す
+	missedStartGrace = 10 * time.Minute
+)
+
+type HealthResult struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// healthz はプロセスが生きているかだけを返す
+func healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, &HealthResult{Status: "ok"})
+}
+
+// readyz はDBとHubのゴルーチンが応答するかを確認する
+func readyz(hub *Hub, db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var (
+			checks = map[string]string{}
+			status = http.StatusOK
+		)
+
+		ctx, cancel := context.WithTimeout(c.Request().Context(), readyCheckTimeout)
+		defer cancel()
+		if err := pingDB(db, ctx); err != nil {
+			checks["db"] = err.Error()
+			status = http.StatusServiceUnavailable
+		} else {
+			checks["db"] = "ok"
+		}
+
+		if hub.alive(readyCheckTimeout) {
+			checks["hub"] = "ok"
+		} else {
+			checks["hub"] = "not responding"
+			status = http.StatusServiceUnavailable
+		}
+
+		if status != http.StatusOK {
+			return c.JSON(status, &HealthResult{Status: "unavailable", Checks: checks})
+		}
+		return c.JSON(status, &HealthResult{Status: "ok", Checks: checks})
+	}
+}
+
+// reschedulePendingMeetings は再起動で失われた開始通知の予約をやり直す
+func reschedulePendingMeetings(hub *Hub, db *gorm.DB) {
+	meetings := getPendingMeetings(db, time.Now().Add(-missedStartGrace))
+	for _, m := range meetings {
+		go hub.sendStartMeetingMessage(m.MeetingId, m.MeetingStartTime)
+	}
+	fmt.Printf("Log: %d件の会議の開始通知を再予約しました in reschedulePendingMeetings\n", len(meetings))
+}
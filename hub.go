@@ -1,58 +1,324 @@
-// Copyright 2013 The Gorilla WebSocket Authors. All rights reserved.
-// Use of this source code is governed by a BSD-style
-// license that can be found in the LICENSE file.
-
-package main
-
-import "fmt"
-
-// Hub maintains the set of active clients and broadcasts messages to the
-// clients.
-type Hub struct {
-	// Registered clients.
-	clients map[*Client]bool
-
-	// Inbound messages from the clients.
-	broadcast chan []byte
-
-	// Register requests from the clients.
-	register chan *Client
-
-	// Unregister requests from clients.
-	unregister chan *Client
-}
-
-func newHub() *Hub {
-	return &Hub{
-		broadcast:  make(chan []byte),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		clients:    make(map[*Client]bool),
-	}
-}
-
-func (h *Hub) run() {
-	for {
-		// 種別によって場合分け(登録，削除，ブロードキャスト)
-		select {
-		case client := <-h.register:
-			h.clients[client] = true
-		case client := <-h.unregister:
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-				fmt.Println("Warning: unregisterによりWeb SocketをCloseしました in run(hub.go)")
-			}
-		case message := <-h.broadcast:
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					fmt.Println("Warning: broadcastによりWeb SocketをCloseしました in run(hub.go)")
-					delete(h.clients, client)
-				}
-			}
-		}
-	}
-}
+// Copyright 2013 The Gorilla WebSocket Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Hub maintains the set of active clients and broadcasts messages to the
+// clients.
+type Hub struct {
+	config *Config
+
+	upgrader websocket.Upgrader
+
+	limiter *connLimiter
+
+	throttle *messageThrottle
+
+	// 発表者へのペースの提案の間隔
+	pace *cooldown
+
+	// 発表者・ホストへの通知の間隔
+	notified *cooldown
+
+	// Registered clients.
+	clients map[*Client]bool
+
+	// Inbound messages from the clients.
+	broadcast chan []byte
+
+	// Messages for a single client.
+	direct chan *directMessage
+
+	// Messages for the clients in a single meeting.
+	multicast chan *multicastMessage
+
+	// Messages for every connection of a single user.
+	user chan *userMessage
+
+	// Register requests from the clients.
+	register chan *Client
+
+	// Unregister requests from clients.
+	unregister chan *Client
+
+	// Liveness probes from /readyz. run() replies on the given channel.
+	ping chan chan struct{}
+
+	// Closed when the server starts shutting down.
+	quit     chan struct{}
+	quitOnce sync.Once
+
+	// Closed by run() once every client has been sent the shutdown frame.
+	stopped chan struct{}
+
+	// Running writePump goroutines, waited on during shutdown.
+	writers sync.WaitGroup
+}
+
+// directMessage は特定のクライアントにだけ送るメッセージ
+type directMessage struct {
+	client  *Client
+	message []byte
+}
+
+// multicastMessage は会議の参加者にだけ送るメッセージ
+// 接続時に会議を指定していないクライアントには従来通り全て送る
+type multicastMessage struct {
+	meetingId     int
+	message       []byte
+	followersOnly bool // 発表者のページに追従しないクライアントには送らない
+}
+
+// userMessage は接続時にuserIdを指定した利用者の全ての接続に送るメッセージ
+// meetingIdが0でなければ，その会議を指定した接続にだけ送る
+type userMessage struct {
+	meetingId int
+	match     func(userId string) bool // 送る利用者か
+	message   []byte
+	delivered chan int // 送れた接続の数
+}
+
+func newHub(config *Config) *Hub {
+	return &Hub{
+		config:     config,
+		upgrader:   newUpgrader(config.AllowedOrigins),
+		limiter:    newConnLimiter(config.WebSocket),
+		throttle:   newMessageThrottle(config.RateLimit.Messages),
+		pace:       newCooldown(),
+		notified:   newCooldown(),
+		direct:     make(chan *directMessage),
+		multicast:  make(chan *multicastMessage),
+		user:       make(chan *userMessage),
+		broadcast:  make(chan []byte),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		clients:    make(map[*Client]bool),
+		ping:       make(chan chan struct{}),
+		quit:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+}
+
+func (h *Hub) run() {
+	quit := h.quit
+	stopping := false
+	for {
+		// 種別によって場合分け(登録，削除，ブロードキャスト)
+		select {
+		case client := <-h.register:
+			if stopping {
+				close(client.send)
+				h.limiter.release(client)
+				continue
+			}
+			h.clients[client] = true
+			wsClients.WithLabelValues(meetingLabel(client.meetingId)).Inc()
+		case client := <-h.unregister:
+			if _, ok := h.clients[client]; ok {
+				h.removeClient(client)
+				fmt.Println("Warning: unregisterによりWeb SocketをCloseしました in run(hub.go)")
+			}
+		case message := <-h.broadcast:
+			queued := 0
+			for client := range h.clients {
+				select {
+				case client.send <- message:
+					queued += len(client.send)
+				default:
+					h.removeClient(client)
+					fmt.Println("Warning: broadcastによりWeb SocketをCloseしました in run(hub.go)")
+					droppedClients.Inc()
+				}
+			}
+			broadcastQueueDepth.Set(float64(queued))
+		case direct := <-h.direct:
+			if _, ok := h.clients[direct.client]; ok {
+				select {
+				case direct.client.send <- direct.message:
+				default:
+					h.removeClient(direct.client)
+					fmt.Println("Warning: 個別送信によりWeb SocketをCloseしました in run(hub.go)")
+					droppedClients.Inc()
+				}
+			}
+		case multicast := <-h.multicast:
+			for client := range h.clients {
+				if client.meetingId != 0 && client.meetingId != multicast.meetingId {
+					continue
+				}
+				if multicast.followersOnly && !client.following() {
+					continue
+				}
+				select {
+				case client.send <- multicast.message:
+				default:
+					h.removeClient(client)
+					fmt.Println("Warning: 会議内の送信によりWeb SocketをCloseしました in run(hub.go)")
+					droppedClients.Inc()
+				}
+			}
+		case user := <-h.user:
+			delivered := 0
+			for client := range h.clients {
+				if client.userId == "" || !user.match(client.userId) {
+					continue
+				}
+				if user.meetingId != 0 && client.meetingId != user.meetingId {
+					continue
+				}
+				select {
+				case client.send <- user.message:
+					delivered++
+				default:
+					h.removeClient(client)
+					fmt.Println("Warning: 利用者への送信によりWeb SocketをCloseしました in run(hub.go)")
+					droppedClients.Inc()
+				}
+			}
+			user.delivered <- delivered
+		case reply := <-h.ping:
+			close(reply)
+		case <-quit:
+			// 全クライアントに終了通知を送り，sendチャネルを閉じる(残りはwritePumpが送り切る)
+			quit = nil
+			stopping = true
+			message, _ := json.Marshal(ServerShutdownResult{
+				MessageType:    ServerShutdownMsgType,
+				ReconnectAfter: h.config.Shutdown.ReconnectAfter,
+			})
+			for client := range h.clients {
+				select {
+				case client.send <- message:
+				default:
+					fmt.Println("Warning: 終了通知を送信できませんでした in run(hub.go)")
+				}
+				h.removeClient(client)
+			}
+			close(h.stopped)
+			fmt.Println("Log: 全クライアントに終了通知を送信しました in run(hub.go)")
+		}
+	}
+}
+
+// sendTo はclientにだけメッセージを送る
+func (h *Hub) sendTo(client *Client, message []byte) {
+	select {
+	case h.direct <- &directMessage{client: client, message: message}:
+	case <-h.quit:
+	}
+}
+
+// sendToMeeting はmeetingIdの会議の参加者にメッセージを送る
+func (h *Hub) sendToMeeting(meetingId int, message []byte, followersOnly bool) {
+	select {
+	case h.multicast <- &multicastMessage{meetingId: meetingId, message: message, followersOnly: followersOnly}:
+	case <-h.quit:
+	}
+}
+
+// sendToUser はuserIdの利用者の接続にメッセージを送り，送れたかを返す
+func (h *Hub) sendToUser(userId string, message []byte) bool {
+	return h.sendToMeetingUsers(0, []string{userId}, message) > 0
+}
+
+// sendToMeetingUsers はmeetingIdの会議にいるuserIdsの利用者の接続にだけメッセージを送り，送れた接続の数を返す
+func (h *Hub) sendToMeetingUsers(meetingId int, userIds []string, message []byte) int {
+	return h.sendToMatchingUsers(meetingId, func(userId string) bool { return hasUserId(userIds, userId) }, message)
+}
+
+func hasUserId(userIds []string, userId string) bool {
+	for _, id := range userIds {
+		if id == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// sendToAsker は質問者(完全匿名の会議では仮名がaskerIdになる利用者)の会議への接続にだけメッセージを送る
+// 仮名は保存せず，接続している利用者の仮名をサーバー側で計算して照合する
+func (h *Hub) sendToAsker(meeting Meeting, askerId string, message []byte) int {
+	return h.sendToMatchingUsers(meeting.MeetingId, func(userId string) bool { return meetingAskerId(meeting, userId) == askerId }, message)
+}
+
+// sendToMatchingUsers はmatchに当てはまる利用者の接続にメッセージを送り，送れた接続の数を返す
+func (h *Hub) sendToMatchingUsers(meetingId int, match func(userId string) bool, message []byte) int {
+	user := &userMessage{meetingId: meetingId, match: match, message: message, delivered: make(chan int, 1)}
+	select {
+	case h.user <- user:
+	case <-h.quit:
+		return 0
+	}
+	return <-user.delivered
+}
+
+// removeClient はクライアントを登録解除してsendチャネルを閉じる
+// run()のゴルーチンからのみ呼ぶ
+func (h *Hub) removeClient(client *Client) {
+	delete(h.clients, client)
+	close(client.send)
+	wsClients.WithLabelValues(meetingLabel(client.meetingId)).Dec()
+	h.limiter.release(client)
+}
+
+// alive はrun()のゴルーチンが応答するかを確認する
+func (h *Hub) alive(timeout time.Duration) bool {
+	reply := make(chan struct{})
+	select {
+	case h.ping <- reply:
+	case <-h.quit:
+		return false
+	case <-time.After(timeout):
+		return false
+	}
+	select {
+	case <-reply:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// closing はシャットダウンが始まっているかを返す
+func (h *Hub) closing() bool {
+	select {
+	case <-h.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// shutdown は全クライアントに終了通知を送り，sendチャネルが送り切られるのを待つ
+func (h *Hub) shutdown(ctx context.Context) error {
+	h.quitOnce.Do(func() { close(h.quit) })
+
+	select {
+	case <-h.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		h.writers.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
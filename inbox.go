@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+)
+
+type InboxResult struct {
+	Result   bool                 `json:"result"`
+	UserId   string               `json:"userId"`
+	Messages []InboxMessageResult `json:"messages"` // 新しい順
+}
+
+type InboxMessageResult struct {
+	InboxMessageId int             `json:"inboxMessageId"`
+	MessageType    string          `json:"messageType"`
+	Message        json.RawMessage `json:"message"` // WebSocketで送るメッセージと同じ形式
+	CreatedAt      string          `json:"createdAt"`
+	IsRead         bool            `json:"isRead"`
+}
+
+// notifyUser はuserIdの利用者にメッセージを送る
+// 接続していない場合はinboxMessageを受信箱に入れ，次にログインしたときに受け取れるようにする
+func notifyUser(hub *Hub, db *gorm.DB, userId string, messageType string, message interface{}, inboxMessage interface{}) {
+	messagejson, _ := json.Marshal(message)
+	if hub.sendToUser(userId, messagejson) {
+		fmt.Printf("Log: 通知を送信しました: %s, %s in notifyUser\n", userId, messageType)
+		return
+	}
+	inboxjson, _ := json.Marshal(inboxMessage)
+	createInboxMessage(db, userId, messageType, string(inboxjson))
+}
+
+// notifyAsker は質問者にメッセージを送る
+// 完全匿名の会議ではaskerIdは仮名のため，接続していれば仮名で照合して送り，していなければ仮名の受信箱に入れる
+func notifyAsker(hub *Hub, db *gorm.DB, meeting Meeting, askerId string, messageType string, message interface{}, inboxMessage interface{}) {
+	if meeting.AnonymityLevel != anonymityFull {
+		notifyUser(hub, db, askerId, messageType, message, inboxMessage)
+		return
+	}
+	messagejson, _ := json.Marshal(message)
+	if hub.sendToAsker(meeting, askerId, messagejson) > 0 {
+		fmt.Printf("Log: 通知を送信しました: %d, %s in notifyAsker\n", meeting.MeetingId, messageType)
+		return
+	}
+	inboxjson, _ := json.Marshal(inboxMessage)
+	createInboxMessage(db, askerId, messageType, string(inboxjson))
+}
+
+// inboxUserIds は利用者の受信箱の宛先を返す
+// 利用者のIDに加えて，完全匿名の会議での仮名宛てのメッセージも本人の受信箱に含める
+func inboxUserIds(db *gorm.DB, userId string) []string {
+	userIds := []string{userId}
+	for _, meeting := range getFullAnonymityMeetings(db) {
+		if askerId := meetingAskerId(meeting, userId); askerId != "" {
+			userIds = append(userIds, askerId)
+		}
+	}
+	return userIds
+}
+
+// isInboxOwner は受信箱を読み書きするのが受信箱の利用者本人かを確認する (?userId=...)
+func isInboxOwner(c echo.Context) bool {
+	userId := c.QueryParam("userId")
+	if userId == "" || userId != c.Param("id") {
+		fmt.Printf("Error: 本人以外は受信箱を使えません: %s, %s in isInboxOwner\n", c.Param("id"), userId)
+		return false
+	}
+	return true
+}
+
+// inbox は利用者の受信箱を返す (?userId=本人&unread=true で未読のみ)
+func inbox(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !isInboxOwner(c) {
+			return c.JSON(http.StatusForbidden, &InboxResult{Result: false})
+		}
+		userId := c.Param("id")
+		messages := getInboxMessages(db, inboxUserIds(db, userId), c.QueryParam("unread") == "true")
+
+		location, _ := time.LoadLocation("Asia/Tokyo")
+		result := &InboxResult{
+			Result:   true,
+			UserId:   userId,
+			Messages: make([]InboxMessageResult, 0, len(messages)),
+		}
+		for _, message := range messages {
+			result.Messages = append(result.Messages, InboxMessageResult{
+				InboxMessageId: message.InboxMessageId,
+				MessageType:    message.MessageType,
+				Message:        json.RawMessage(message.Body),
+				CreatedAt:      message.CreatedAt.In(location).Format("2006/01/02 15:04:05"),
+				IsRead:         message.IsRead,
+			})
+		}
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+// inboxRead は受信箱のメッセージを既読にする (?userId=本人)
+func inboxRead(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !isInboxOwner(c) {
+			return c.JSON(http.StatusForbidden, &Result{Result: false})
+		}
+		inboxMessageId, err := strconv.Atoi(c.Param("messageId"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		if !readInboxMessage(db, inboxUserIds(db, c.Param("id")), inboxMessageId) {
+			return c.JSON(http.StatusNotFound, &Result{Result: false})
+		}
+		return c.JSON(http.StatusOK, &Result{Result: true})
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+)
+
+// 接続数の上限に達した場合に送るWebSocketのクローズコード
+const (
+	closeTooManyConnsPerIP   = 4001
+	closeTooManyConnsPerUser = 4002
+	closeMeetingFull         = 4003
+)
+
+// originAllowed はOriginが許可リストに含まれるかを返す
+// Originヘッダが無い(ブラウザ以外からの)接続は許可する
+func originAllowed(allowedOrigins []string, origin string) bool {
+	if origin == "" {
+		return true
+	}
+	for _, o := range allowedOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// corsMiddleware はupgraderと同じ許可リストでCORSを設定する
+// 許可リストが空の場合はクロスオリジンのリクエストを一切許可しない
+func corsMiddleware(allowedOrigins []string) echo.MiddlewareFunc {
+	if len(allowedOrigins) == 0 {
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+	return middleware.CORSWithConfig(middleware.CORSConfig{
+		AllowOrigins: allowedOrigins,
+		AllowMethods: []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete},
+	})
+}
+
+// connLimiter はIP，ユーザー，会議毎の同時接続数を数える
+type connLimiter struct {
+	config WebSocketConfig
+
+	mu        sync.Mutex
+	byIP      map[string]int
+	byUser    map[string]int
+	byMeeting map[int]int
+}
+
+func newConnLimiter(config WebSocketConfig) *connLimiter {
+	return &connLimiter{
+		config:    config,
+		byIP:      make(map[string]int),
+		byUser:    make(map[string]int),
+		byMeeting: make(map[int]int),
+	}
+}
+
+// acquire は上限に達していなければ接続を数えて0を返す
+// 上限に達していればクローズコードと理由を返す
+func (l *connLimiter) acquire(client *Client) (int, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.config.MaxConnsPerIP > 0 && l.byIP[client.ip] >= l.config.MaxConnsPerIP {
+		return closeTooManyConnsPerIP, "too many connections from this address"
+	}
+	if client.userId != "" && l.config.MaxConnsPerUser > 0 && l.byUser[client.userId] >= l.config.MaxConnsPerUser {
+		return closeTooManyConnsPerUser, "too many connections for this user"
+	}
+	if client.meetingId != 0 && l.config.MaxClientsPerMeeting > 0 && l.byMeeting[client.meetingId] >= l.config.MaxClientsPerMeeting {
+		return closeMeetingFull, "meeting is full"
+	}
+
+	l.byIP[client.ip]++
+	if client.userId != "" {
+		l.byUser[client.userId]++
+	}
+	if client.meetingId != 0 {
+		l.byMeeting[client.meetingId]++
+	}
+	return 0, ""
+}
+
+// release はacquireで数えた接続を減らす
+func (l *connLimiter) release(client *Client) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.byIP[client.ip]--; l.byIP[client.ip] <= 0 {
+		delete(l.byIP, client.ip)
+	}
+	if client.userId != "" {
+		if l.byUser[client.userId]--; l.byUser[client.userId] <= 0 {
+			delete(l.byUser, client.userId)
+		}
+	}
+	if client.meetingId != 0 {
+		if l.byMeeting[client.meetingId]--; l.byMeeting[client.meetingId] <= 0 {
+			delete(l.byMeeting, client.meetingId)
+		}
+	}
+}
+
+// meetingLabel はメトリクス用の会議IDのラベル (会議未指定は "none")
+func meetingLabel(meetingId int) string {
+	if meetingId == 0 {
+		return "none"
+	}
+	return strconv.Itoa(meetingId)
+}
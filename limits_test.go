@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestConnLimiterSharedIP は同じIPの別の利用者がMaxConnsPerIPまで接続でき，利用者毎の上限はuserIdで数えることを確認する
+func TestConnLimiterSharedIP(t *testing.T) {
+	config := defaultConfig().WebSocket
+	config.MaxConnsPerIP = 10
+	config.MaxConnsPerUser = 3
+	config.MaxClientsPerMeeting = 0
+	limiter := newConnLimiter(config)
+
+	clients := make([]*Client, 0, config.MaxConnsPerIP)
+	for i := 0; i < config.MaxConnsPerIP; i++ {
+		client := &Client{ip: "192.0.2.1", userId: fmt.Sprintf("user%d", i), meetingId: 1}
+		if code, reason := limiter.acquire(client); code != 0 {
+			t.Fatalf("user%d: rejected: %d %s", i, code, reason)
+		}
+		clients = append(clients, client)
+	}
+	if code, _ := limiter.acquire(&Client{ip: "192.0.2.1", userId: "other", meetingId: 1}); code != closeTooManyConnsPerIP {
+		t.Errorf("over MaxConnsPerIP: got %d, want %d", code, closeTooManyConnsPerIP)
+	}
+
+	limiter.release(clients[0])
+	if code, reason := limiter.acquire(&Client{ip: "192.0.2.1", userId: "other", meetingId: 1}); code != 0 {
+		t.Errorf("after release: rejected: %d %s", code, reason)
+	}
+}
+
+// TestConnLimiterPerUser は同じuserIdの接続はIPが違ってもMaxConnsPerUserまでであることを確認する
+func TestConnLimiterPerUser(t *testing.T) {
+	config := defaultConfig().WebSocket
+	config.MaxConnsPerIP = 10
+	config.MaxConnsPerUser = 3
+	limiter := newConnLimiter(config)
+
+	for i := 0; i < config.MaxConnsPerUser; i++ {
+		if code, reason := limiter.acquire(&Client{ip: fmt.Sprintf("192.0.2.%d", i+1), userId: "tanaka1"}); code != 0 {
+			t.Fatalf("connection %d: rejected: %d %s", i, code, reason)
+		}
+	}
+	if code, _ := limiter.acquire(&Client{ip: "198.51.100.1", userId: "tanaka1"}); code != closeTooManyConnsPerUser {
+		t.Errorf("over MaxConnsPerUser: got %d, want %d", code, closeTooManyConnsPerUser)
+	}
+}
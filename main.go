@@ -1,74 +1,153 @@
-// Copyright 2013 The Gorilla WebSocket Authors. All rights reserved.
-// Use of this source code is governed by a BSD-style
-// license that can be found in the LICENSE file.
-
-package main
-
-import (
-	// "fmt"
-	"fmt"
-	"log"
-	"net/http"
-	"os"
-
-	"github.com/joho/godotenv"
-	"github.com/labstack/echo"
-	"github.com/labstack/echo/middleware"
-)
-
-//　webページに移動
-func serveHome(w http.ResponseWriter, r *http.Request) {
-	log.Println(r.URL)
-	if r.URL.Path != "/" {
-		http.Error(w, "Not found", http.StatusNotFound)
-		return
-	}
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-	http.ServeFile(w, r, "./public/home.html")
-}
-
-func main() {
-	fmt.Println("Start main func.")
-	// err := godotenv.Load()
-	godotenv.Load()
-	// if err != nil {
-	// 	log.Fatal("Error loading .env file")
-	// }
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		fmt.Println("$PORT must be set")
-		port = "8080"
-	}
-
-	hub := newHub()
-	// startEcho()
-	go hub.run() // hubのゴルーチン開始
-
-	fmt.Println("Start echo.")
-	e := echo.New()
-	e.Use(middleware.CORS())
-
-	db := connectDB()
-
-	dbsetting(db)
-
-	initRouting(e, hub, db)
-
-	fmt.Println("End main func.")
-	// e.Logger.Fatal(e.Start(":1323"))
-	e.Logger.Fatal(e.Start(":" + port))
-
-	// http.HandleFunc("/", serveHome) // TOP画面の表示周り(それ以外はNot Found)
-	// // websockerの扱い(直接アクセスはBad Request)
-	// http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-	// 	serveWs(hub, w, r)
-	// })
-	// err := http.ListenAndServe(*addr, nil)
-	// if err != nil {
-	// 	log.Fatal("ListenAndServe: ", err)
-	// }
-}
+// Copyright 2013 The Gorilla WebSocket Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	// "fmt"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jinzhu/gorm"
+	"github.com/joho/godotenv"
+	"github.com/labstack/echo"
+)
+
+//　webページに移動
+func serveHome(w http.ResponseWriter, r *http.Request) {
+	log.Println(r.URL)
+	if r.URL.Path != "/" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	http.ServeFile(w, r, "./public/home.html")
+}
+
+// shutdown は新規接続の受付を止め，WebSocketに終了通知を送り切ってからDBを閉じる
+func shutdown(ctx context.Context, e *echo.Echo, hub *Hub, db *gorm.DB) {
+	if err := e.Shutdown(ctx); err != nil {
+		fmt.Printf("Error: HTTPサーバーの停止に失敗しました: %v in shutdown\n", err)
+	}
+	if err := hub.shutdown(ctx); err != nil {
+		fmt.Printf("Error: WebSocketの送信待ちがタイムアウトしました: %v in shutdown\n", err)
+	}
+	if err := db.Close(); err != nil {
+		fmt.Printf("Error: DBの切断に失敗しました: %v in shutdown\n", err)
+	} else {
+		fmt.Printf("Log: DBを切断しました in shutdown\n")
+	}
+}
+
+func main() {
+	// err := godotenv.Load()
+	godotenv.Load()
+	// if err != nil {
+	// 	log.Fatal("Error loading .env file")
+	// }
+
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML config file")
+	showConfig := flag.Bool("print-config", false, "print the effective config (secrets redacted) and exit")
+	checkSpecOnly := flag.Bool("check-spec", false, "check that /openapi.json, /asyncapi.json and the generated SDKs match the server and exit")
+	generateSDKDir := flag.String("generate-sdk", "", "write the Go and TypeScript client SDKs under the given directory and exit")
+	flag.Parse()
+
+	if *generateSDKDir != "" {
+		if err := generateSDK(*generateSDKDir); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Log: SDKを生成しました in main")
+		return
+	}
+	if *checkSpecOnly {
+		// 経路の登録だけを行うため，設定の読み込みとDBへの接続はしない
+		e := echo.New()
+		initRouting(e, newHub(defaultConfig()), nil, nil)
+		problems := append(checkSpec(e), checkSDK(".")...)
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		if len(problems) != 0 {
+			os.Exit(1)
+		}
+		fmt.Println("Log: 仕様と経路が一致しました in main")
+		return
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *showConfig {
+		if err := printConfig(config); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	setAnonymitySecret(config.AnonymitySecret)
+
+	fmt.Println("Start main func.")
+	hub := newHub(config)
+	// startEcho()
+	go hub.run() // hubのゴルーチン開始
+
+	fmt.Println("Start echo.")
+	e := echo.New()
+	e.Use(corsMiddleware(config.AllowedOrigins))
+	e.Use(httpMetrics)
+
+	db := connectDB(config.DB)
+	migrateDB(db)
+
+	dbsetting(db)
+
+	store, err := newBlobStore(config.Storage)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	initRouting(e, hub, db, store)
+
+	reschedulePendingMeetings(hub, db)
+
+	go runNotifier(hub, db) // 発表の残り時間の通知
+
+	go func() {
+		// e.Logger.Fatal(e.Start(":1323"))
+		if err := e.Start(":" + config.Port); err != nil && err != http.ErrServerClosed {
+			e.Logger.Fatal(err)
+		}
+	}()
+
+	// SIGINT/SIGTERM(Railwayの再デプロイ)を受けたら停止処理を行う
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+	fmt.Println("Log: 停止シグナルを受信しました in main")
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Shutdown.Timeout)
+	defer cancel()
+	shutdown(ctx, e, hub, db)
+
+	fmt.Println("End main func.")
+
+	// http.HandleFunc("/", serveHome) // TOP画面の表示周り(それ以外はNot Found)
+	// // websockerの扱い(直接アクセスはBad Request)
+	// http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+	// 	serveWs(hub, w, r)
+	// })
+	// err := http.ListenAndServe(*addr, nil)
+	// if err != nil {
+	// 	log.Fatal("ListenAndServe: ", err)
+	// }
+}
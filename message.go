@@ -7,14 +7,22 @@ import (
 )
 
 const (
-	presenEndMessage         = "発表ありがとうございました。\n"
-	questionBodyAskMessage   = "匿名質問です。%dページについての質問です。%s\n"
-	questionModeratorMessage = "%dページについて疑問に思う方が多いようです。詳しい説明をお願いします。\n"
-	questionPersonMessage    = "次に%sさん、質問お願いします。\n"
-	questionEndMessage       = "回答ありがとうございました。\n"
-	personEndMessage         = "これで%sさんの発表時間を終わります。次の発表者は%sさんです。よろしくお願いします。\n"
-	meetingStartMessage      = "これから会議を開始します。最初の発表者は%sさんです。よろしくお願いします。\n"
-	meetingEndMessage        = "これで会議を終了します。お疲れ様でした。\n"
+	presenEndMessage          = "発表ありがとうございました。\n"
+	questionBodyAskMessage    = "匿名質問です。%dページについての質問です。%s\n"
+	questionAttributedMessage = "%sさんからの質問です。%dページについての質問です。%s\n"
+	questionModeratorMessage  = "%dページについて疑問に思う方が多いようです。詳しい説明をお願いします。\n"
+	questionPersonMessage     = "次に%sさん、質問お願いします。\n"
+	questionEndMessage        = "回答ありがとうございました。\n"
+	personEndMessage          = "これで%sさんの発表時間を終わります。次の発表者は%sさんです。よろしくお願いします。\n"
+	meetingStartMessage       = "これから会議を開始します。最初の発表者は%sさんです。よろしくお願いします。\n"
+	meetingEndMessage         = "これで会議を終了します。お疲れ様でした。\n"
+	paceTooFastMessage        = "話すペースが速いと感じている方が%d人います。少しゆっくり話してください。\n"
+	paceTooSlowMessage        = "話すペースが遅いと感じている方が%d人います。少しテンポを上げてください。\n"
+	confusedPageNotice        = "%d人が%dページを分かりにくいと感じています。\n"
+	timeRemainingNotice       = "発表の残り時間は%d分です。\n"
+	timeUpNotice              = "発表の予定時間を過ぎました。\n"
+	pendingQuestionsNotice    = "承認待ちの質問が%d件あります。\n"
+	unansweredQuestionsNotice = "未回答の質問が%d件あります。\n"
 )
 
 func presenOrQuestionEnd(db *gorm.DB, meetingId int, presenterId string, isPresenEnd bool, questionUserId string) (msg, qUserId string, qId int) {
@@ -40,6 +48,12 @@ func presenOrQuestionEnd(db *gorm.DB, meetingId int, presenterId string, isPrese
 			var qBody string
 			qBody, dPage = getQuestionBody(db, qId)
 			msg = fmt.Sprintf(endMessage+questionBodyAskMessage, dPage, qBody)
+			// 質問者を明かす会議では質問者の名前を読み上げる
+			if _, meeting := getMeeting(db, meetingId); meeting.AnonymityLevel == anonymityAttributed {
+				if found, question := getQuestion(db, qId); found {
+					msg = fmt.Sprintf(endMessage+questionAttributedMessage, getUserName(db, question.UserId), dPage, qBody)
+				}
+			}
 			return msg, "", qId
 		} else {
 			dPage = getQuestionDocumentPage(db, qId)
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsNamespace = "rochup"
+
+var (
+	// 会議毎の接続中のWebSocketクライアント数
+	wsClients = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "websocket_clients",
+		Help:      "Number of connected WebSocket clients, by meeting.",
+	}, []string{"meeting_id"})
+
+	// 全クライアントのsendチャネルに溜まっている未送信メッセージ数
+	broadcastQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "broadcast_queue_depth",
+		Help:      "Number of messages waiting in client send buffers after the last broadcast.",
+	})
+
+	// sendチャネルが詰まっていたために切断したクライアント数
+	droppedClients = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "websocket_dropped_clients_total",
+		Help:      "Clients disconnected by the hub because their send buffer was full.",
+	})
+
+	// messageType毎の受信メッセージ数
+	messagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "websocket_messages_received_total",
+		Help:      "WebSocket messages received, by messageType.",
+	}, []string{"message_type"})
+
+	// 流量制限・ミュートにより拒否したメッセージ数
+	throttledEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "throttled_events_total",
+		Help:      "Messages and requests rejected by rate limiting or mutes, by message type and reason.",
+	}, []string{"message_type", "reason"})
+
+	// db.goの関数毎のクエリ時間
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of database operations, by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	// echoのルート毎のレスポンス時間
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests, by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// 会議毎の司会進行の遷移数
+	moderatorTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "moderator_transitions_total",
+		Help:      "Moderator state transitions, by meeting and transition kind.",
+	}, []string{"meeting_id", "transition"})
+)
+
+// 司会進行の遷移の種類
+const (
+	transitionMeetingStart = "meeting_start"
+	transitionQuestion     = "question"
+	transitionNextPresen   = "next_presenter"
+	transitionMeetingEnd   = "meeting_end"
+)
+
+// observeDBQuery はdb.goの各関数の先頭で
+// defer observeDBQuery("funcName", time.Now()) として呼ぶ
+func observeDBQuery(operation string, start time.Time) {
+	dbQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func countModeratorTransition(meetingId int, transition string) {
+	moderatorTransitions.WithLabelValues(strconv.Itoa(meetingId), transition).Inc()
+}
+
+// httpMetrics はルート毎のレスポンス時間を記録するechoのミドルウェア
+func httpMetrics(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+		if err != nil {
+			c.Error(err)
+		}
+		status := strconv.Itoa(c.Response().Status)
+		httpRequestDuration.WithLabelValues(c.Request().Method, c.Path(), status).Observe(time.Since(start).Seconds())
+		return nil
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+)
+
+const (
+	QuestionModerateMsgType   = "question_moderate"
+	QuestionModerationMsgType = "question_moderation"
+	QuestionUpdateMsgType     = "question_update"
+)
+
+// 質問に対するホストの操作
+const (
+	moderationApprove = "approve"
+	moderationReject  = "reject"
+	moderationEdit    = "edit"
+	moderationMerge   = "merge"
+
+	// 操作ではなく，重複の可能性がある質問が投稿されたことをホストに知らせる
+	moderationDuplicate = "duplicate"
+
+	// 質問者かホストが質問を取り下げたことをホストに知らせる
+	moderationWithdraw = "withdraw"
+)
+
+// QuestionModerationResult はホストにだけ送る質問の承認に関するメッセージ
+// Actionは "pending"(承認待ちの質問が来た)，"duplicate"(重複の可能性がある質問が来た) か，ホストが行った操作
+// 質問者が自分の質問を統合・編集した場合も "merge"・"edit" を送る
+type QuestionModerationResult struct {
+	MessageType  string `json:"messageType"`
+	MeetingId    int    `json:"meetingId"`
+	Action       string `json:"action"`
+	HostId       string `json:"hostId"`  // 操作したホストか質問者 ("pending"と"duplicate"の場合は空)
+	AskerId      string `json:"askerId"` // 質問者 (完全匿名の会議では仮名)
+	QuestionId   int    `json:"questionId"`
+	QuestionBody string `json:"questionBody"`
+	DocumentId   int    `json:"documentId"`
+	DocumentPage int    `json:"documentPage"`
+	QuestionTime string `json:"questionTime"`
+	VoteNum      int    `json:"voteNum"`
+	MergedInto   int    `json:"mergedInto"`  // only if `Action == "merge"`
+	DuplicateOf  int    `json:"duplicateOf"` // 重複の可能性が高い質問 (無ければ0)
+}
+
+// QuestionUpdateResult は公開中の質問が変更されたことを会議の参加者に知らせるメッセージ
+type QuestionUpdateResult struct {
+	MessageType      string `json:"messageType"`
+	MeetingId        int    `json:"meetingId"`
+	QuestionId       int    `json:"questionId"`
+	QuestionBody     string `json:"questionBody"`
+	ModerationStatus string `json:"moderationStatus"`
+	MergedInto       int    `json:"mergedInto"`
+}
+
+type ModerationQueueResult struct {
+	Result    bool                       `json:"result"`
+	MeetingId int                        `json:"meetingId"`
+	Questions []QuestionModerationResult `json:"questions"` // 古い順
+}
+
+type QuestionModerateRequest struct {
+	HostId       string `json:"hostId"`
+	Action       string `json:"action"`       // approve, reject, edit, merge
+	QuestionBody string `json:"questionBody"` // only if `Action == "edit"`
+	MergeInto    int    `json:"mergeInto"`    // only if `Action == "merge"`
+}
+
+type PreModerationRequest struct {
+	HostId  string `json:"hostId"`
+	Enabled bool   `json:"enabled"`
+}
+
+func questionModerationResult(meetingId int, action string, hostId string, question Question) QuestionModerationResult {
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	return QuestionModerationResult{
+		MessageType:  QuestionModerationMsgType,
+		MeetingId:    meetingId,
+		Action:       action,
+		HostId:       hostId,
+		AskerId:      visibleAskerId("", true, question),
+		QuestionId:   question.QuestionId,
+		QuestionBody: question.QuestionBody,
+		DocumentId:   question.DocumentId,
+		DocumentPage: question.DocumentPage,
+		QuestionTime: question.QuestionTime.In(location).Format("2006/01/02 15:04:05"),
+		VoteNum:      question.VoteNum,
+		MergedInto:   question.MergedInto,
+		DuplicateOf:  question.DuplicateOf,
+	}
+}
+
+// sendToHosts はその会議に接続しているホストにだけメッセージを送る (ホストの別の会議の接続には送らない)
+func sendToHosts(hub *Hub, db *gorm.DB, meetingId int, message interface{}) {
+	messagejson, _ := json.Marshal(message)
+	hub.sendToMeetingUsers(meetingId, getHostIds(db, meetingId), messagejson)
+}
+
+// questionBroadcast は承認された質問を通常の質問と同じ形で会議に送る
+func questionBroadcast(hub *Hub, db *gorm.DB, meetingId int, question Question) {
+	_, meeting := getMeeting(db, meetingId)
+	messagejson, _ := json.Marshal(questionResult(meetingId, getPresenterId(db, question.DocumentId), visibleAskerId(meeting.AnonymityLevel, false, question), question))
+	hub.sendToMeeting(meetingId, messagejson, false)
+}
+
+// questionUpdateBroadcast は公開中の質問の変更を会議に送る
+func questionUpdateBroadcast(hub *Hub, meetingId int, question Question) {
+	messagejson, _ := json.Marshal(QuestionUpdateResult{
+		MessageType:      QuestionUpdateMsgType,
+		MeetingId:        meetingId,
+		QuestionId:       question.QuestionId,
+		QuestionBody:     question.QuestionBody,
+		ModerationStatus: question.ModerationStatus,
+		MergedInto:       question.MergedInto,
+	})
+	hub.sendToMeeting(meetingId, messagejson, false)
+}
+
+// questionMergeBroadcast は質問の統合と統合先の合算後の投票数を会議に送る
+func questionMergeBroadcast(hub *Hub, meetingId int, question Question, wasPublished bool, voteNum int) {
+	if wasPublished {
+		questionUpdateBroadcast(hub, meetingId, question)
+	}
+	messagejson, _ := json.Marshal(QuestionVoteResult{
+		MessageType: "question_vote",
+		MeetingId:   meetingId,
+		QuestionId:  question.MergedInto,
+		VoteNum:     voteNum,
+	})
+	hub.sendToMeeting(meetingId, messagejson, false)
+}
+
+// moderateQuestion はホストによる質問の承認・却下・編集・統合を行う
+// 失敗した場合はHTTPのステータスとエラーのcodeを返す
+func moderateQuestion(hub *Hub, db *gorm.DB, hostId string, questionId int, request QuestionModerateRequest) (int, string) {
+	found, question := getQuestion(db, questionId)
+	if !found || question.IsVoice {
+		return http.StatusNotFound, "question_not_found"
+	}
+	found, document := getDocument(db, question.DocumentId)
+	if !found {
+		return http.StatusNotFound, "question_not_found"
+	}
+	meetingId := document.MeetingId
+	if !isHost(db, meetingId, hostId) {
+		fmt.Printf("Error: ホスト以外は質問を承認できません: %d, %s in moderateQuestion\n", meetingId, hostId)
+		return http.StatusForbidden, "forbidden"
+	}
+
+	wasPublished := question.ModerationStatus == questionApproved
+	switch request.Action {
+	case moderationApprove:
+		if !setQuestionStatus(db, questionId, []string{questionPending, questionRejected}, questionApproved) {
+			return http.StatusConflict, "invalid_moderation"
+		}
+		question.ModerationStatus = questionApproved
+		questionBroadcast(hub, db, meetingId, question)
+		notifyQuestionQueues(hub, db, meetingId, question.DocumentId)
+	case moderationReject:
+		if !setQuestionStatus(db, questionId, []string{questionPending, questionApproved}, questionRejected) {
+			return http.StatusConflict, "invalid_moderation"
+		}
+		question.ModerationStatus = questionRejected
+		if wasPublished {
+			questionUpdateBroadcast(hub, meetingId, question)
+		}
+	case moderationEdit:
+		body := strings.TrimSpace(request.QuestionBody)
+		if body == "" || !editQuestion(db, questionId, hostId, body) {
+			return http.StatusBadRequest, "invalid_moderation"
+		}
+		question.QuestionBody = body
+		if wasPublished {
+			questionUpdateBroadcast(hub, meetingId, question)
+		}
+	case moderationMerge:
+		ok, voteNum := mergeQuestion(db, questionId, request.MergeInto)
+		if !ok {
+			return http.StatusConflict, "invalid_moderation"
+		}
+		question.ModerationStatus = questionMerged
+		question.MergedInto = request.MergeInto
+		questionMergeBroadcast(hub, meetingId, question, wasPublished, voteNum)
+	default:
+		return http.StatusBadRequest, "invalid_moderation"
+	}
+
+	sendToHosts(hub, db, meetingId, questionModerationResult(meetingId, request.Action, hostId, question))
+	return http.StatusOK, ""
+}
+
+// moderationQueue は承認待ちの質問の一覧を返す (?userId=ホスト)
+func moderationQueue(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		meetingId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &ModerationQueueResult{Result: false})
+		}
+		if !isHost(db, meetingId, c.QueryParam("userId")) {
+			return c.JSON(http.StatusForbidden, &ModerationQueueResult{Result: false})
+		}
+		questions := getPendingQuestions(db, meetingId)
+		result := &ModerationQueueResult{
+			Result:    true,
+			MeetingId: meetingId,
+			Questions: make([]QuestionModerationResult, 0, len(questions)),
+		}
+		for _, question := range questions {
+			result.Questions = append(result.Questions, questionModerationResult(meetingId, questionPending, "", question))
+		}
+		return c.JSON(http.StatusOK, result)
+	}
+}
+
+// questionModerate はホストが質問を承認・却下・編集・統合する
+// WebSocketの question_moderate メッセージと同じ操作
+func questionModerate(hub *Hub, db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		questionId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		request := new(QuestionModerateRequest)
+		if err := c.Bind(request); err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		if status, _ := moderateQuestion(hub, db, request.HostId, questionId, *request); status != http.StatusOK {
+			return c.JSON(status, &Result{Result: false})
+		}
+		return c.JSON(http.StatusOK, &Result{Result: true})
+	}
+}
+
+// meetingPreModeration はホストが会議の質問の事前承認を切り替える
+func meetingPreModeration(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		meetingId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		request := new(PreModerationRequest)
+		if err := c.Bind(request); err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		if !isHost(db, meetingId, request.HostId) {
+			return c.JSON(http.StatusForbidden, &Result{Result: false})
+		}
+		return c.JSON(http.StatusOK, &Result{Result: setPreModeration(db, meetingId, request.Enabled)})
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const NotificationMsgType = "notification"
+
+// 発表の残り時間を確認する間隔
+const notifyCheckInterval = 10 * time.Second
+
+// 発表者・ホストだけへの通知の種類
+const (
+	notifyConfusedPage        = "confused_page"        // ページを分からないと感じている人が多い
+	notifyTimeRemaining       = "time_remaining"       // 発表の残り時間
+	notifyTimeUp              = "time_up"              // 発表の予定時間を過ぎた
+	notifyPendingQuestions    = "pending_questions"    // 承認待ちの質問が溜まっている (ホスト)
+	notifyUnansweredQuestions = "unanswered_questions" // 未回答の質問が溜まっている (発表者)
+)
+
+// 通知の宛先
+const (
+	rolePresenter = "presenter"
+	roleHost      = "host"
+)
+
+// NotificationResult は発表者・ホストだけに送る通知
+type NotificationResult struct {
+	MessageType  string   `json:"messageType"`
+	MeetingId    int      `json:"meetingId"`
+	Kind         string   `json:"kind"`
+	Roles        []string `json:"roles"` // 宛先 (presenter, host)
+	DocumentId   int      `json:"documentId"`
+	DocumentPage int      `json:"documentPage,omitempty"`
+	Count        int      `json:"count"` // 人数・件数・残りの秒数
+	Message      string   `json:"message"`
+	CreatedAt    string   `json:"createdAt"`
+}
+
+// roleUserIds は会議で役割を持つ利用者を返す
+// 発表者は資料の発表者 (documentIdが0なら発表中の発表者)
+func roleUserIds(db *gorm.DB, meetingId int, documentId int, role string) []string {
+	switch role {
+	case rolePresenter:
+		if documentId != 0 {
+			if presenterId := getPresenterId(db, documentId); presenterId != "" {
+				return []string{presenterId}
+			}
+			return []string{}
+		}
+		if found, state := getModeratorState(db, meetingId); found && state.PresenterId != "" {
+			return []string{state.PresenterId}
+		}
+	case roleHost:
+		return getHostIds(db, meetingId)
+	}
+	return []string{}
+}
+
+// notify は通知をrolesの利用者の会議への接続にだけ送る (同じ利用者には1回)
+func notify(hub *Hub, db *gorm.DB, notification NotificationResult, roles ...string) {
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	notification.MessageType = NotificationMsgType
+	notification.Roles = roles
+	notification.CreatedAt = time.Now().In(location).Format("2006/01/02 15:04:05")
+
+	userIds := make([]string, 0, 2)
+	for _, role := range roles {
+		for _, userId := range roleUserIds(db, notification.MeetingId, notification.DocumentId, role) {
+			if !hasUserId(userIds, userId) {
+				userIds = append(userIds, userId)
+			}
+		}
+	}
+	if len(userIds) == 0 {
+		return
+	}
+	messagejson, _ := json.Marshal(notification)
+	if hub.sendToMeetingUsers(notification.MeetingId, userIds, messagejson) == 0 {
+		fmt.Printf("Log: 宛先が接続していないため通知を送れませんでした: %d, %s in notify\n", notification.MeetingId, notification.Kind)
+	}
+}
+
+// notifyConfused はページを分からないと感じている人数が一定以上になったら発表者とホストに知らせる
+func notifyConfused(hub *Hub, db *gorm.DB, meetingId int, documentId int, documentPage int, reactionNum int) {
+	config := hub.config.Moderator
+	if config.NotifyConfusedNum <= 0 || reactionNum < config.NotifyConfusedNum {
+		return
+	}
+	if !hub.notified.ready(fmt.Sprintf("%s/%d/%d", notifyConfusedPage, documentId, documentPage), config.NotifyCooldown) {
+		return
+	}
+	notify(hub, db, NotificationResult{
+		MeetingId:    meetingId,
+		Kind:         notifyConfusedPage,
+		DocumentId:   documentId,
+		DocumentPage: documentPage,
+		Count:        reactionNum,
+		Message:      fmt.Sprintf(confusedPageNotice, reactionNum, documentPage),
+	}, rolePresenter, roleHost)
+}
+
+// notifyQuestionQueues は承認待ちの質問をホストに，資料への未回答の質問を発表者に，一定の件数以上になったら知らせる
+func notifyQuestionQueues(hub *Hub, db *gorm.DB, meetingId int, documentId int) {
+	config := hub.config.Moderator
+	if config.NotifyQuestionNum <= 0 {
+		return
+	}
+	if pending := len(getPendingQuestions(db, meetingId)); pending >= config.NotifyQuestionNum && hub.notified.ready(fmt.Sprintf("%s/%d", notifyPendingQuestions, meetingId), config.NotifyCooldown) {
+		notify(hub, db, NotificationResult{
+			MeetingId: meetingId,
+			Kind:      notifyPendingQuestions,
+			Count:     pending,
+			Message:   fmt.Sprintf(pendingQuestionsNotice, pending),
+		}, roleHost)
+	}
+	if unanswered := len(getUnansweredQuestions(db, documentId)); unanswered >= config.NotifyQuestionNum && hub.notified.ready(fmt.Sprintf("%s/%d", notifyUnansweredQuestions, documentId), config.NotifyCooldown) {
+		notify(hub, db, NotificationResult{
+			MeetingId:  meetingId,
+			Kind:       notifyUnansweredQuestions,
+			DocumentId: documentId,
+			Count:      unanswered,
+			Message:    fmt.Sprintf(unansweredQuestionsNotice, unanswered),
+		}, rolePresenter)
+	}
+}
+
+// notifyPresentationTime は発表の残り時間が少なくなった・予定時間を過ぎた発表者とホストに知らせる
+// 1回の発表につきそれぞれ1回送る (予定時間を過ぎた発表がさらに発表時間と通知の時間だけ続けば再び送る)
+func notifyPresentationTime(hub *Hub, db *gorm.DB, now time.Time) {
+	config := hub.config.Moderator
+	for _, state := range getPresentingStates(db) {
+		remaining := state.PresenterStartedAt.Add(config.PresentationTime).Sub(now)
+		notification := NotificationResult{
+			MeetingId:    state.MeetingId,
+			DocumentId:   state.DocumentId,
+			DocumentPage: state.DocumentPage,
+		}
+		switch {
+		case remaining <= 0:
+			notification.Kind = notifyTimeUp
+			notification.Message = timeUpNotice
+		case remaining <= config.TimeWarning:
+			notification.Kind = notifyTimeRemaining
+			notification.Count = int(math.Ceil(remaining.Seconds()))
+			notification.Message = fmt.Sprintf(timeRemainingNotice, int(math.Ceil(remaining.Minutes())))
+		default:
+			continue
+		}
+		key := fmt.Sprintf("%s/%d/%s/%d", notification.Kind, state.MeetingId, state.PresenterId, state.PresenterStartedAt.Unix())
+		if hub.notified.once(key, config.PresentationTime+config.TimeWarning) {
+			notify(hub, db, notification, rolePresenter, roleHost)
+		}
+	}
+}
+
+// runNotifier は発表の残り時間を定期的に確認する (発表時間を計らない設定では何もしない)
+func runNotifier(hub *Hub, db *gorm.DB) {
+	if hub.config.Moderator.PresentationTime <= 0 {
+		return
+	}
+	ticker := time.NewTicker(notifyCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			notifyPresentationTime(hub, db, now)
+		case <-hub.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+const (
+	// レイアウトの簡易図の横幅(px)の上限
+	previewWidth = 320
+
+	// レイアウトの簡易図の高さ(px)の上限 (極端に縦長なページは全体が収まるように縮める)
+	maxPreviewHeight = 4 * previewWidth
+
+	// 1資料あたりのページ数の上限
+	maxDocumentPages = 500
+)
+
+var (
+	previewBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	previewBorder     = color.RGBA{0xd0, 0xd0, 0xd0, 0xff}
+	previewShape      = color.RGBA{0xe4, 0xe8, 0xef, 0xff}
+	previewText       = color.RGBA{0x40, 0x40, 0x48, 0xff}
+)
+
+// pdfPage はPDFから取り出した1ページ分の情報
+type pdfPage struct {
+	Num     int
+	Width   float64 // points
+	Height  float64 // points
+	Text    string
+	Preview []byte // レイアウトの簡易図 (PNG)
+}
+
+// extractPdfPages はPDFのページ数，ページ毎のテキストとレイアウトの簡易図を取り出す
+func extractPdfPages(r io.ReaderAt, size int64) (pages []pdfPage, err error) {
+	// 壊れたPDFではライブラリがpanicすることがあるため，エラーとして扱う
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			pages = nil
+			err = fmt.Errorf("PDFの解析に失敗しました: %v", recovered)
+		}
+	}()
+
+	reader, err := pdf.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	num := reader.NumPage()
+	if num <= 0 {
+		return nil, fmt.Errorf("ページがありません")
+	}
+	if num > maxDocumentPages {
+		return nil, fmt.Errorf("ページ数が多すぎます: %d", num)
+	}
+
+	pages = make([]pdfPage, 0, num)
+	for i := 1; i <= num; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			return nil, fmt.Errorf("%dページ目を読み込めません", i)
+		}
+		width, height := pageSize(page)
+		content := pageContent(page)
+		preview, err := renderLayoutPreview(content, width, height)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, pdfPage{
+			Num:     i,
+			Width:   width,
+			Height:  height,
+			Text:    pageText(content.Text),
+			Preview: preview,
+		})
+	}
+	return pages, nil
+}
+
+// pageSize はMediaBoxからページの大きさを返す (無ければA4横)
+// MediaBoxは親のPagesから継承されることがある
+func pageSize(page pdf.Page) (float64, float64) {
+	var box pdf.Value
+	for v := page.V; !v.IsNull(); v = v.Key("Parent") {
+		if box = v.Key("MediaBox"); !box.IsNull() {
+			break
+		}
+	}
+	if box.Len() != 4 {
+		return 842, 595
+	}
+	width := math.Abs(box.Index(2).Float64() - box.Index(0).Float64())
+	height := math.Abs(box.Index(3).Float64() - box.Index(1).Float64())
+	if width == 0 || height == 0 {
+		return 842, 595
+	}
+	return width, height
+}
+
+// pageContent はページの文字と図形を取り出す
+// 対応していない形式のページは空として扱う
+func pageContent(page pdf.Page) (content pdf.Content) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			content = pdf.Content{}
+		}
+	}()
+	return page.Content()
+}
+
+// pageText は1文字ずつの配置から，行毎に改行したテキストを組み立てる
+func pageText(texts []pdf.Text) string {
+	var (
+		builder strings.Builder
+		lastY   = math.NaN()
+		lastEnd = math.NaN()
+	)
+	for _, t := range texts {
+		if !math.IsNaN(lastY) {
+			if math.Abs(t.Y-lastY) > t.FontSize/2 {
+				builder.WriteString("\n")
+			} else if t.X-lastEnd > t.FontSize/4 {
+				builder.WriteString(" ")
+			}
+		}
+		builder.WriteString(t.S)
+		lastY = t.Y
+		lastEnd = t.X + t.W
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+// renderLayoutPreview はページのレイアウトを縮小した簡易図(ワイヤーフレーム)をPNGで返す
+// ページの内容を描画するのではなく，図形の範囲を塗りつぶし，文字を位置と大きさの帯として描く
+// ページ全体が previewWidth x maxPreviewHeight に収まるように縮小する (切り取らない)
+func renderLayoutPreview(content pdf.Content, width float64, height float64) ([]byte, error) {
+	if !(width > 0) || !(height > 0) || math.IsInf(width, 0) || math.IsInf(height, 0) {
+		return nil, fmt.Errorf("ページの大きさが不正です: %v x %v", width, height)
+	}
+	scale := math.Min(previewWidth/width, maxPreviewHeight/height)
+	w := int(math.Max(1, math.Round(width*scale)))
+	h := int(math.Max(1, math.Round(height*scale)))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), &image.Uniform{previewBackground}, image.Point{}, draw.Src)
+
+	// PDFの座標は左下が原点なので上下を反転する
+	toRect := func(minX, minY, maxX, maxY float64) image.Rectangle {
+		return image.Rect(
+			int(math.Floor(minX*scale)), h-int(math.Ceil(maxY*scale)),
+			int(math.Ceil(maxX*scale)), h-int(math.Floor(minY*scale)),
+		).Intersect(img.Bounds())
+	}
+
+	for _, r := range content.Rect {
+		rect := toRect(r.Min.X, r.Min.Y, r.Max.X, r.Max.Y)
+		// ページ全体を覆う背景は描かない
+		if rect.Dx() >= w-1 && rect.Dy() >= h-1 {
+			continue
+		}
+		draw.Draw(img, rect, &image.Uniform{previewShape}, image.Point{}, draw.Src)
+	}
+	// 字幅の情報が無いフォントでは全ての字が同じ位置になるため，半角分ずつずらす
+	var lastX, lastY, shift float64
+	for _, t := range content.Text {
+		x, w := t.X, t.W
+		if w <= 0 {
+			w = t.FontSize / 2
+			if t.X == lastX && t.Y == lastY {
+				shift += w
+			} else {
+				shift = 0
+			}
+			lastX, lastY = t.X, t.Y
+			x += shift
+		}
+		if strings.TrimSpace(t.S) == "" {
+			continue
+		}
+		// ベースラインから字の高さの7割程度を帯にする
+		rect := toRect(x, t.Y, x+w, t.Y+t.FontSize*0.7)
+		draw.Draw(img, rect, &image.Uniform{previewText}, image.Point{}, draw.Src)
+	}
+
+	bounds := img.Bounds()
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		img.Set(x, bounds.Min.Y, previewBorder)
+		img.Set(x, bounds.Max.Y-1, previewBorder)
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		img.Set(bounds.Min.X, y, previewBorder)
+		img.Set(bounds.Max.X-1, y, previewBorder)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// TestRenderLayoutPreviewFits は縦長のページを切り取らずに上限の高さに収めることを確認する
+func TestRenderLayoutPreviewFits(t *testing.T) {
+	// ページの上端の図形が簡易図に残ることを確かめる
+	content := pdf.Content{Rect: []pdf.Rect{{Min: pdf.Point{X: 10, Y: 9800}, Max: pdf.Point{X: 90, Y: 9990}}}}
+	for _, size := range []struct {
+		width, height float64
+		w, h          int
+	}{
+		{width: 800, height: 600, w: previewWidth, h: 240},
+		{width: 100, height: 10000, w: 13, h: maxPreviewHeight},
+	} {
+		data, err := renderLayoutPreview(content, size.width, size.height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bounds := img.Bounds(); bounds.Dx() != size.w || bounds.Dy() != size.h {
+			t.Errorf("%v x %v: got %d x %d, want %d x %d", size.width, size.height, bounds.Dx(), bounds.Dy(), size.w, size.h)
+		}
+		if size.height == 10000 {
+			if r, g, b, _ := img.At(size.w/2, 10).RGBA(); [3]uint32{r >> 8, g >> 8, b >> 8} != [3]uint32{uint32(previewShape.R), uint32(previewShape.G), uint32(previewShape.B)} {
+				t.Errorf("the top of a tall page is cropped")
+			}
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+)
+
+const (
+	QuestionEditMsgType     = "question_edit"
+	QuestionWithdrawMsgType = "question_withdraw"
+)
+
+type QuestionEditRequest struct {
+	UserId       string `json:"userId"`
+	QuestionBody string `json:"questionBody"`
+}
+
+type QuestionWithdrawRequest struct {
+	UserId string `json:"userId"`
+}
+
+type QuestionHistoryResult struct {
+	Result       bool                 `json:"result"`
+	QuestionId   int                  `json:"questionId"`
+	QuestionBody string               `json:"questionBody"` // 現在の本文
+	Edits        []QuestionEditResult `json:"edits"`        // 古い順
+}
+
+type QuestionEditResult struct {
+	UserId       string `json:"userId"`
+	PreviousBody string `json:"previousBody"` // 編集前の本文
+	EditedAt     string `json:"editedAt"`
+}
+
+// questionOwnerOrHost は質問を操作できる利用者(質問者かホスト)かを確認し，質問の会議を返す
+// 失敗した場合はHTTPのステータスとエラーのcodeを返す
+func questionOwnerOrHost(db *gorm.DB, userId string, question Question) (int, int, string) {
+	found, document := getDocument(db, question.DocumentId)
+	if !found {
+		return 0, http.StatusNotFound, "question_not_found"
+	}
+	if !isQuestionAsker(db, userId, question) && !isHost(db, document.MeetingId, userId) {
+		fmt.Printf("Error: 質問者とホスト以外は質問を変更できません: %d, %s in questionOwnerOrHost\n", question.QuestionId, userId)
+		return 0, http.StatusForbidden, "forbidden"
+	}
+	return document.MeetingId, http.StatusOK, ""
+}
+
+// questionActorId は質問を操作した利用者として記録・通知するIDを返す
+// 質問者本人の操作は保存されている質問者のID (完全匿名の会議では仮名) にする
+func questionActorId(db *gorm.DB, userId string, question Question) string {
+	if isQuestionAsker(db, userId, question) {
+		return question.UserId
+	}
+	return userId
+}
+
+// editOwnQuestion は質問者かホストが質問の本文を変更する
+// 公開中の質問は会議に，承認待ちの質問はホストと質問者にだけ変更を知らせる
+func editOwnQuestion(hub *Hub, db *gorm.DB, userId string, questionId int, questionBody string) (int, string) {
+	found, question := getQuestion(db, questionId)
+	if !found || question.IsVoice {
+		return http.StatusNotFound, "question_not_found"
+	}
+	meetingId, status, code := questionOwnerOrHost(db, userId, question)
+	if code != "" {
+		return status, code
+	}
+	actorId := questionActorId(db, userId, question)
+	body := strings.TrimSpace(questionBody)
+	if body == "" || !editQuestion(db, questionId, actorId, body) {
+		return http.StatusConflict, "invalid_edit"
+	}
+
+	question.QuestionBody = body
+	if question.ModerationStatus == questionApproved {
+		questionUpdateBroadcast(hub, meetingId, question)
+	} else {
+		notifyQuestionUpdate(hub, db, meetingId, question)
+	}
+	sendToHosts(hub, db, meetingId, questionModerationResult(meetingId, moderationEdit, actorId, question))
+	return http.StatusOK, ""
+}
+
+// withdrawQuestion は質問者かホストが質問を取り下げる
+func withdrawQuestion(hub *Hub, db *gorm.DB, userId string, questionId int) (int, string) {
+	found, question := getQuestion(db, questionId)
+	if !found || question.IsVoice {
+		return http.StatusNotFound, "question_not_found"
+	}
+	meetingId, status, code := questionOwnerOrHost(db, userId, question)
+	if code != "" {
+		return status, code
+	}
+	if !setQuestionStatus(db, questionId, []string{questionApproved, questionPending}, questionWithdrawn) {
+		return http.StatusConflict, "invalid_withdraw"
+	}
+
+	wasPublished := question.ModerationStatus == questionApproved
+	question.ModerationStatus = questionWithdrawn
+	if wasPublished {
+		questionUpdateBroadcast(hub, meetingId, question)
+	} else {
+		notifyQuestionUpdate(hub, db, meetingId, question)
+	}
+	sendToHosts(hub, db, meetingId, questionModerationResult(meetingId, moderationWithdraw, questionActorId(db, userId, question), question))
+	return http.StatusOK, ""
+}
+
+// notifyQuestionUpdate は承認待ちの質問の変更を質問者にだけ送る (ホストには question_moderation で送る)
+func notifyQuestionUpdate(hub *Hub, db *gorm.DB, meetingId int, question Question) {
+	messagejson, _ := json.Marshal(QuestionUpdateResult{
+		MessageType:      QuestionUpdateMsgType,
+		MeetingId:        meetingId,
+		QuestionId:       question.QuestionId,
+		QuestionBody:     question.QuestionBody,
+		ModerationStatus: question.ModerationStatus,
+		MergedInto:       question.MergedInto,
+	})
+	_, meeting := getMeeting(db, meetingId)
+	hub.sendToAsker(meeting, question.UserId, messagejson)
+}
+
+// questionEditHandler は質問の本文を変更する
+// WebSocketの question_edit メッセージと同じ操作
+func questionEditHandler(hub *Hub, db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		questionId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		request := new(QuestionEditRequest)
+		if err := c.Bind(request); err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		if status, _ := editOwnQuestion(hub, db, request.UserId, questionId, request.QuestionBody); status != http.StatusOK {
+			return c.JSON(status, &Result{Result: false})
+		}
+		return c.JSON(http.StatusOK, &Result{Result: true})
+	}
+}
+
+// questionWithdrawHandler は質問を取り下げる
+// WebSocketの question_withdraw メッセージと同じ操作
+func questionWithdrawHandler(hub *Hub, db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		questionId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		request := new(QuestionWithdrawRequest)
+		if err := c.Bind(request); err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		if status, _ := withdrawQuestion(hub, db, request.UserId, questionId); status != http.StatusOK {
+			return c.JSON(status, &Result{Result: false})
+		}
+		return c.JSON(http.StatusOK, &Result{Result: true})
+	}
+}
+
+// questionHistory は質問の編集履歴を返す (?userId=質問者かホスト)
+// 質問者かどうかが分からないよう，それ以外の利用者にも同じ形で履歴を空にして返す
+func questionHistory(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		questionId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &QuestionHistoryResult{Result: false})
+		}
+		found, question := getQuestion(db, questionId)
+		if !found || question.IsVoice {
+			return c.JSON(http.StatusNotFound, &QuestionHistoryResult{Result: false})
+		}
+		_, status, code := questionOwnerOrHost(db, c.QueryParam("userId"), question)
+		if status == http.StatusNotFound {
+			return c.JSON(status, &QuestionHistoryResult{Result: false})
+		}
+		if code != "" {
+			result := &QuestionHistoryResult{Result: true, QuestionId: questionId, Edits: []QuestionEditResult{}}
+			if question.ModerationStatus == questionApproved {
+				result.QuestionBody = question.QuestionBody
+			}
+			return c.JSON(http.StatusOK, result)
+		}
+
+		location, _ := time.LoadLocation("Asia/Tokyo")
+		edits := getQuestionEdits(db, questionId)
+		result := &QuestionHistoryResult{
+			Result:       true,
+			QuestionId:   questionId,
+			QuestionBody: question.QuestionBody,
+			Edits:        make([]QuestionEditResult, 0, len(edits)),
+		}
+		for _, edit := range edits {
+			result.Edits = append(result.Edits, QuestionEditResult{
+				UserId:       edit.UserId,
+				PreviousBody: edit.PreviousBody,
+				EditedAt:     edit.EditedAt.In(location).Format("2006/01/02 15:04:05"),
+			})
+		}
+		return c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+)
+
+const (
+	defaultQuestionLimit = 50
+	maxQuestionLimit     = 200
+)
+
+// QuestionListResult は GET /meetings/:id/questions の結果
+// QuestionsはWebSocketの question メッセージと同じ形
+type QuestionListResult struct {
+	Result     bool             `json:"result"`
+	MeetingId  int              `json:"meetingId"`
+	Questions  []QuestionResult `json:"questions"`
+	NextCursor string           `json:"nextCursor"` // 次のページが無ければ空
+}
+
+// questionResult は質問をWebSocketの question メッセージの形にする
+func questionResult(meetingId int, presenterId string, askerId string, question Question) QuestionResult {
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	return QuestionResult{
+		MessageType:  "question",
+		QuestionId:   question.QuestionId,
+		MeetingId:    meetingId,
+		QuestionBody: question.QuestionBody,
+		DocumentId:   question.DocumentId,
+		DocumentPage: question.DocumentPage,
+		QuestionTime: question.QuestionTime.In(location).Format("2006/01/02 15:04:05"),
+		PresenterId:  presenterId,
+		AskerId:      askerId,
+		VoteNum:      question.VoteNum,
+		IsAnswered:   question.QuestionOk,
+		IsVoice:      question.IsVoice,
+
+		DocumentVersion: question.DocumentVersion,
+	}
+}
+
+// encodeQuestionCursor は次のページの開始位置を表す文字列を作る
+// 並び順のキー(質問時刻か投票数)と質問IDを持つ
+func encodeQuestionCursor(sortByVotes bool, question Question) string {
+	key := strconv.FormatInt(question.QuestionTime.UnixNano(), 10)
+	if sortByVotes {
+		key = strconv.Itoa(question.VoteNum)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(key + ":" + strconv.Itoa(question.QuestionId)))
+}
+
+func decodeQuestionCursor(cursor string, filter *QuestionFilter) error {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return err
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("cursorの形式が不正です")
+	}
+	key, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return err
+	}
+	if filter.AfterId, err = strconv.Atoi(parts[1]); err != nil || filter.AfterId <= 0 {
+		return fmt.Errorf("cursorの形式が不正です")
+	}
+	if filter.SortByVotes {
+		filter.AfterVoteNum = int(key)
+	} else {
+		filter.AfterTime = time.Unix(0, key)
+	}
+	return nil
+}
+
+// parseQuestionFilter は一覧の条件をクエリパラメータから読む
+// 質問者での絞り込みは，質問者を見られる利用者(ホスト，質問者を明かす会議の参加者)のみ
+func parseQuestionFilter(c echo.Context, db *gorm.DB, meeting Meeting) (QuestionFilter, error) {
+	filter := QuestionFilter{MeetingId: meeting.MeetingId, Limit: defaultQuestionLimit}
+	userId := c.QueryParam("userId")
+
+	if presenterId := c.QueryParam("presenterId"); presenterId != "" {
+		if filter.DocumentId = getDocumentId(db, presenterId, meeting.MeetingId); filter.DocumentId == -1 {
+			return filter, fmt.Errorf("発表者ではありません: %s", presenterId)
+		}
+	}
+	if documentId := c.QueryParam("documentId"); documentId != "" {
+		id, err := strconv.Atoi(documentId)
+		if err != nil || (filter.DocumentId != 0 && filter.DocumentId != id) {
+			return filter, fmt.Errorf("documentIdが不正です: %s", documentId)
+		}
+		filter.DocumentId = id
+	}
+	if page := c.QueryParam("page"); page != "" {
+		documentPage, err := strconv.Atoi(page)
+		if err != nil || documentPage <= 0 {
+			return filter, fmt.Errorf("pageが不正です: %s", page)
+		}
+		filter.DocumentPage = documentPage
+	}
+	if answered := c.QueryParam("answered"); answered != "" {
+		isAnswered, err := strconv.ParseBool(answered)
+		if err != nil {
+			return filter, fmt.Errorf("answeredが不正です: %s", answered)
+		}
+		filter.Answered = &isAnswered
+	}
+	switch kind := c.QueryParam("kind"); kind {
+	case "":
+	case "voice", "text":
+		isVoice := kind == "voice"
+		filter.IsVoice = &isVoice
+	default:
+		return filter, fmt.Errorf("kindが不正です: %s", kind)
+	}
+	if askerId := c.QueryParam("askerId"); askerId != "" {
+		// userIdは自己申告のため，本人であっても匿名の会議では質問者に結び付けない
+		if meeting.AnonymityLevel != anonymityAttributed && !isHost(db, meeting.MeetingId, userId) {
+			return filter, fmt.Errorf("質問者で絞り込めません: %s", userId)
+		}
+		filter.AskerId = askerId
+	}
+	switch sort := c.QueryParam("sort"); sort {
+	case "", "time":
+	case "votes":
+		filter.SortByVotes = true
+	default:
+		return filter, fmt.Errorf("sortが不正です: %s", sort)
+	}
+	if limit := c.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > maxQuestionLimit {
+			return filter, fmt.Errorf("limitが不正です: %s", limit)
+		}
+		filter.Limit = n
+	}
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		if err := decodeQuestionCursor(cursor, &filter); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+// listMeetingQuestions は会議の公開中の質問をクエリパラメータの条件で絞り込み・並び替えて返す
+func listMeetingQuestions(c echo.Context, db *gorm.DB, meeting Meeting) (*QuestionListResult, error) {
+	filter, err := parseQuestionFilter(c, db, meeting)
+	if err != nil {
+		fmt.Printf("Error: 質問の一覧の条件が不正です: %d, %v in listMeetingQuestions\n", meeting.MeetingId, err)
+		return nil, invalidError("invalid_query", "invalid query parameter", map[string]interface{}{"reason": err.Error()})
+	}
+
+	// 1件多く取得して次のページがあるかを調べる
+	filter.Limit++
+	questions, ok := findQuestions(db, filter)
+	if !ok {
+		return nil, &StoreError{Code: "internal_error", Message: "failed to find questions"}
+	}
+	result := &QuestionListResult{Result: true, MeetingId: meeting.MeetingId}
+	if len(questions) == filter.Limit {
+		questions = questions[:filter.Limit-1]
+		result.NextCursor = encodeQuestionCursor(filter.SortByVotes, questions[len(questions)-1])
+	}
+
+	forHost := isHost(db, meeting.MeetingId, c.QueryParam("userId"))
+	presenterIds := make(map[int]string)
+	result.Questions = make([]QuestionResult, 0, len(questions))
+	for _, question := range questions {
+		if _, ok := presenterIds[question.DocumentId]; !ok {
+			presenterIds[question.DocumentId] = getPresenterId(db, question.DocumentId)
+		}
+		result.Questions = append(result.Questions, questionResult(meeting.MeetingId, presenterIds[question.DocumentId], visibleAskerId(meeting.AnonymityLevel, forHost, question), question))
+	}
+	return result, nil
+}
+
+// meetingQuestions は会議の公開中の質問を絞り込み・並び替えて返す
+// ?presenterId=&documentId=&page=&answered=true|false&kind=voice|text&askerId=&sort=time|votes&limit=&cursor=&userId=閲覧者
+func meetingQuestions(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		meetingId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &QuestionListResult{Result: false})
+		}
+		found, meeting := getMeeting(db, meetingId)
+		if !found {
+			return c.JSON(http.StatusNotFound, &QuestionListResult{Result: false})
+		}
+		result, err := listMeetingQuestions(c, db, meeting)
+		if err != nil {
+			if errors.Is(err, errInvalid) {
+				return c.JSON(http.StatusBadRequest, &QuestionListResult{Result: false})
+			}
+			return c.JSON(http.StatusInternalServerError, &QuestionListResult{Result: false})
+		}
+		return c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 使われなくなったバケットを掃除する間隔
+const bucketSweepInterval = 5 * time.Minute
+
+// 制限に掛かった理由 (エラーフレームのcode)
+const (
+	throttleRateLimited = "rate_limited"
+	throttleMuted       = "muted"
+)
+
+// tokenBucket は一定の速度でトークンが補充されるバケット
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take はトークンを1つ消費できればtrueを返す
+// 消費できなければ次のトークンが補充されるまでの時間を返す
+func (b *tokenBucket) take(config BucketConfig, now time.Time) (bool, time.Duration) {
+	b.tokens = math.Min(float64(config.Burst), b.tokens+now.Sub(b.last).Seconds()*config.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / config.Rate * float64(time.Second))
+}
+
+// rateLimiter はキー毎のトークンバケットを管理する
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow はconfigの設定でkeyのトークンを消費する
+// Rateが0以下の場合は制限しない
+func (l *rateLimiter) allow(key string, config BucketConfig) (bool, time.Duration) {
+	if config.Rate <= 0 {
+		return true, 0
+	}
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > bucketSweepInterval {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > bucketSweepInterval {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(config.Burst), last: now}
+		l.buckets[key] = b
+	}
+	return b.take(config, now)
+}
+
+// messageThrottle はWebSocketのメッセージの流量制限と，ホストによるミュートを扱う
+type messageThrottle struct {
+	config  map[string]BucketConfig
+	limiter *rateLimiter
+
+	mu    sync.Mutex
+	mutes map[string]time.Time // meetingId/userId -> ミュート解除時刻
+}
+
+func newMessageThrottle(config map[string]BucketConfig) *messageThrottle {
+	return &messageThrottle{
+		config:  config,
+		limiter: newRateLimiter(),
+		mutes:   make(map[string]time.Time),
+	}
+}
+
+func muteKey(meetingId int, userId string) string {
+	return fmt.Sprintf("%d/%s", meetingId, userId)
+}
+
+// mute はuserIdを会議内でdurationの間ミュートし，解除時刻を返す
+func (t *messageThrottle) mute(meetingId int, userId string, duration time.Duration) time.Time {
+	until := time.Now().Add(duration)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.mutes[muteKey(meetingId, userId)] = until
+	return until
+}
+
+func (t *messageThrottle) unmute(meetingId int, userId string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.mutes, muteKey(meetingId, userId))
+}
+
+// mutedUntil はuserIdが会議でミュートされていれば解除時刻を返す
+// meetingIdが0 (メッセージから会議が分からない) の場合はどの会議のミュートも当てはめる
+func (t *messageThrottle) mutedUntil(meetingId int, userId string) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if meetingId != 0 {
+		key := muteKey(meetingId, userId)
+		until, ok := t.mutes[key]
+		if ok && now.After(until) {
+			delete(t.mutes, key)
+			return time.Time{}, false
+		}
+		return until, ok
+	}
+	for key, until := range t.mutes {
+		if now.After(until) {
+			delete(t.mutes, key)
+			continue
+		}
+		if strings.SplitN(key, "/", 2)[1] == userId {
+			return until, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// check はmessageTypeのメッセージを受け付けてよいかを返す
+// 受け付けない場合は理由と再送までの時間を返す
+// ミュート中は流量制限の設定が無い種類のメッセージも受け付けない
+func (t *messageThrottle) check(meetingId int, senderKey string, userId string, messageType string) (string, time.Duration) {
+	if userId != "" {
+		if until, muted := t.mutedUntil(meetingId, userId); muted {
+			return throttleMuted, time.Until(until)
+		}
+	}
+	config, ok := t.config[messageType]
+	if !ok {
+		return "", 0
+	}
+	key := fmt.Sprintf("%d/%s/%s", meetingId, senderKey, messageType)
+	if ok, retryAfter := t.limiter.allow(key, config); !ok {
+		return throttleRateLimited, retryAfter
+	}
+	return "", 0
+}
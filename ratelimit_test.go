@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testMessage はクライアントから送るメッセージの構造体の全ての項目を埋めたメッセージを作る (meetingIdは省く)
+func testMessage(payload interface{}, userId string) map[string]interface{} {
+	obj := map[string]interface{}{}
+	t := reflect.TypeOf(payload)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		switch {
+		case name == "meetingId":
+		case name == "userId":
+			if userId != "" {
+				obj[name] = userId
+			}
+		case field.Type.Kind() == reflect.String:
+			obj[name] = "x"
+		case field.Type.Kind() == reflect.Int:
+			obj[name] = float64(1)
+		case field.Type.Kind() == reflect.Bool:
+			obj[name] = true
+		}
+	}
+	return obj
+}
+
+// testMeetingOf は資料1と質問1を会議7のものとする
+func testMeetingOf(obj map[string]interface{}) int {
+	if _, ok := obj["documentId"]; ok {
+		return 7
+	}
+	if _, ok := obj["questionId"]; ok {
+		return 7
+	}
+	return 0
+}
+
+// TestMutedSender はミュートされた利用者がmeetingIdやuserIdを省いてもどの種類のメッセージも送れないことを確認する
+func TestMutedSender(t *testing.T) {
+	throttle := newMessageThrottle(map[string]BucketConfig{})
+	throttle.mute(7, "muted1", time.Minute)
+
+	for _, m := range wsMessages {
+		if !m.FromClient {
+			continue
+		}
+		// 接続時にuserIdだけを指定した接続
+		c := &Client{userId: "muted1"}
+		userId, meetingId := c.sender(testMessage(m.Payload, ""), testMeetingOf)
+		if reason, _ := throttle.check(meetingId, "user:"+userId, userId, m.MessageType); reason != throttleMuted {
+			t.Errorf("%s from a connection with userId: got %q, want %q", m.MessageType, reason, throttleMuted)
+		}
+
+		// userIdを指定していない接続で，以前のメッセージで名乗った利用者
+		legacy := &Client{}
+		legacy.sender(testMessage(HandsUpMessage{}, "muted1"), testMeetingOf)
+		userId, meetingId = legacy.sender(testMessage(m.Payload, ""), testMeetingOf)
+		if reason, _ := throttle.check(meetingId, "user:"+userId, userId, m.MessageType); reason != throttleMuted {
+			t.Errorf("%s from a legacy connection: got %q, want %q", m.MessageType, reason, throttleMuted)
+		}
+
+		// ミュートされていない利用者
+		other := &Client{userId: "other1"}
+		userId, meetingId = other.sender(testMessage(m.Payload, ""), testMeetingOf)
+		if reason, _ := throttle.check(meetingId, "user:"+userId, userId, m.MessageType); reason != "" {
+			t.Errorf("%s from another user: got %q, want none", m.MessageType, reason)
+		}
+	}
+}
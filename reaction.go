@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+)
+
+const PaceSuggestionMsgType = "pace_suggestion"
+
+// リアクションの種類
+const (
+	reactionConfused = "confused" // 分からない (kindを省略した場合．司会が質問を促す)
+	reactionAgree    = "agree"
+	reactionTooFast  = "too_fast" // 司会が発表者にペースを提案する
+	reactionTooSlow  = "too_slow" // 同上
+	reactionApplause = "applause"
+	reactionEmoji    = "emoji" // emojiに絵文字を指定する
+)
+
+// 絵文字のリアクションの最大の文字数 (結合文字を含む)
+const maxReactionEmojiLength = 8
+
+// ペースを提案する最小の人数
+const minPaceReactions = 2
+
+var reactionKinds = []string{reactionConfused, reactionAgree, reactionTooFast, reactionTooSlow, reactionApplause, reactionEmoji}
+
+// PaceSuggestionResult は話すペースの提案 (発表者のみ)
+type PaceSuggestionResult struct {
+	MessageType  string `json:"messageType"`
+	MeetingId    int    `json:"meetingId"`
+	DocumentId   int    `json:"documentId"`
+	DocumentPage int    `json:"documentPage"` // 発表中のページ
+	Kind         string `json:"kind"`         // too_fast, too_slow
+	ReactionNum  int    `json:"reactionNum"`  // 期間内にリアクションした人数
+	Message      string `json:"message"`
+}
+
+type PageReactionsResult struct {
+	DocumentPage int            `json:"documentPage"`
+	Counts       map[string]int `json:"counts"`      // 種類毎の数
+	EmojiCounts  map[string]int `json:"emojiCounts"` // 絵文字毎の数
+}
+
+// DocumentReactionsResult は GET /document/:id/reactions の結果
+type DocumentReactionsResult struct {
+	Result          bool                  `json:"result"`
+	MeetingId       int                   `json:"meetingId"`
+	DocumentId      int                   `json:"documentId"`
+	DocumentVersion int                   `json:"documentVersion"`
+	Pages           []PageReactionsResult `json:"pages"`  // ページ毎の合計
+	Window          int                   `json:"window"` // 直近の集計の秒数 (?window= を指定した場合)
+	Recent          []PageReactionsResult `json:"recent"` // 直近window秒のリアクション (参加者を特定できるもののみ)
+}
+
+// validReaction はリアクションの種類と絵文字が正しいかを返す
+func validReaction(kind string, emoji string) bool {
+	if kind != reactionEmoji {
+		return emoji == "" && isReactionKind(kind)
+	}
+	if emoji == "" || utf8.RuneCountInString(emoji) > maxReactionEmojiLength || !utf8.ValidString(emoji) {
+		return false
+	}
+	for _, r := range emoji {
+		if r < utf8.RuneSelf || unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isReactionKind(kind string) bool {
+	for _, k := range reactionKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// pageReactionResults はリアクションの数をページ毎にまとめる
+// confusedはpagesのページのみ，legacyの(参加者を特定できないものを含む)数で上書きする
+func pageReactionResults(counts []PageReactionCount, legacy []Reaction, version int) []PageReactionsResult {
+	results := make([]PageReactionsResult, 0, len(counts))
+	index := make(map[int]int)
+	page := func(documentPage int) *PageReactionsResult {
+		i, ok := index[documentPage]
+		if !ok {
+			i = len(results)
+			index[documentPage] = i
+			results = append(results, PageReactionsResult{DocumentPage: documentPage, Counts: make(map[string]int), EmojiCounts: make(map[string]int)})
+		}
+		return &results[i]
+	}
+	for _, count := range counts {
+		result := page(count.DocumentPage)
+		result.Counts[count.Kind] += count.ReactionNum
+		if count.Kind == reactionEmoji {
+			result.EmojiCounts[count.Emoji] += count.ReactionNum
+		}
+	}
+	for _, reaction := range legacy {
+		if reaction.DocumentVersion == version && reaction.ReactionNum > 0 {
+			page(reaction.DocumentPage).Counts[reactionConfused] = reaction.ReactionNum
+		}
+	}
+	return results
+}
+
+// pageReactionSummary は資料のページへのリアクションの種類毎の数を返す
+func pageReactionSummary(db *gorm.DB, documentId int, version int, documentPage int) (map[string]int, map[string]int) {
+	for _, result := range pageReactionResults(getPageReactionCounts(db, documentId, version, time.Time{}), getDocumentReactions(db, documentId), version) {
+		if result.DocumentPage == documentPage {
+			return result.Counts, result.EmojiCounts
+		}
+	}
+	return map[string]int{}, map[string]int{}
+}
+
+// 期限の切れたキーを消す間隔
+const cooldownPruneInterval = time.Minute
+
+// cooldown はキー毎に最後に送った時刻を覚え，同じ通知を送り過ぎないようにする
+type cooldown struct {
+	mu       sync.Mutex
+	last     map[string]cooldownEntry
+	prunedAt time.Time
+}
+
+type cooldownEntry struct {
+	at       time.Time
+	interval time.Duration
+}
+
+func newCooldown() *cooldown {
+	return &cooldown{last: make(map[string]cooldownEntry)}
+}
+
+// ready はkeyを前回からinterval以上経っていれば記録してtrueを返す
+func (c *cooldown) ready(key string, interval time.Duration) bool {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.prune(now)
+	if last, ok := c.last[key]; ok && now.Sub(last.at) < last.interval {
+		return false
+	}
+	c.last[key] = cooldownEntry{at: now, interval: interval}
+	return true
+}
+
+// prune は間隔が過ぎて不要になったキーを消す (会議が終わった資料などのキーが溜まらないようにする)
+func (c *cooldown) prune(now time.Time) {
+	if now.Sub(c.prunedAt) < cooldownPruneInterval {
+		return
+	}
+	c.prunedAt = now
+	for key, last := range c.last {
+		if now.Sub(last.at) >= last.interval {
+			delete(c.last, key)
+		}
+	}
+}
+
+// once はkeyをttlの間に1回だけtrueを返す (ttlが過ぎたキーは消す)
+func (c *cooldown) once(key string, ttl time.Duration) bool {
+	return c.ready(key, ttl)
+}
+
+// suggestPace は直近の「速すぎる」「遅すぎる」のリアクションが参加者の一定の割合を超えたら発表者にペースを提案する
+func suggestPace(hub *Hub, db *gorm.DB, documentId int, kind string) {
+	config := hub.config.Moderator
+	if config.PaceThreshold <= 0 {
+		return
+	}
+	found, document := getDocument(db, documentId)
+	if !found {
+		return
+	}
+
+	// ページ毎に付けられるため，複数のページに付けた参加者も1人と数える
+	reactionNum := countReactionUsers(db, documentId, document.Version, kind, time.Now().Add(-config.PaceWindow))
+	audience := countJoiningParticipants(db, document.MeetingId, document.UserId)
+	if reactionNum < minPaceReactions || reactionNum < int(math.Ceil(config.PaceThreshold*float64(audience))) {
+		return
+	}
+	if !hub.pace.ready(fmt.Sprintf("%d/%s", documentId, kind), config.PaceCooldown) {
+		return
+	}
+
+	body := fmt.Sprintf(paceTooFastMessage, reactionNum)
+	if kind == reactionTooSlow {
+		body = fmt.Sprintf(paceTooSlowMessage, reactionNum)
+	}
+	messagejson, _ := json.Marshal(PaceSuggestionResult{
+		MessageType:  PaceSuggestionMsgType,
+		MeetingId:    document.MeetingId,
+		DocumentId:   documentId,
+		DocumentPage: getCurrentPage(db, documentId),
+		Kind:         kind,
+		ReactionNum:  reactionNum,
+		Message:      body,
+	})
+	if hub.sendToMeetingUsers(document.MeetingId, []string{document.UserId}, messagejson) == 0 {
+		fmt.Printf("Log: 発表者が接続していないためペースの提案を送れませんでした: %d, %s in suggestPace\n", documentId, document.UserId)
+	}
+}
+
+// documentReactions は資料のページ毎・種類毎のリアクションの数を返す
+// 会議の参加者のみ取得できる (?userId=&version=&window=秒)
+func documentReactions(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		documentId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &DocumentReactionsResult{Result: false})
+		}
+		found, document := getDocument(db, documentId)
+		if !found {
+			return c.JSON(http.StatusNotFound, &DocumentReactionsResult{Result: false})
+		}
+		if !isMeetingMember(db, document.MeetingId, c.QueryParam("userId")) {
+			return c.JSON(http.StatusForbidden, &DocumentReactionsResult{Result: false})
+		}
+		version := document.Version
+		if v := c.QueryParam("version"); v != "" {
+			if version, err = strconv.Atoi(v); err != nil || version < 1 || version > document.Version {
+				return c.JSON(http.StatusBadRequest, &DocumentReactionsResult{Result: false})
+			}
+		}
+		window := 0
+		if w := strings.TrimSpace(c.QueryParam("window")); w != "" {
+			if window, err = strconv.Atoi(w); err != nil || window <= 0 {
+				return c.JSON(http.StatusBadRequest, &DocumentReactionsResult{Result: false})
+			}
+		}
+
+		result := &DocumentReactionsResult{
+			Result:          true,
+			MeetingId:       document.MeetingId,
+			DocumentId:      documentId,
+			DocumentVersion: version,
+			Pages:           pageReactionResults(getPageReactionCounts(db, documentId, version, time.Time{}), getDocumentReactions(db, documentId), version),
+			Window:          window,
+			Recent:          []PageReactionsResult{},
+		}
+		if window > 0 {
+			since := time.Now().Add(-time.Duration(window) * time.Second)
+			result.Recent = pageReactionResults(getPageReactionCounts(db, documentId, version, since), nil, version)
+		}
+		return c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+)
+
+const (
+	reportTimeLayout = "2006/01/02 15:04:05"
+
+	// 発表者毎に載せるリアクションの多いページの数
+	reportHotSpotNum = 3
+)
+
+// MeetingReport は会議の議事録
+type MeetingReport struct {
+	MeetingId         int
+	MeetingName       string
+	StartTime         time.Time
+	EndTime           *time.Time
+	HostName          string
+	Presenters        []PresenterReport // 発表順
+	ModeratorMessages []ModeratorMessage
+	AskerNames        map[int]string // 質問者の名前 (質問ID毎，質問者を明かす会議のみ)
+}
+
+// PresenterReport は発表者毎の記録
+type PresenterReport struct {
+	Order      int
+	UserId     string
+	UserName   string
+	DocumentId int
+	Answered   []Question       // 回答済みの質問
+	Unanswered []Question       // 未回答の質問
+	FollowUps  []FollowUpReport // 会議後に文章で回答した質問
+	Answers    map[int][]Answer // 会議中に文章で回答した質問の回答 (質問ID毎)
+	HandsUp    []HandsUpReport  // 挙手 (挙手した順)
+	HotSpots   []PageAnalytics  // リアクションの多いページ
+}
+
+// FollowUpReport は会議後の回答の記録
+type FollowUpReport struct {
+	Question
+	Answer Answer
+}
+
+// HandsUpReport は挙手の記録 (挙手は他の参加者にも見えるため名前を載せる)
+type HandsUpReport struct {
+	Question
+	UserName string
+}
+
+// buildMeetingReport は会議の議事録をDBから組み立てる
+func buildMeetingReport(db *gorm.DB, meeting Meeting, now time.Time) *MeetingReport {
+	report := &MeetingReport{
+		MeetingId:         meeting.MeetingId,
+		MeetingName:       meeting.MeetingName,
+		StartTime:         meeting.MeetingStartTime,
+		EndTime:           meeting.MeetingEndTime,
+		ModeratorMessages: getModeratorMessages(db, meeting.MeetingId),
+		AskerNames:        make(map[int]string),
+	}
+	if meeting.HostUserId != "" {
+		report.HostName = getUserName(db, meeting.HostUserId)
+	}
+
+	for _, presenter := range getPresenters(db, meeting.MeetingId) {
+		presenterReport := PresenterReport{
+			Order:    presenter.ParticipantOrder,
+			UserId:   presenter.UserId,
+			UserName: getUserName(db, presenter.UserId),
+		}
+		found, document := false, Document{}
+		if documentId := getDocumentId(db, presenter.UserId, meeting.MeetingId); documentId != -1 {
+			found, document = getDocument(db, documentId)
+		}
+		if !found {
+			report.Presenters = append(report.Presenters, presenterReport)
+			continue
+		}
+		presenterReport.DocumentId = document.DocumentId
+
+		questions := getDocumentQuestions(db, document.DocumentId)
+		answers := getDocumentAnswers(db, document.DocumentId)
+		presenterReport.Answers = answers
+		for _, question := range questions {
+			if visibleAskerId(meeting.AnonymityLevel, false, question) != "" {
+				report.AskerNames[question.QuestionId] = getUserName(db, question.UserId)
+			}
+			if followUp, ok := reportFollowUp(answers[question.QuestionId]); ok {
+				presenterReport.FollowUps = append(presenterReport.FollowUps, FollowUpReport{Question: question, Answer: followUp})
+				continue
+			}
+			switch {
+			case question.ModerationStatus != questionApproved:
+				// 承認待ち・却下・統合された質問は載せない
+			case question.IsVoice && !question.IsNominated:
+				presenterReport.HandsUp = append(presenterReport.HandsUp, HandsUpReport{Question: question, UserName: getUserName(db, question.UserId)})
+			case question.IsVoice:
+				// 司会による指名は挙手でも質問でもないため載せない
+			case question.QuestionOk:
+				presenterReport.Answered = append(presenterReport.Answered, question)
+			default:
+				presenterReport.Unanswered = append(presenterReport.Unanswered, question)
+			}
+		}
+		sort.Stable(ReverseByVoteNum(presenterReport.Unanswered))
+
+		analytics := analyzeDocument(document, 0, getPageViews(db, document.DocumentId), getDocumentReactions(db, document.DocumentId), getDocumentPageReactionCounts(db, document.DocumentId), questions, now)
+		pages := analytics.Pages
+		sort.SliceStable(pages, func(i, j int) bool { return pages[i].ReactionNum > pages[j].ReactionNum })
+		for _, page := range pages {
+			if page.ReactionNum == 0 || len(presenterReport.HotSpots) == reportHotSpotNum {
+				break
+			}
+			presenterReport.HotSpots = append(presenterReport.HotSpots, page)
+		}
+		report.Presenters = append(report.Presenters, presenterReport)
+	}
+	return report
+}
+
+// reportFollowUp は回答の中から事後の回答を探す
+func reportFollowUp(answers []Answer) (Answer, bool) {
+	for _, answer := range answers {
+		if answer.IsFollowUp {
+			return answer, true
+		}
+	}
+	return Answer{}, false
+}
+
+func reportTime(t time.Time) string {
+	location, _ := time.LoadLocation("Asia/Tokyo")
+	return t.In(location).Format(reportTimeLayout)
+}
+
+// reportQuestionBody は質問の本文を1行にする
+// 質問者を明かす会議では質問者の名前を付ける
+func reportQuestionBody(report *MeetingReport, question Question) string {
+	body := strings.Join(strings.Fields(question.QuestionBody), " ")
+	if question.UserId == moderatorUserId {
+		return "(司会) " + body
+	}
+	if name, ok := report.AskerNames[question.QuestionId]; ok {
+		return "(" + name + ") " + body
+	}
+	return body
+}
+
+// renderReportMarkdown は議事録をMarkdownで書き出す
+// 質問者は質問者を明かす会議の場合のみ載せる
+func renderReportMarkdown(w io.Writer, report *MeetingReport) {
+	fmt.Fprintf(w, "# %s 議事録\n\n", report.MeetingName)
+	fmt.Fprintf(w, "- 開始: %s\n", reportTime(report.StartTime))
+	if report.EndTime != nil {
+		fmt.Fprintf(w, "- 終了: %s\n", reportTime(*report.EndTime))
+	} else {
+		fmt.Fprintf(w, "- 終了: (未終了)\n")
+	}
+	if report.HostName != "" {
+		fmt.Fprintf(w, "- ホスト: %s\n", report.HostName)
+	}
+
+	fmt.Fprintf(w, "\n## 発表順\n\n")
+	for i, presenter := range report.Presenters {
+		fmt.Fprintf(w, "%d. %s\n", i+1, presenter.UserName)
+	}
+
+	for i, presenter := range report.Presenters {
+		fmt.Fprintf(w, "\n## %d. %s\n", i+1, presenter.UserName)
+
+		fmt.Fprintf(w, "\n### 回答済みの質問 (%d件)\n\n", len(presenter.Answered))
+		for _, question := range presenter.Answered {
+			fmt.Fprintf(w, "- p.%d [%d票] %s\n", question.DocumentPage, question.VoteNum, reportQuestionBody(report, question))
+			for _, answer := range presenter.Answers[question.QuestionId] {
+				fmt.Fprintf(w, "  - 回答 (%s): %s\n", reportTime(answer.AnsweredAt), strings.Join(strings.Fields(answer.AnswerBody), " "))
+			}
+		}
+		fmt.Fprintf(w, "\n### 未回答の質問 (%d件)\n\n", len(presenter.Unanswered))
+		for _, question := range presenter.Unanswered {
+			fmt.Fprintf(w, "- p.%d [%d票] %s\n", question.DocumentPage, question.VoteNum, reportQuestionBody(report, question))
+		}
+
+		fmt.Fprintf(w, "\n### 会議後に回答した質問 (%d件)\n\n", len(presenter.FollowUps))
+		for _, followUp := range presenter.FollowUps {
+			fmt.Fprintf(w, "- p.%d [%d票] %s\n  - 回答 (%s): %s\n", followUp.DocumentPage, followUp.VoteNum, reportQuestionBody(report, followUp.Question), reportTime(followUp.Answer.AnsweredAt), strings.Join(strings.Fields(followUp.Answer.AnswerBody), " "))
+		}
+
+		fmt.Fprintf(w, "\n### 挙手 (%d件)\n\n", len(presenter.HandsUp))
+		for _, handsUp := range presenter.HandsUp {
+			status := "未指名"
+			if handsUp.QuestionOk {
+				status = "指名済み"
+			} else if handsUp.IsLowered {
+				status = "取り下げ"
+			}
+			fmt.Fprintf(w, "- %s %s p.%d (%s)\n", reportTime(handsUp.QuestionTime), handsUp.UserName, handsUp.DocumentPage, status)
+		}
+
+		fmt.Fprintf(w, "\n### リアクションの多いページ\n\n")
+		if len(presenter.HotSpots) == 0 {
+			fmt.Fprintf(w, "- なし\n")
+		}
+		for _, page := range presenter.HotSpots {
+			fmt.Fprintf(w, "- p.%d: リアクション%d件, 質問%d件, 表示%.0f秒\n", page.DocumentPage, page.ReactionNum, page.QuestionNum, page.DwellSeconds)
+		}
+	}
+
+	fmt.Fprintf(w, "\n## 司会メッセージ\n\n")
+	for _, message := range report.ModeratorMessages {
+		fmt.Fprintf(w, "- %s %s\n", reportTime(message.SentAt), strings.Join(strings.Fields(message.Body), " "))
+	}
+}
+
+// writeReportCsv は議事録を1行1項目のCSVで書き出す
+func writeReportCsv(w io.Writer, report *MeetingReport) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"type", "presenterOrder", "presenterId", "presenterName", "documentPage", "time", "voteNum", "reactionNum", "answered", "body", "answer"})
+	for i, presenter := range report.Presenters {
+		order := strconv.Itoa(i + 1)
+		questions := append(append([]Question{}, presenter.Answered...), presenter.Unanswered...)
+		sort.Stable(ByQuestionTime(questions))
+		for _, question := range questions {
+			writer.Write([]string{"question", order, presenter.UserId, presenter.UserName, strconv.Itoa(question.DocumentPage), reportTime(question.QuestionTime), strconv.Itoa(question.VoteNum), "", strconv.FormatBool(question.QuestionOk), reportQuestionBody(report, question), ""})
+		}
+		for _, followUp := range presenter.FollowUps {
+			writer.Write([]string{"followup", order, presenter.UserId, presenter.UserName, strconv.Itoa(followUp.DocumentPage), reportTime(followUp.QuestionTime), strconv.Itoa(followUp.VoteNum), "", "true", reportQuestionBody(report, followUp.Question), strings.TrimSpace(followUp.Answer.AnswerBody)})
+		}
+		for _, handsUp := range presenter.HandsUp {
+			writer.Write([]string{"handsup", order, presenter.UserId, presenter.UserName, strconv.Itoa(handsUp.DocumentPage), reportTime(handsUp.QuestionTime), "", "", strconv.FormatBool(handsUp.QuestionOk), handsUp.UserName, ""})
+		}
+		for _, page := range presenter.HotSpots {
+			writer.Write([]string{"hotspot", order, presenter.UserId, presenter.UserName, strconv.Itoa(page.DocumentPage), "", "", strconv.Itoa(page.ReactionNum), "", "", ""})
+		}
+	}
+	for _, message := range report.ModeratorMessages {
+		writer.Write([]string{"moderator", "", "", "", "", reportTime(message.SentAt), "", "", "", strings.TrimSpace(message.Body), ""})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// meetingReport は会議の議事録を返す (?format=md|csv|pdf，省略時はMarkdown)
+// 会議の参加者のみ取得できる (?userId=...)
+func meetingReport(db *gorm.DB) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		meetingId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+		found, meeting := getMeeting(db, meetingId)
+		if !found {
+			return c.JSON(http.StatusNotFound, &Result{Result: false})
+		}
+		if !isMeetingMember(db, meetingId, c.QueryParam("userId")) {
+			fmt.Printf("Error: 会議の参加者以外は議事録を取得できません: %d, %s in meetingReport\n", meetingId, c.QueryParam("userId"))
+			return c.JSON(http.StatusForbidden, &Result{Result: false})
+		}
+
+		var (
+			contentType string
+			extension   string
+		)
+		format := c.QueryParam("format")
+		switch format {
+		case "", "md", "markdown":
+			contentType, extension = "text/markdown; charset=UTF-8", "md"
+		case "csv":
+			contentType, extension = "text/csv; charset=UTF-8", "csv"
+		case "pdf":
+			contentType, extension = "application/pdf", "pdf"
+		default:
+			return c.JSON(http.StatusBadRequest, &Result{Result: false})
+		}
+
+		report := buildMeetingReport(db, meeting, time.Now())
+		c.Response().Header().Set(echo.HeaderContentType, contentType)
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"meeting-%d-report.%s\"", meetingId, extension))
+		c.Response().WriteHeader(http.StatusOK)
+		switch extension {
+		case "csv":
+			return writeReportCsv(c.Response(), report)
+		case "pdf":
+			var markdown strings.Builder
+			renderReportMarkdown(&markdown, report)
+			return writeTextPdf(c.Response(), strings.Split(markdown.String(), "\n"))
+		default:
+			renderReportMarkdown(c.Response(), report)
+			return nil
+		}
+	}
+}
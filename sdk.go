@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SDKの生成 (go generate もしくは --generate-sdk で実行する)
+// apiOperations と wsMessages から辿れる構造体を，Goの client パッケージとTypeScriptの型に書き出す
+
+const sdkHeader = "Code generated by websocket --generate-sdk; DO NOT EDIT."
+
+// sdkTypes は apiOperations と wsMessages から辿れる構造体を名前順に返す
+func sdkTypes() []reflect.Type {
+	found := map[string]reflect.Type{}
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			walk(t.Elem())
+		case reflect.Struct:
+			if t == timeType || t.Name() == "" {
+				return
+			}
+			if _, ok := found[t.Name()]; ok {
+				return
+			}
+			found[t.Name()] = t
+			for i := 0; i < t.NumField(); i++ {
+				walk(t.Field(i).Type)
+			}
+		}
+	}
+	for _, op := range apiOperations {
+		for _, v := range []interface{}{op.Request, op.Response} {
+			if v != nil {
+				walk(reflect.TypeOf(v))
+			}
+		}
+	}
+	walk(reflect.TypeOf(APIError{}))
+	for _, m := range wsMessages {
+		walk(reflect.TypeOf(m.Payload))
+	}
+
+	types := make([]reflect.Type, 0, len(found))
+	for _, t := range found {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name() < types[j].Name() })
+	return types
+}
+
+// jsonField はencoding/jsonと同じ規則で項目名とomitemptyを返す (出力しない項目はnameが空)
+func jsonField(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return field.Name, false
+	}
+	if tag == "-" {
+		return "", false
+	}
+	parts := strings.SplitN(tag, ",", 2)
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	return name, len(parts) == 2 && strings.Contains(parts[1], "omitempty")
+}
+
+// exportedName は question_vote を QuestionVote のような名前にする
+func exportedName(s string) string {
+	name := ""
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == '_' || r == '/' || r == '.' || r == ':' }) {
+		name += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return name
+}
+
+func goTypeExpr(t reflect.Type) string {
+	switch t {
+	case timeType:
+		return "time.Time"
+	case rawMessageType:
+		return "json.RawMessage"
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + goTypeExpr(t.Elem())
+	case reflect.Slice:
+		return "[]" + goTypeExpr(t.Elem())
+	case reflect.Map:
+		return "map[" + goTypeExpr(t.Key()) + "]" + goTypeExpr(t.Elem())
+	case reflect.Struct:
+		return t.Name()
+	case reflect.Interface:
+		return "interface{}"
+	}
+	return t.Kind().String()
+}
+
+// goPathExpr は /meeting/:id/report を "/meeting/" + strconv.Itoa(id) + "/report" にする
+func goPathExpr(path string) (string, []string) {
+	expr := make([]string, 0, 4)
+	params := make([]string, 0, 2)
+	literal := ""
+	for _, segment := range strings.Split(path, "/")[1:] {
+		if !strings.HasPrefix(segment, ":") {
+			literal += "/" + segment
+			continue
+		}
+		name := segment[1:]
+		expr = append(expr, fmt.Sprintf("%q", literal+"/"))
+		literal = ""
+		if pathParamSchema(path, name)["type"] == "string" {
+			expr = append(expr, "url.PathEscape("+name+")")
+			params = append(params, name+" string")
+		} else {
+			expr = append(expr, "strconv.Itoa("+name+")")
+			params = append(params, name+" int")
+		}
+	}
+	if literal != "" || len(expr) == 0 {
+		expr = append(expr, fmt.Sprintf("%q", literal))
+	}
+	return strings.Join(expr, " + "), params
+}
+
+func generateGoSDK() ([]byte, error) {
+	var b bytes.Buffer
+
+	for _, t := range sdkTypes() {
+		fmt.Fprintf(&b, "type %s struct {\n", t.Name())
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if name, _ := jsonField(field); name == "" {
+				continue
+			}
+			fmt.Fprintf(&b, "\t%s %s `%s`\n", field.Name, goTypeExpr(field.Type), field.Tag)
+		}
+		b.WriteString("}\n\n")
+	}
+
+	for _, op := range apiOperations {
+		if op.Status == http.StatusSwitchingProtocols || op.Produces == "text/html" {
+			continue // WebSocketは Conn で扱い，トップページはAPIではない
+		}
+		pathExpr, params := goPathExpr(op.Path)
+		args := append([]string{"ctx context.Context"}, params...)
+		query := "nil"
+		if len(op.Query) != 0 {
+			args = append(args, "query url.Values")
+			query = "query"
+		}
+		name := exportedName(operationId(op))
+		fmt.Fprintf(&b, "// %s は %s %s (%s)\n", name, op.Method, op.Path, op.Summary)
+		switch {
+		case op.Upload:
+			args = append(args, "userId string", "fileName string", "file io.Reader")
+			responseType := reflect.TypeOf(op.Response).Name()
+			fmt.Fprintf(&b, "func (c *Client) %s(%s) (*%s, error) {\n", name, strings.Join(args, ", "), responseType)
+			fmt.Fprintf(&b, "\tresult := new(%s)\n\terr := c.upload(ctx, %s, userId, fileName, file, result)\n\treturn result, err\n}\n\n", responseType, pathExpr)
+		case op.Response != nil:
+			body := "nil"
+			if op.Request != nil {
+				args = append(args, "request "+reflect.TypeOf(op.Request).Name())
+				body = "request"
+			}
+			responseType := reflect.TypeOf(op.Response).Name()
+			fmt.Fprintf(&b, "func (c *Client) %s(%s) (*%s, error) {\n", name, strings.Join(args, ", "), responseType)
+			fmt.Fprintf(&b, "\tresult := new(%s)\n\terr := c.do(ctx, %q, %s, %s, %s, result)\n\treturn result, err\n}\n\n", responseType, op.Method, pathExpr, query, body)
+		case op.Produces != "":
+			fmt.Fprintf(&b, "func (c *Client) %s(%s) ([]byte, error) {\n", name, strings.Join(args, ", "))
+			fmt.Fprintf(&b, "\treturn c.doRaw(ctx, %q, %s, %s)\n}\n\n", op.Method, pathExpr, query)
+		default:
+			fmt.Fprintf(&b, "func (c *Client) %s(%s) error {\n", name, strings.Join(args, ", "))
+			fmt.Fprintf(&b, "\treturn c.do(ctx, %q, %s, %s, nil, nil)\n}\n\n", op.Method, pathExpr, query)
+		}
+	}
+
+	b.WriteString("// Handlers はサーバーから届くメッセージ毎のコールバック (nilのメッセージは無視する)\n")
+	b.WriteString("type Handlers struct {\n")
+	for _, m := range wsMessages {
+		if !m.FromClient {
+			fmt.Fprintf(&b, "\t%s func(%s) // %s\n", "On"+exportedName(m.MessageType), wsMessageName(m), m.Summary)
+		}
+	}
+	b.WriteString("\n\t// OnUnknown は上記以外のmessageTypeのメッセージ\n\tOnUnknown func(messageType string, message json.RawMessage)\n}\n\n")
+
+	b.WriteString("func (h *Handlers) dispatch(messageType string, raw json.RawMessage) error {\n\tswitch messageType {\n")
+	for _, m := range wsMessages {
+		if m.FromClient {
+			continue
+		}
+		handler := "On" + exportedName(m.MessageType)
+		fmt.Fprintf(&b, "\tcase %q:\n\t\tif h.%s == nil {\n\t\t\treturn nil\n\t\t}\n", m.MessageType, handler)
+		fmt.Fprintf(&b, "\t\tvar message %s\n\t\tif err := json.Unmarshal(raw, &message); err != nil {\n\t\t\treturn err\n\t\t}\n\t\th.%s(message)\n", wsMessageName(m), handler)
+	}
+	b.WriteString("\tdefault:\n\t\tif h.OnUnknown != nil {\n\t\t\th.OnUnknown(messageType, raw)\n\t\t}\n\t}\n\treturn nil\n}\n\n")
+
+	for _, m := range wsMessages {
+		if !m.FromClient {
+			continue
+		}
+		name := "Send" + exportedName(m.MessageType)
+		fmt.Fprintf(&b, "// %s は %s を送る (%s)\n", name, m.MessageType, m.Summary)
+		fmt.Fprintf(&b, "func (c *Conn) %s(message %s) error {\n\tmessage.MessageType = %q\n\treturn c.Send(message)\n}\n\n", name, wsMessageName(m), m.MessageType)
+	}
+
+	// time は構造体で使う場合のみ読み込む
+	imports := []string{"context", "encoding/json", "io", "net/url", "strconv"}
+	if strings.Contains(b.String(), "time.Time") {
+		imports = append(imports, "time")
+	}
+	var source bytes.Buffer
+	fmt.Fprintf(&source, "// %s\n\npackage client\n\nimport (\n", sdkHeader)
+	for _, path := range imports {
+		fmt.Fprintf(&source, "\t%q\n", path)
+	}
+	source.WriteString(")\n\n")
+	source.Write(b.Bytes())
+	return format.Source(source.Bytes())
+}
+
+func tsTypeExpr(t reflect.Type) string {
+	switch t {
+	case timeType:
+		return "string"
+	case rawMessageType:
+		return "unknown"
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return tsTypeExpr(t.Elem()) + " | null"
+	case reflect.Slice, reflect.Array:
+		elem := tsTypeExpr(t.Elem())
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case reflect.Map:
+		return "Record<" + tsTypeExpr(t.Key()) + ", " + tsTypeExpr(t.Elem()) + ">"
+	case reflect.Struct:
+		return t.Name()
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return "unknown"
+}
+
+func generateTypeScriptSDK() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// %s\n\n", sdkHeader)
+
+	for _, t := range sdkTypes() {
+		fmt.Fprintf(&b, "export interface %s {\n", t.Name())
+		for i := 0; i < t.NumField(); i++ {
+			name, omitempty := jsonField(t.Field(i))
+			if name == "" {
+				continue
+			}
+			optional := ""
+			if omitempty {
+				optional = "?"
+			}
+			fmt.Fprintf(&b, "  %s%s: %s;\n", name, optional, tsTypeExpr(t.Field(i).Type))
+		}
+		b.WriteString("}\n\n")
+	}
+
+	for _, direction := range []struct {
+		name       string
+		fromClient bool
+	}{{"ClientMessage", true}, {"ServerMessage", false}} {
+		fmt.Fprintf(&b, "export interface %sMap {\n", direction.name)
+		for _, m := range wsMessages {
+			if m.FromClient == direction.fromClient {
+				fmt.Fprintf(&b, "  %s: %s & { messageType: %q };\n", m.MessageType, wsMessageName(m), m.MessageType)
+			}
+		}
+		fmt.Fprintf(&b, "}\n\nexport type %s = %sMap[keyof %sMap];\n\n", direction.name, direction.name, direction.name)
+	}
+	return b.Bytes()
+}
+
+// generateSDK はdirの client/ と client-ts/ にSDKを書き出す
+func generateSDK(dir string) error {
+	goSource, err := generateGoSDK()
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "client", "zz_generated.go"), goSource, 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "client-ts", "index.ts"), generateTypeScriptSDK(), 0644)
+}
+
+// checkSDK はdirのSDKが現在の構造体から生成したものと一致するかを確認し，不一致を返す
+func checkSDK(dir string) []string {
+	goSource, err := generateGoSDK()
+	if err != nil {
+		return []string{"failed to generate the Go SDK: " + err.Error()}
+	}
+	problems := make([]string, 0)
+	for path, want := range map[string][]byte{
+		filepath.Join(dir, "client", "zz_generated.go"): goSource,
+		filepath.Join(dir, "client-ts", "index.ts"):     generateTypeScriptSDK(),
+	} {
+		got, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			problems = append(problems, "missing SDK (run go generate): "+path)
+			continue
+		}
+		if err != nil {
+			problems = append(problems, "failed to read the SDK: "+err.Error())
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			problems = append(problems, "outdated SDK (run go generate): "+path)
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
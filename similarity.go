@@ -0,0 +1,155 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 質問の重複の検出
+// 外部のサービスを使わず，正規化した文字列のn-gramのTF-IDFのコサイン類似度で比べる
+// 日本語は単語に区切らず文字のbigram，英語などは単語(とその連続)を特徴量にする
+
+// 英語の質問で類似度に影響させない語
+var similarityStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "is": true, "are": true, "was": true, "were": true,
+	"be": true, "to": true, "of": true, "in": true, "on": true, "for": true, "and": true,
+	"or": true, "it": true, "this": true, "that": true, "do": true, "does": true, "you": true,
+	"i": true, "we": true, "can": true, "please": true, "what": true, "how": true, "why": true,
+}
+
+// normalizeQuestion は全角・半角，大文字・小文字，ひらがな・カタカナの違いを無くす
+func normalizeQuestion(text string) string {
+	text = strings.ToLower(norm.NFKC.String(text))
+	return strings.Map(func(r rune) rune {
+		// カタカナはひらがなに寄せる
+		if r >= 'ァ' && r <= 'ヶ' {
+			return r - 'ァ' + 'ぁ'
+		}
+		return r
+	}, text)
+}
+
+// questionTerms は質問を特徴量(語)の出現回数に変換する
+func questionTerms(text string) map[string]float64 {
+	terms := make(map[string]float64)
+	var (
+		words []string
+		run   []rune
+	)
+	flushRun := func() {
+		// 日本語の連続は文字のbigram (1文字だけならその文字)
+		if len(run) == 1 {
+			terms[string(run)]++
+		}
+		for i := 0; i+1 < len(run); i++ {
+			terms[string(run[i:i+2])]++
+		}
+		run = run[:0]
+	}
+	flushWords := func() {
+		for i, word := range words {
+			terms[word]++
+			if i+1 < len(words) {
+				terms[word+" "+words[i+1]]++
+			}
+		}
+		words = words[:0]
+	}
+
+	for _, field := range strings.FieldsFunc(normalizeQuestion(text), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}) {
+		var word []rune
+		for _, r := range field {
+			if r < 0x80 {
+				if len(run) != 0 {
+					flushRun()
+				}
+				word = append(word, r)
+				continue
+			}
+			if len(word) != 0 {
+				if w := string(word); !similarityStopWords[w] {
+					words = append(words, w)
+				}
+				word = word[:0]
+			}
+			run = append(run, r)
+		}
+		if len(word) != 0 {
+			if w := string(word); !similarityStopWords[w] {
+				words = append(words, w)
+			}
+		}
+		flushRun()
+	}
+	flushWords()
+	return terms
+}
+
+// similarQuestion は類似した質問の候補
+type similarQuestion struct {
+	Question   Question
+	Similarity float64
+}
+
+// findSimilarQuestions はtargetと類似度がthreshold以上の質問を類似度の高い順に返す
+// IDFは比較対象の質問とtargetから求める
+func findSimilarQuestions(target Question, candidates []Question, threshold float64) []similarQuestion {
+	if len(candidates) == 0 {
+		return []similarQuestion{}
+	}
+	documents := make([]map[string]float64, len(candidates)+1)
+	documents[0] = questionTerms(target.QuestionBody)
+	for i, candidate := range candidates {
+		documents[i+1] = questionTerms(candidate.QuestionBody)
+	}
+
+	documentFrequency := make(map[string]int)
+	for _, terms := range documents {
+		for term := range terms {
+			documentFrequency[term]++
+		}
+	}
+	vectors := make([]map[string]float64, len(documents))
+	for i, terms := range documents {
+		vector := make(map[string]float64, len(terms))
+		for term, count := range terms {
+			// 全ての質問に出てくる語も0にならないよう平滑化する
+			idf := math.Log(float64(len(documents)+1)/float64(documentFrequency[term]+1)) + 1
+			vector[term] = (1 + math.Log(count)) * idf
+		}
+		vectors[i] = vector
+	}
+
+	similar := make([]similarQuestion, 0, 3)
+	for i, candidate := range candidates {
+		if candidate.QuestionId == target.QuestionId {
+			continue
+		}
+		if similarity := cosineSimilarity(vectors[0], vectors[i+1]); similarity >= threshold {
+			similar = append(similar, similarQuestion{Question: candidate, Similarity: similarity})
+		}
+	}
+	sort.SliceStable(similar, func(i, j int) bool { return similar[i].Similarity > similar[j].Similarity })
+	return similar
+}
+
+func cosineSimilarity(a map[string]float64, b map[string]float64) float64 {
+	var dot, normA, normB float64
+	for term, weight := range a {
+		dot += weight * b[term]
+		normA += weight * weight
+	}
+	for _, weight := range b {
+		normB += weight * weight
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
@@ -0,0 +1,553 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// REST APIとWebSocketのメッセージの仕様
+// 下の一覧と構造体から /openapi.json と /asyncapi.json を作る
+// 経路を追加した場合は apiOperations にも追加する (--check-spec で確認できる)
+// 経路が読むクエリパラメータ・本文とreadPumpが読むメッセージの項目は go test で確認する (spec_test.go)
+// 構造体や一覧を変更した場合は go generate でSDKを作り直す
+
+//go:generate go run . --generate-sdk .
+
+const specVersion = "1.0.0"
+
+// apiOperation はREST APIの1つの操作
+type apiOperation struct {
+	Method   string
+	Path     string // echoの形式 (/meeting/:id/report)
+	Summary  string
+	Query    []string    // クエリパラメータ
+	Request  interface{} // JSONの本文 (無ければnil)
+	Response interface{} // 成功時のJSONの本文 (JSON以外はnil)
+	Status   int         // 成功時のステータス (0は200)
+	Produces string      // JSON以外の応答のContent-Type
+	Upload   bool        // multipart/form-data で file と userId を送る
+}
+
+// wsMessage はWebSocketの1つのメッセージ
+type wsMessage struct {
+	MessageType string
+	Summary     string
+	Payload     interface{}
+	FromClient  bool // クライアントから送る (falseはサーバーから届く)
+}
+
+// クライアントから送るメッセージ (readPumpが読む項目)
+// omitemptyの項目は省略できる
+
+type QuestionMessage struct {
+	MessageType     string `json:"messageType"`
+	UserId          string `json:"userId"`
+	MeetingId       int    `json:"meetingId"`
+	QuestionBody    string `json:"questionBody"`
+	DocumentId      int    `json:"documentId"`
+	DocumentPage    int    `json:"documentPage,omitempty"` // 省略すると発表中のページ
+	QuestionTime    string `json:"questionTime"`
+	DocumentVersion int    `json:"documentVersion,omitempty"` // 省略すると現在のバージョン
+}
+
+type QuestionVoteMessage struct {
+	MessageType string `json:"messageType"`
+	QuestionId  int    `json:"questionId"`
+	IsVote      bool   `json:"isVote"`
+}
+
+type HandsUpMessage struct {
+	MessageType  string `json:"messageType"`
+	UserId       string `json:"userId"`
+	DocumentId   int    `json:"documentId"`
+	DocumentPage int    `json:"documentPage,omitempty"` // 省略すると発表中のページ (isUpがfalseの場合は使わない)
+	IsUp         bool   `json:"isUp"`
+}
+
+type ReactionMessage struct {
+	MessageType  string `json:"messageType"`
+	DocumentId   int    `json:"documentId"`
+	DocumentPage int    `json:"documentPage"`
+	IsReaction   bool   `json:"isReaction"`
+	Kind         string `json:"kind,omitempty"`  // confused (省略時), agree, too_fast, too_slow, applause, emoji (confused以外はuserIdを付けて接続した場合のみ)
+	Emoji        string `json:"emoji,omitempty"` // kindがemojiの場合
+}
+
+type QuestionReplyMessage struct {
+	MessageType   string `json:"messageType"`
+	QuestionId    int    `json:"questionId"`
+	UserId        string `json:"userId,omitempty"` // 接続のuserIdが無い場合
+	Body          string `json:"body"`
+	IsAnswer      bool   `json:"isAnswer,omitempty"`
+	ParentReplyId int    `json:"parentReplyId,omitempty"`
+}
+
+type QuestionModerateMessage struct {
+	MessageType  string `json:"messageType"`
+	QuestionId   int    `json:"questionId"`
+	Action       string `json:"action"`
+	QuestionBody string `json:"questionBody,omitempty"` // action が edit の場合
+	MergeInto    int    `json:"mergeInto,omitempty"`    // action が merge の場合
+}
+
+// QuestionMergeMessage は質問者がuserIdを付けて接続した場合のみ送れる
+type QuestionMergeMessage struct {
+	MessageType string `json:"messageType"`
+	QuestionId  int    `json:"questionId"`
+	MergeInto   int    `json:"mergeInto"`
+}
+
+// QuestionEditMessage は質問者かホストがuserIdを付けて接続した場合のみ送れる
+type QuestionEditMessage struct {
+	MessageType  string `json:"messageType"`
+	QuestionId   int    `json:"questionId"`
+	QuestionBody string `json:"questionBody"`
+}
+
+// QuestionWithdrawMessage は質問者かホストがuserIdを付けて接続した場合のみ送れる
+type QuestionWithdrawMessage struct {
+	MessageType string `json:"messageType"`
+	QuestionId  int    `json:"questionId"`
+}
+
+type HandsModerateMessage struct {
+	MessageType string `json:"messageType"`
+	DocumentId  int    `json:"documentId"`
+	UserId      string `json:"userId"`
+	Action      string `json:"action"`             // move, call, lower
+	Position    int    `json:"position,omitempty"` // action が move の場合 (1から挙手の数まで)
+}
+
+type MuteMessage struct {
+	MessageType string `json:"messageType"`
+	MeetingId   int    `json:"meetingId"`
+	UserId      string `json:"userId"`
+	IsMute      bool   `json:"isMute"`
+	Duration    int    `json:"duration,omitempty"` // seconds，省略すると設定の既定値
+}
+
+// PageChangeMessage は発表中の発表者がuserIdを付けて接続した場合のみ送れる
+type PageChangeMessage struct {
+	MessageType  string `json:"messageType"`
+	MeetingId    int    `json:"meetingId"`
+	DocumentId   int    `json:"documentId"`
+	DocumentPage int    `json:"documentPage"`
+}
+
+type FollowMessage struct {
+	MessageType string `json:"messageType"`
+	Follow      bool   `json:"follow"`
+}
+
+type FinishWordMessage struct {
+	MessageType    string `json:"messageType"`
+	MeetingId      int    `json:"meetingId"`
+	PresenterId    string `json:"presenterId"`
+	FinishType     string `json:"finishType"`               // present または question
+	QuestionUserId string `json:"questionUserId,omitempty"` // finishType が question の場合
+}
+
+var apiOperations = []apiOperation{
+	{Method: "GET", Path: "/", Summary: "トップページ", Produces: "text/html"},
+	{Method: "GET", Path: "/ws", Summary: "WebSocketの接続 (メッセージは /asyncapi.json)", Query: []string{"userId", "meetingId", "follow"}, Status: http.StatusSwitchingProtocols},
+	{Method: "GET", Path: "/openapi.json", Summary: "このOpenAPIの文書", Produces: "application/json"},
+	{Method: "GET", Path: "/asyncapi.json", Summary: "WebSocketのメッセージのAsyncAPIの文書", Produces: "application/json"},
+	{Method: "GET", Path: "/metrics", Summary: "Prometheusのメトリクス", Produces: "text/plain"},
+	{Method: "GET", Path: "/healthz", Summary: "プロセスの死活", Response: HealthResult{}},
+	{Method: "GET", Path: "/readyz", Summary: "DBとHubの応答 (応答しなければ503)", Response: HealthResult{}},
+
+	{Method: "POST", Path: "/user/signup", Summary: "利用者の登録", Request: UserSignupRequest{}, Response: Result{}},
+	{Method: "POST", Path: "/user/login", Summary: "ログイン (試行回数を超えると429)", Request: UserLoginRequest{}, Response: UserLoginResult{}},
+	{Method: "GET", Path: "/user/:id/inbox", Summary: "オフラインの間に届いた通知 (本人)", Query: []string{"userId", "unread"}, Response: InboxResult{}},
+	{Method: "POST", Path: "/user/:id/inbox/:messageId/read", Summary: "通知を既読にする (本人)", Query: []string{"userId"}, Response: Result{}},
+
+	{Method: "POST", Path: "/meeting/create", Summary: "会議の作成", Request: CreateMeetingRequest{}, Response: CreateMeetingResult{}},
+	{Method: "POST", Path: "/meeting/join", Summary: "会議への参加", Request: JoinMeetingRequest{}, Response: JoinMeetingResult{}},
+	{Method: "POST", Path: "/meeting/exit", Summary: "会議からの退出", Request: ExitMeetingRequest{}, Response: ExitMeetingResult{}},
+	{Method: "GET", Path: "/meeting/:id/page", Summary: "発表中のページ", Response: CurrentPageResult{}},
+	{Method: "GET", Path: "/meeting/:id/report", Summary: "議事録", Query: []string{"userId", "format"}, Produces: "text/markdown"},
+	{Method: "GET", Path: "/meeting/:id/unanswered", Summary: "未回答の質問 (発表者とホスト)", Query: []string{"userId"}, Response: UnansweredQuestionsResult{}},
+	{Method: "GET", Path: "/meeting/:id/moderation", Summary: "承認待ちの質問 (ホスト)", Query: []string{"userId"}, Response: ModerationQueueResult{}},
+	{Method: "POST", Path: "/meeting/:id/moderation", Summary: "質問の事前承認の切り替え (ホスト)", Request: PreModerationRequest{}, Response: Result{}},
+	{Method: "GET", Path: "/meeting/:id/duplicates", Summary: "重複の可能性がある質問 (ホスト)", Query: []string{"userId"}, Response: DuplicateQuestionsResult{}},
+	{Method: "GET", Path: "/meetings/:id/questions", Summary: "公開中の質問の一覧", Query: []string{"userId", "presenterId", "documentId", "page", "answered", "kind", "askerId", "sort", "limit", "cursor"}, Response: QuestionListResult{}},
+
+	{Method: "POST", Path: "/questions", Summary: "会議の質問 (旧形式)", Request: QuestionsGetRequest{}, Response: QuestionsGetResult{}},
+	{Method: "POST", Path: "/question/:id/moderate", Summary: "質問の承認・却下・編集・統合 (ホスト)", Request: QuestionModerateRequest{}, Response: Result{}},
+	{Method: "POST", Path: "/question/:id/merge", Summary: "自分の質問を似た質問に統合する (パスワードで本人確認)", Request: QuestionMergeRequest{}, Response: Result{}},
+	{Method: "POST", Path: "/question/:id/edit", Summary: "質問の本文の変更", Request: QuestionEditRequest{}, Response: Result{}},
+	{Method: "POST", Path: "/question/:id/withdraw", Summary: "質問の取り下げ", Request: QuestionWithdrawRequest{}, Response: Result{}},
+	{Method: "GET", Path: "/question/:id/history", Summary: "質問の変更履歴 (質問者とホスト)", Query: []string{"userId"}, Response: QuestionHistoryResult{}},
+	{Method: "POST", Path: "/question/:id/followup", Summary: "会議後の回答", Request: FollowUpAnswerRequest{}, Response: FollowUpAnswerResponse{}},
+
+	{Method: "POST", Path: "/document/register", Summary: "資料URLと原稿の登録", Request: DocumentRegisterRequest{}, Response: DocumentRegisterResult{}},
+	{Method: "POST", Path: "/document/get", Summary: "資料URLと原稿", Request: DocumentGetRequest{}, Response: DocumentGetResult{}},
+	{Method: "GET", Path: "/document/:id/hands", Summary: "挙手の順番", Query: []string{"userId"}, Response: HandsResult{}},
+	{Method: "POST", Path: "/document/:id/hands/moderate", Summary: "挙手の並べ替え・指名・取り下げ (ホスト)", Request: HandsModerateRequest{}, Response: Result{}},
+	{Method: "GET", Path: "/document/:id/reactions", Summary: "ページ毎・種類毎のリアクションの数", Query: []string{"userId", "version", "window"}, Response: DocumentReactionsResult{}},
+	{Method: "POST", Path: "/document/:id/upload", Summary: "PDFのアップロード (発表者)", Upload: true, Response: DocumentUploadResult{}},
+	{Method: "GET", Path: "/document/:id/file", Summary: "アップロードされたPDF", Query: []string{"userId"}, Produces: "application/pdf"},
+	{Method: "GET", Path: "/document/:id/pages", Summary: "ページの一覧", Query: []string{"userId"}, Response: DocumentPagesResult{}},
+	{Method: "GET", Path: "/document/:id/pages/:page/layout", Summary: "ページのレイアウトの簡易図 (文字と図形の配置．ページの内容の描画ではない)", Query: []string{"userId"}, Produces: "image/png"},
+	{Method: "GET", Path: "/document/:id/versions", Summary: "資料のバージョンの一覧", Query: []string{"userId"}, Response: DocumentVersionsResult{}},
+	{Method: "GET", Path: "/document/:id/versions/:version", Summary: "資料のバージョン", Query: []string{"userId"}, Response: DocumentVersionGetResult{}},
+	{Method: "POST", Path: "/document/:id/versions/:version/restore", Summary: "資料のバージョンを戻す (発表者)", Request: DocumentRestoreRequest{}, Response: DocumentRestoreResult{}},
+	{Method: "GET", Path: "/document/:id/analytics", Summary: "ページ毎の集計", Query: []string{"userId", "version", "format"}, Response: DocumentAnalyticsResult{}},
+
+	{Method: "POST", Path: "/api/v2/users", Summary: "利用者の登録", Request: CreateUserRequest{}, Response: UserResource{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/api/v2/sessions", Summary: "ログイン", Request: UserLoginRequest{}, Response: UserResource{}},
+	{Method: "POST", Path: "/api/v2/meetings", Summary: "会議の作成", Request: CreateMeetingRequest{}, Response: MeetingResource{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/api/v2/meetings/:id", Summary: "会議", Response: MeetingResource{}},
+	{Method: "PUT", Path: "/api/v2/meetings/:id/participants/:userId", Summary: "会議への参加", Response: MeetingResource{}},
+	{Method: "DELETE", Path: "/api/v2/meetings/:id/participants/:userId", Summary: "会議からの退出", Query: []string{"documentId"}, Status: http.StatusNoContent},
+	{Method: "GET", Path: "/api/v2/meetings/:id/questions", Summary: "公開中の質問の一覧", Query: []string{"userId", "presenterId", "documentId", "page", "answered", "kind", "askerId", "sort", "limit", "cursor"}, Response: QuestionPageResource{}},
+	{Method: "GET", Path: "/api/v2/meetings/:id/page", Summary: "発表中のページ", Response: CurrentPageResult{}},
+	{Method: "GET", Path: "/api/v2/documents/:id", Summary: "資料", Response: DocumentResource{}},
+	{Method: "PATCH", Path: "/api/v2/documents/:id", Summary: "資料URLと原稿の変更", Request: UpdateDocumentRequest{}, Response: DocumentResource{}},
+	{Method: "GET", Path: "/api/v2/questions/:id", Summary: "質問", Query: []string{"userId"}, Response: QuestionResult{}},
+	{Method: "PATCH", Path: "/api/v2/questions/:id", Summary: "質問の本文の変更", Request: QuestionEditRequest{}, Response: QuestionResult{}},
+	{Method: "DELETE", Path: "/api/v2/questions/:id", Summary: "質問の取り下げ", Query: []string{"userId"}, Status: http.StatusNoContent},
+}
+
+var wsMessages = []wsMessage{
+	{MessageType: "message", Summary: "チャット", Payload: Message{}, FromClient: true},
+	{MessageType: "question", Summary: "質問の投稿", Payload: QuestionMessage{}, FromClient: true},
+	{MessageType: "question_vote", Summary: "質問への投票", Payload: QuestionVoteMessage{}, FromClient: true},
+	{MessageType: "handsup", Summary: "挙手", Payload: HandsUpMessage{}, FromClient: true},
+	{MessageType: "reaction", Summary: "ページへのリアクション", Payload: ReactionMessage{}, FromClient: true},
+	{MessageType: QuestionReplyMsgType, Summary: "質問への返信", Payload: QuestionReplyMessage{}, FromClient: true},
+	{MessageType: QuestionModerateMsgType, Summary: "質問の承認・却下・編集・統合 (ホスト)", Payload: QuestionModerateMessage{}, FromClient: true},
+	{MessageType: QuestionMergeMsgType, Summary: "自分の質問を似た質問に統合する", Payload: QuestionMergeMessage{}, FromClient: true},
+	{MessageType: QuestionEditMsgType, Summary: "質問の本文の変更", Payload: QuestionEditMessage{}, FromClient: true},
+	{MessageType: QuestionWithdrawMsgType, Summary: "質問の取り下げ", Payload: QuestionWithdrawMessage{}, FromClient: true},
+	{MessageType: HandsModerateMsgType, Summary: "挙手の並べ替え・指名・取り下げ (ホスト)", Payload: HandsModerateMessage{}, FromClient: true},
+	{MessageType: "mute", Summary: "参加者のミュート (ホスト)", Payload: MuteMessage{}, FromClient: true},
+	{MessageType: PageChangeMsgType, Summary: "ページの切り替え (発表者)", Payload: PageChangeMessage{}, FromClient: true},
+	{MessageType: "follow", Summary: "発表者のページへの追従の切り替え", Payload: FollowMessage{}, FromClient: true},
+	{MessageType: "finishword", Summary: "発表・質問の終了", Payload: FinishWordMessage{}, FromClient: true},
+
+	{MessageType: "message", Summary: "チャット", Payload: Message{}},
+	{MessageType: "question", Summary: "公開された質問", Payload: QuestionResult{}},
+	{MessageType: "question_vote", Summary: "質問の投票数", Payload: QuestionVoteResult{}},
+	{MessageType: "handsup", Summary: "挙手", Payload: HandsUpResult{}},
+	{MessageType: HandsQueueMsgType, Summary: "発表者の枠への挙手の順番", Payload: HandsQueueResult{}},
+	{MessageType: "reaction", Summary: "ページのリアクション数", Payload: ReactionResult{}},
+	{MessageType: PaceSuggestionMsgType, Summary: "話すペースの提案 (発表者)", Payload: PaceSuggestionResult{}},
+	{MessageType: NotificationMsgType, Summary: "分かりにくいページ・残り時間・溜まった質問の通知 (発表者とホスト)", Payload: NotificationResult{}},
+	{MessageType: QuestionReplyMsgType, Summary: "質問への返信", Payload: QuestionReplyResult{}},
+	{MessageType: "mute", Summary: "ミュートの変更", Payload: MuteResult{}},
+	{MessageType: PageChangeMsgType, Summary: "ページの切り替え", Payload: PageChangeResult{}},
+	{MessageType: "document_update", Summary: "資料の更新", Payload: DocumentUpdateResult{}},
+	{MessageType: ModeratorMsgType, Summary: "司会のメッセージ", Payload: ModeratorMsg{}},
+	{MessageType: QuestionModerationMsgType, Summary: "質問の承認待ち・承認・却下など (ホストと質問者)", Payload: QuestionModerationResult{}},
+	{MessageType: QuestionUpdateMsgType, Summary: "質問の変更・取り下げ・統合", Payload: QuestionUpdateResult{}},
+	{MessageType: QuestionDuplicateMsgType, Summary: "投稿した質問と似た質問 (質問者)", Payload: QuestionDuplicateResult{}},
+	{MessageType: FollowUpAnswerMsgType, Summary: "会議後の回答 (質問者)", Payload: FollowUpAnswerResult{}},
+	{MessageType: ServerShutdownMsgType, Summary: "サーバーの停止", Payload: ServerShutdownResult{}},
+	{MessageType: ErrorMsgType, Summary: "送ったメッセージのエラー (送った接続のみ)", Payload: ErrorResult{}},
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaBuilder はGoの型からJSON Schemaを作り，構造体はcomponentsに登録して参照する
+type schemaBuilder struct {
+	schemas map[string]interface{}
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{schemas: map[string]interface{}{}}
+}
+
+func (b *schemaBuilder) ref(v interface{}) map[string]interface{} {
+	return b.schemaOf(reflect.TypeOf(v))
+}
+
+func (b *schemaBuilder) schemaOf(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := b.schemaOf(t.Elem())
+		if _, ok := schema["$ref"]; ok {
+			return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		if _, ok := b.schemas[t.Name()]; !ok {
+			b.schemas[t.Name()] = nil // 再帰する型のために先に登録する
+			b.schemas[t.Name()] = b.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return map[string]interface{}{}
+}
+
+// structSchema はencoding/jsonと同じ規則で項目名を決める
+// omitemptyでない項目は常に出力されるためrequiredとする
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, options := field.Name, ""
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			parts := strings.SplitN(tag, ",", 2)
+			if parts[0] != "" {
+				name = parts[0]
+			}
+			if len(parts) == 2 {
+				options = parts[1]
+			}
+		}
+		properties[name] = b.schemaOf(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) != 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// openAPIPath はechoの経路をOpenAPIの形式にし，パスパラメータを返す
+func openAPIPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	params := make([]string, 0, 2)
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			params = append(params, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// pathParamSchema はパスパラメータの型を返す (利用者IDのみ文字列で，それ以外はID・ページ番号)
+func pathParamSchema(path string, name string) map[string]interface{} {
+	if name == "userId" || (name == "id" && strings.HasPrefix(path, "/user/")) {
+		return map[string]interface{}{"type": "string"}
+	}
+	return map[string]interface{}{"type": "integer"}
+}
+
+func openAPIDocument() map[string]interface{} {
+	b := newSchemaBuilder()
+	paths := map[string]interface{}{}
+	for _, op := range apiOperations {
+		path, pathParams := openAPIPath(op.Path)
+		parameters := make([]interface{}, 0, len(pathParams)+len(op.Query))
+		for _, name := range pathParams {
+			parameters = append(parameters, map[string]interface{}{"name": name, "in": "path", "required": true, "schema": pathParamSchema(op.Path, name)})
+		}
+		for _, name := range op.Query {
+			parameters = append(parameters, map[string]interface{}{"name": name, "in": "query", "schema": map[string]interface{}{"type": "string"}})
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]interface{}{"description": http.StatusText(status)}
+		switch {
+		case op.Response != nil:
+			success["content"] = map[string]interface{}{"application/json": map[string]interface{}{"schema": b.ref(op.Response)}}
+		case op.Produces != "":
+			success["content"] = map[string]interface{}{op.Produces: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}}
+		}
+		responses := map[string]interface{}{fmt.Sprint(status): success}
+		// v2は APIError，v1は成功時と同じ形で result が false
+		if strings.HasPrefix(op.Path, "/api/v2/") {
+			responses["default"] = map[string]interface{}{
+				"description": "error",
+				"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": b.ref(APIError{})}},
+			}
+		}
+
+		operation := map[string]interface{}{
+			"operationId": operationId(op),
+			"summary":     op.Summary,
+			"parameters":  parameters,
+			"responses":   responses,
+		}
+		switch {
+		case op.Request != nil:
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": b.ref(op.Request)}},
+			}
+		case op.Upload:
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{"multipart/form-data": map[string]interface{}{"schema": map[string]interface{}{
+					"type":       "object",
+					"required":   []string{"userId", "file"},
+					"properties": map[string]interface{}{"userId": map[string]interface{}{"type": "string"}, "file": map[string]interface{}{"type": "string", "format": "binary"}},
+				}}},
+			}
+		}
+
+		if _, ok := paths[path]; !ok {
+			paths[path] = map[string]interface{}{}
+		}
+		paths[path].(map[string]interface{})[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi":    "3.0.3",
+		"info":       map[string]interface{}{"title": "websocket REST API", "version": specVersion},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": b.schemas},
+	}
+}
+
+// operationId は /meeting/:id/report を getMeetingIdReport のような名前にする
+func operationId(op apiOperation) string {
+	name := strings.ToLower(op.Method)
+	for _, segment := range strings.FieldsFunc(op.Path, func(r rune) bool { return r == '/' || r == '.' || r == '_' }) {
+		segment = strings.TrimPrefix(segment, ":")
+		name += strings.ToUpper(segment[:1]) + segment[1:]
+	}
+	return name
+}
+
+// wsMessageName は components/messages での名前
+// 同じmessageTypeでも送る向きで構造体が異なるため構造体名を使う (チャットは両方向で同じ構造体)
+func wsMessageName(m wsMessage) string {
+	return reflect.TypeOf(m.Payload).Name()
+}
+
+func asyncAPIDocument() map[string]interface{} {
+	b := newSchemaBuilder()
+	messages := map[string]interface{}{}
+	publish := make([]interface{}, 0, len(wsMessages))
+	subscribe := make([]interface{}, 0, len(wsMessages))
+	for _, m := range wsMessages {
+		name := wsMessageName(m)
+		messages[name] = map[string]interface{}{
+			"name":    m.MessageType,
+			"summary": m.Summary,
+			"payload": map[string]interface{}{"allOf": []interface{}{
+				b.ref(m.Payload),
+				map[string]interface{}{"properties": map[string]interface{}{"messageType": map[string]interface{}{"type": "string", "enum": []string{m.MessageType}}}},
+			}},
+		}
+		ref := map[string]interface{}{"$ref": "#/components/messages/" + name}
+		if m.FromClient {
+			publish = append(publish, ref)
+		} else {
+			subscribe = append(subscribe, ref)
+		}
+	}
+
+	return map[string]interface{}{
+		"asyncapi": "2.6.0",
+		"info":     map[string]interface{}{"title": "websocket WebSocket API", "version": specVersion},
+		"channels": map[string]interface{}{
+			"/ws": map[string]interface{}{
+				"description": "?userId=&meetingId=&follow=false で接続する",
+				"publish":     map[string]interface{}{"summary": "クライアントから送るメッセージ", "message": map[string]interface{}{"oneOf": publish}},
+				"subscribe":   map[string]interface{}{"summary": "サーバーから届くメッセージ", "message": map[string]interface{}{"oneOf": subscribe}},
+			},
+		},
+		"components": map[string]interface{}{"messages": messages, "schemas": b.schemas},
+	}
+}
+
+// checkSpec は登録された経路と apiOperations，メッセージの構造体が一致するかを確認し，不一致を返す
+func checkSpec(e *echo.Echo) []string {
+	problems := make([]string, 0)
+	documented := map[string]bool{}
+	for _, op := range apiOperations {
+		key := op.Method + " " + op.Path
+		if documented[key] {
+			problems = append(problems, "duplicated operation: "+key)
+		}
+		documented[key] = true
+		for _, v := range []interface{}{op.Request, op.Response} {
+			if v != nil && reflect.TypeOf(v).Kind() != reflect.Struct {
+				problems = append(problems, fmt.Sprintf("%s: %T is not a struct", key, v))
+			}
+		}
+	}
+
+	routed := map[string]bool{}
+	for _, route := range e.Routes() {
+		// /api/v2 と /api/v2/* は存在しない経路のエラーを返すためのもの
+		if route.Path == "/api/v2" || strings.HasSuffix(route.Path, "/*") {
+			continue
+		}
+		key := route.Method + " " + route.Path
+		routed[key] = true
+		if !documented[key] {
+			problems = append(problems, "undocumented route: "+key)
+		}
+	}
+	for key := range documented {
+		if !routed[key] {
+			problems = append(problems, "documented but not routed: "+key)
+		}
+	}
+
+	names := map[string]bool{}
+	for _, m := range wsMessages {
+		name := wsMessageName(m)
+		key := fmt.Sprintf("%s/%t", name, m.FromClient)
+		if names[key] {
+			problems = append(problems, "duplicated message: "+name)
+		}
+		names[key] = true
+		t := reflect.TypeOf(m.Payload)
+		if field, ok := t.FieldByName("MessageType"); !ok || field.Tag.Get("json") != "messageType" {
+			problems = append(problems, "message without messageType: "+name)
+		}
+	}
+
+	sort.Strings(problems)
+	return problems
+}
+
+func openAPISpec(c echo.Context) error {
+	return c.JSON(http.StatusOK, openAPIDocument())
+}
+
+func asyncAPISpec(c echo.Context) error {
+	return c.JSON(http.StatusOK, asyncAPIDocument())
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+)
+
+// TestCheckSpec は登録された経路が apiOperations と一致するかを確認する (--check-spec と同じ)
+func TestCheckSpec(t *testing.T) {
+	e := echo.New()
+	initRouting(e, newHub(defaultConfig()), nil, nil)
+	for _, problem := range checkSpec(e) {
+		t.Error(problem)
+	}
+}
+
+// source はパッケージのソースの関数と文字列の定数
+type source struct {
+	funcs   map[string]*ast.FuncDecl // 関数 (メソッドは名前のみ)
+	methods map[string]*ast.FuncDecl
+	consts  map[string]string
+}
+
+func parseSource(t *testing.T) *source {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &source{funcs: map[string]*ast.FuncDecl{}, methods: map[string]*ast.FuncDecl{}, consts: map[string]string{}}
+	for _, file := range pkgs["main"].Files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					src.funcs[decl.Name.Name] = decl
+				} else {
+					src.methods[decl.Name.Name] = decl
+				}
+			case *ast.GenDecl:
+				if decl.Tok != token.CONST {
+					continue
+				}
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.ValueSpec)
+					for i, name := range spec.Names {
+						if i < len(spec.Values) {
+							if value, ok := stringLit(spec.Values[i]); ok {
+								src.consts[name.Name] = value
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return src
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	value, err := strconv.Unquote(lit.Value)
+	return value, err == nil
+}
+
+// jsonObjParams は map[string]interface{} の引数の名前を返す
+func jsonObjParams(decl *ast.FuncDecl) map[string]bool {
+	params := map[string]bool{}
+	for _, field := range decl.Type.Params.List {
+		if types.ExprString(field.Type) == "map[string]interface{}" {
+			for _, name := range field.Names {
+				params[name.Name] = true
+			}
+		}
+	}
+	return params
+}
+
+// jsonKeys はnodeでJSONのオブジェクトから読む項目の名前を集める
+// map[string]interface{} を受け取るメソッドを呼んでいればその中も調べる
+func (src *source) jsonKeys(node ast.Node, params map[string]bool, keys map[string]bool) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IndexExpr:
+			key, ok := stringLit(n.Index)
+			if !ok {
+				return true
+			}
+			switch x := n.X.(type) {
+			case *ast.TypeAssertExpr:
+				if types.ExprString(x.Type) == "map[string]interface{}" {
+					keys[key] = true
+				}
+			case *ast.Ident:
+				if params[x.Name] {
+					keys[key] = true
+				}
+			}
+		case *ast.CallExpr:
+			if sel, ok := n.Fun.(*ast.SelectorExpr); ok {
+				if decl, ok := src.methods[sel.Sel.Name]; ok {
+					if inner := jsonObjParams(decl); len(inner) > 0 {
+						src.jsonKeys(decl.Body, inner, keys)
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+// TestReadPumpMessages はreadPumpが読む項目とクライアントから送るメッセージの構造体の項目が一致するかを確認する
+func TestReadPumpMessages(t *testing.T) {
+	src := parseSource(t)
+	readPump, ok := src.methods["readPump"]
+	if !ok {
+		t.Fatal("readPump not found")
+	}
+
+	read := map[string]map[string]bool{}
+	ast.Inspect(readPump.Body, func(n ast.Node) bool {
+		sw, ok := n.(*ast.SwitchStmt)
+		if !ok || types.ExprString(sw.Tag) != "message_type" {
+			return true
+		}
+		for _, stmt := range sw.Body.List {
+			clause := stmt.(*ast.CaseClause)
+			for _, expr := range clause.List {
+				messageType, ok := stringLit(expr)
+				if !ok {
+					messageType = src.consts[types.ExprString(expr)]
+				}
+				keys := map[string]bool{}
+				for _, body := range clause.Body {
+					src.jsonKeys(body, nil, keys)
+				}
+				read[messageType] = keys
+			}
+		}
+		return false
+	})
+
+	for _, m := range wsMessages {
+		if !m.FromClient {
+			continue
+		}
+		keys, ok := read[m.MessageType]
+		if !ok {
+			t.Errorf("%s: not handled by readPump", m.MessageType)
+			continue
+		}
+		fields := map[string]bool{}
+		payload := reflect.TypeOf(m.Payload)
+		for i := 0; i < payload.NumField(); i++ {
+			name := strings.Split(payload.Field(i).Tag.Get("json"), ",")[0]
+			if name != "" && name != "-" && name != "messageType" {
+				fields[name] = true
+			}
+		}
+		for _, key := range sortedKeys(keys) {
+			if !fields[key] {
+				t.Errorf("%s: readPump reads %q which is not in %s", m.MessageType, key, payload.Name())
+			}
+		}
+		for _, field := range sortedKeys(fields) {
+			if !keys[field] {
+				t.Errorf("%s: %s.%s is not read by readPump", m.MessageType, payload.Name(), field)
+			}
+		}
+	}
+}
+
+// routeReads は経路の処理が読むクエリパラメータと本文の構造体
+type routeReads struct {
+	query    map[string]bool
+	requests map[string]bool
+}
+
+// handlerReads はnodeと，そこから呼ぶパッケージの関数が読むクエリパラメータと new(...) する構造体を集める
+func (src *source) handlerReads(node ast.Node, reads routeReads, visited map[string]bool) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		switch fun := call.Fun.(type) {
+		case *ast.SelectorExpr:
+			// c.QueryParam("...") と r.URL.Query().Get("...")
+			if len(call.Args) == 1 {
+				if key, ok := stringLit(call.Args[0]); ok {
+					if fun.Sel.Name == "QueryParam" || (fun.Sel.Name == "Get" && strings.HasSuffix(types.ExprString(fun.X), ".Query()")) {
+						reads.query[key] = true
+					}
+				}
+			}
+		case *ast.Ident:
+			if fun.Name == "new" && len(call.Args) == 1 {
+				reads.requests[types.ExprString(call.Args[0])] = true
+			}
+			if decl, ok := src.funcs[fun.Name]; ok && !visited[fun.Name] {
+				visited[fun.Name] = true
+				src.handlerReads(decl.Body, reads, visited)
+			}
+		}
+		return true
+	})
+}
+
+// TestRouteQueries は経路の処理が読むクエリパラメータと本文が apiOperations と一致するかを確認する
+func TestRouteQueries(t *testing.T) {
+	src := parseSource(t)
+
+	// echo.Group を受け取る関数は，渡された Group の接頭辞を経路に付ける
+	prefixes := map[string]string{"initRouting": ""}
+	for _, decl := range src.funcs {
+		ast.Inspect(decl.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			fun, ok := call.Fun.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			for _, arg := range call.Args {
+				if group, ok := arg.(*ast.CallExpr); ok {
+					if sel, ok := group.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Group" && len(group.Args) > 0 {
+						if prefix, ok := stringLit(group.Args[0]); ok {
+							prefixes[fun.Name] = prefix
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	routes := map[string]routeReads{}
+	for name, prefix := range prefixes {
+		decl, ok := src.funcs[name]
+		if !ok {
+			t.Fatalf("%s not found", name)
+		}
+		ast.Inspect(decl.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) < 2 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			switch sel.Sel.Name {
+			case "GET", "POST", "PUT", "PATCH", "DELETE":
+			default:
+				return true
+			}
+			path, ok := stringLit(call.Args[0])
+			if !ok {
+				return true
+			}
+			reads := routeReads{query: map[string]bool{}, requests: map[string]bool{}}
+			src.handlerReads(call.Args[1], reads, map[string]bool{})
+			routes[sel.Sel.Name+" "+prefix+path] = reads
+			return true
+		})
+	}
+
+	for _, op := range apiOperations {
+		key := op.Method + " " + op.Path
+		reads, ok := routes[key]
+		if !ok {
+			t.Errorf("%s: route not found in the source", key)
+			continue
+		}
+		documented := map[string]bool{}
+		for _, name := range op.Query {
+			documented[name] = true
+			if !reads.query[name] {
+				t.Errorf("%s: query %q is documented but not read", key, name)
+			}
+		}
+		for _, name := range sortedKeys(reads.query) {
+			if !documented[name] {
+				t.Errorf("%s: query %q is read but not documented", key, name)
+			}
+		}
+		if op.Request != nil {
+			if name := reflect.TypeOf(op.Request).Name(); !reads.requests[name] {
+				t.Errorf("%s: request %s is documented but not bound", key, name)
+			}
+		}
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// errBlobNotFound はキーに対応するファイルが無いことを表す
+var errBlobNotFound = errors.New("blob not found")
+
+// BlobStore はアップロードされた資料ファイルの保存先
+type BlobStore interface {
+	// Put はrの内容をkeyに保存する
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+
+	// Get はkeyの内容を返す (Range要求に応えられるようSeekできる)
+	Get(ctx context.Context, key string) (io.ReadSeekCloser, time.Time, error)
+
+	// Delete はkeyを削除する
+	Delete(ctx context.Context, key string) error
+}
+
+func newBlobStore(config StorageConfig) (BlobStore, error) {
+	switch config.Driver {
+	case "local":
+		return newLocalStore(config.LocalDir)
+	case "s3":
+		return newS3Store(config.S3)
+	default:
+		return nil, fmt.Errorf("未知のストレージです: %q", config.Driver)
+	}
+}
+
+// localStore はローカルのディレクトリに保存する (開発用のデフォルト)
+type localStore struct {
+	dir string
+}
+
+func newLocalStore(dir string) (*localStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &localStore{dir: dir}, nil
+}
+
+// path はkeyをdir配下のパスに変換する (dirの外を指すkeyは拒否する)
+func (s *localStore) path(key string) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, filepath.Clean(s.dir)+string(filepath.Separator)) {
+		return "", fmt.Errorf("不正なキーです: %q", key)
+	}
+	return path, nil
+}
+
+func (s *localStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// 書き込み途中のファイルが読まれないよう，一時ファイルに書いてから置き換える
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *localStore) Get(ctx context.Context, key string) (io.ReadSeekCloser, time.Time, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, errBlobNotFound
+	} else if err != nil {
+		return nil, time.Time{}, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, time.Time{}, err
+	}
+	return f, info.ModTime(), nil
+}
+
+func (s *localStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// s3Store はS3互換のオブジェクトストレージ(MinIOなど)に保存する
+type s3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+func newS3Store(config S3Config) (*s3Store, error) {
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(config.AccessKey, config.SecretKey, ""),
+		Secure: config.UseSSL,
+		Region: config.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	exists, err := client.BucketExists(ctx, config.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, config.Bucket, minio.MakeBucketOptions{Region: config.Region}); err != nil {
+			return nil, err
+		}
+	}
+	return &s3Store{client: client, bucket: config.Bucket}, nil
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) (io.ReadSeekCloser, time.Time, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	info, err := object.Stat()
+	if err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, time.Time{}, errBlobNotFound
+		}
+		return nil, time.Time{}, err
+	}
+	return object, info.LastModified, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
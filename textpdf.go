@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// 議事録などのテキストを書き出すための簡易的なPDF
+// 日本語を表示するため，埋め込み不要なAdobe-Japan1の標準フォント(平成角ゴシック)を使う
+const (
+	textPdfPageWidth  = 595.0 // A4 (points)
+	textPdfPageHeight = 842.0
+	textPdfMargin     = 50.0
+	textPdfFontSize   = 10.0
+	textPdfLeading    = 1.5 // 行送り (フォントサイズに対する倍率)
+)
+
+// textPdfLine はPDFの1行
+type textPdfLine struct {
+	text     string
+	fontSize float64
+	indent   float64
+}
+
+// markdownPdfLines はMarkdownの見出しと箇条書きを簡易的に解釈してPDFの行に変換する
+func markdownPdfLines(lines []string) []textPdfLine {
+	result := make([]textPdfLine, 0, len(lines))
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "# "):
+			result = append(result, textPdfLine{text: strings.TrimPrefix(line, "# "), fontSize: 16})
+		case strings.HasPrefix(line, "## "):
+			result = append(result, textPdfLine{text: strings.TrimPrefix(line, "## "), fontSize: 13})
+		case strings.HasPrefix(line, "### "):
+			result = append(result, textPdfLine{text: strings.TrimPrefix(line, "### "), fontSize: 11})
+		case strings.HasPrefix(line, "  - "):
+			result = append(result, textPdfLine{text: "・" + strings.TrimPrefix(line, "  - "), fontSize: textPdfFontSize, indent: 2 * textPdfFontSize})
+		case strings.HasPrefix(line, "- "):
+			result = append(result, textPdfLine{text: "・" + strings.TrimPrefix(line, "- "), fontSize: textPdfFontSize, indent: textPdfFontSize})
+		default:
+			result = append(result, textPdfLine{text: line, fontSize: textPdfFontSize})
+		}
+	}
+	return result
+}
+
+// textPdfWidth は文字列の幅をemで返す (ASCIIは半角，それ以外は全角とみなす)
+func textPdfWidth(r rune) float64 {
+	if r < 0x80 {
+		return 0.5
+	}
+	return 1
+}
+
+// wrapTextPdfLine は行を幅に収まるように折り返す
+func wrapTextPdfLine(line textPdfLine) []textPdfLine {
+	maxWidth := (textPdfPageWidth - 2*textPdfMargin - line.indent) / line.fontSize
+	wrapped := make([]textPdfLine, 0, 1)
+	var (
+		current strings.Builder
+		width   float64
+	)
+	for _, r := range line.text {
+		w := textPdfWidth(r)
+		if width+w > maxWidth && current.Len() != 0 {
+			wrapped = append(wrapped, textPdfLine{text: current.String(), fontSize: line.fontSize, indent: line.indent})
+			current.Reset()
+			width = 0
+		}
+		current.WriteRune(r)
+		width += w
+	}
+	return append(wrapped, textPdfLine{text: current.String(), fontSize: line.fontSize, indent: line.indent})
+}
+
+// textPdfString はUniJIS-UCS2-H用にUTF-16BEの16進文字列にする
+// BMP外の文字は表示できないため'?'にする
+func textPdfString(text string) string {
+	var buf strings.Builder
+	buf.WriteString("<")
+	for _, r := range text {
+		if r > 0xffff || r == utf8.RuneError {
+			r = '?'
+		}
+		fmt.Fprintf(&buf, "%04X", r)
+	}
+	buf.WriteString(">")
+	return buf.String()
+}
+
+// writeTextPdf はMarkdownの行をPDFに書き出す
+func writeTextPdf(w io.Writer, markdown []string) error {
+	lines := make([]textPdfLine, 0, len(markdown))
+	for _, line := range markdownPdfLines(markdown) {
+		lines = append(lines, wrapTextPdfLine(line)...)
+	}
+
+	// ページ毎の内容
+	contents := []*bytes.Buffer{new(bytes.Buffer)}
+	y := textPdfPageHeight - textPdfMargin
+	for _, line := range lines {
+		y -= line.fontSize * textPdfLeading
+		if y < textPdfMargin {
+			contents = append(contents, new(bytes.Buffer))
+			y = textPdfPageHeight - textPdfMargin - line.fontSize*textPdfLeading
+		}
+		if line.text == "" {
+			continue
+		}
+		fmt.Fprintf(contents[len(contents)-1], "BT /F1 %.1f Tf 1 0 0 1 %.1f %.1f Tm %s Tj ET\n", line.fontSize, textPdfMargin+line.indent, y, textPdfString(line.text))
+	}
+
+	// 1: Catalog, 2: Pages, 3: Font, 4: CIDFont, 5: FontDescriptor, 6以降: ページと内容
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /HeiseiKakuGo-W5 /Encoding /UniJIS-UCS2-H /DescendantFonts [4 0 R] >>",
+		"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /HeiseiKakuGo-W5 /CIDSystemInfo << /Registry (Adobe) /Ordering (Japan1) /Supplement 2 >> /FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>",
+		"<< /Type /FontDescriptor /FontName /HeiseiKakuGo-W5 /Flags 4 /FontBBox [-92 -250 1010 922] /ItalicAngle 0 /Ascent 752 /Descent -221 /CapHeight 737 /StemV 114 >>",
+	}
+	kids := make([]string, 0, len(contents))
+	for _, content := range contents {
+		pageId := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageId))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", textPdfPageWidth, textPdfPageHeight, pageId+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
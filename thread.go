@@ -0,0 +1,91 @@
+package main
+
+import (
+	"time"
+)
+
+const QuestionReplyMsgType = "question_reply"
+
+// QuestionReplyResult は質問への回答・返信を会議の参加者に知らせるメッセージ
+type QuestionReplyResult struct {
+	MessageType   string `json:"messageType"`
+	MeetingId     int    `json:"meetingId"`
+	QuestionId    int    `json:"questionId"`
+	IsAnswer      bool   `json:"isAnswer"`
+	AnswerId      int    `json:"answerId"`      // only if `IsAnswer == true`
+	ReplyId       int    `json:"replyId"`       // only if `IsAnswer == false`
+	ParentReplyId int    `json:"parentReplyId"` // 質問への返信は0
+	UserId        string `json:"userId"`
+	Body          string `json:"body"`
+	RepliedAt     string `json:"repliedAt"`
+}
+
+// QuestionThread は質問への回答と返信のスレッド
+type QuestionThread struct {
+	QuestionId int            `json:"questionId"`
+	Answers    []AnswerObject `json:"answers"`
+	Replies    []ReplyObject  `json:"replies"` // 質問への返信 (返信への返信はRepliesの中に入れる)
+}
+
+type AnswerObject struct {
+	AnswerId   int    `json:"answerId"`
+	UserId     string `json:"userId"`
+	AnswerBody string `json:"answerBody"`
+	AnsweredAt string `json:"answeredAt"`
+	IsFollowUp bool   `json:"isFollowUp"`
+}
+
+type ReplyObject struct {
+	ReplyId   int           `json:"replyId"`
+	UserId    string        `json:"userId"`
+	ReplyBody string        `json:"replyBody"`
+	RepliedAt string        `json:"repliedAt"`
+	Replies   []ReplyObject `json:"replies"`
+}
+
+// buildQuestionThreads は質問毎の回答と返信をスレッドの形にする (questionIdsと同じ順)
+func buildQuestionThreads(questionIds []int, answers map[int][]Answer, replies map[int][]Reply) []QuestionThread {
+	var (
+		layout      = "2006/01/02 15:04:05"
+		location, _ = time.LoadLocation("Asia/Tokyo")
+	)
+	threads := make([]QuestionThread, 0, len(questionIds))
+	for _, questionId := range questionIds {
+		thread := QuestionThread{
+			QuestionId: questionId,
+			Answers:    make([]AnswerObject, 0, len(answers[questionId])),
+		}
+		for _, answer := range answers[questionId] {
+			thread.Answers = append(thread.Answers, AnswerObject{
+				AnswerId:   answer.AnswerId,
+				UserId:     answer.UserId,
+				AnswerBody: answer.AnswerBody,
+				AnsweredAt: answer.AnsweredAt.In(location).Format(layout),
+				IsFollowUp: answer.IsFollowUp,
+			})
+		}
+
+		// 返信先毎にまとめてから，質問への返信から順に組み立てる
+		children := make(map[int][]Reply)
+		for _, reply := range replies[questionId] {
+			children[reply.ParentReplyId] = append(children[reply.ParentReplyId], reply)
+		}
+		var build func(parentReplyId int) []ReplyObject
+		build = func(parentReplyId int) []ReplyObject {
+			objects := make([]ReplyObject, 0, len(children[parentReplyId]))
+			for _, reply := range children[parentReplyId] {
+				objects = append(objects, ReplyObject{
+					ReplyId:   reply.ReplyId,
+					UserId:    reply.UserId,
+					ReplyBody: reply.ReplyBody,
+					RepliedAt: reply.RepliedAt.In(location).Format(layout),
+					Replies:   build(reply.ReplyId),
+				})
+			}
+			return objects
+		}
+		thread.Replies = build(0)
+		threads = append(threads, thread)
+	}
+	return threads
+}